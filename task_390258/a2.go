@@ -1,20 +1,16 @@
 package main
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 	"log"
-	"sync"
 	"time"
 
 	_ "github.com/go-sql-driver/mysql"
-)
 
-// Example struct for database row
-type BatchItem struct {
-	ID   int
-	Name string
-}
+	"github.com/shailendra-s-123/golang_random_5/task_390258/batchwriter"
+)
 
 func main() {
 	// Set up the database connection
@@ -30,60 +26,43 @@ func main() {
 	db.SetMaxIdleConns(5)
 	db.SetConnMaxLifetime(time.Minute * 3)
 
-	var wg sync.WaitGroup
-	batch := make([]BatchItem, 1000) // Example batch of 1000 items
-
-	// Simulate populating the batch
-	for i := range batch {
-		batch[i] = BatchItem{ID: i + 1, Name: fmt.Sprintf("Item %d", i + 1)}
+	// Rows that still fail after the retries land in dead_letters.jsonl
+	// instead of stopping ingestion; batchwriter.Replay re-sends them.
+	w, err := batchwriter.New(db, batchwriter.Config{
+		Table:      "table_name",
+		Columns:    []string{"id", "name"},
+		Dialect:    batchwriter.MySQL,
+		MaxRows:    100,
+		DeadLetter: batchwriter.NewJSONLStore("dead_letters.jsonl"),
+	})
+	if err != nil {
+		log.Fatal(err)
 	}
 
-	// Write batch items concurrently
-	for i := 0; i < 10; i++ {
-		wg.Add(1)
-
-		go func(workerID int) {
-			defer wg.Done()
-			for {
-				selectedItem := <-batchChannel
-				if selectedItem == nil {
-					return
-				}
-
-				err := writeItem(db, selectedItem)
-				if err != nil {
-					log.Printf("Worker %d: Failed to write item %d: %v. Retrying...\n", workerID, selectedItem.ID, err)
-					time.Sleep(time.Second) // Simulate backoff time
-					continue
-				}
-				log.Printf("Worker %d: Successfully wrote item %d\n", workerID, selectedItem.ID)
-			}
-		}(i)
-	}
+	// The pool grows up to the connection limit while batches stay fast.
+	ingester := batchwriter.NewIngester(w, batchwriter.IngestConfig{
+		MaxWorkers: 10,
+		OnStats: func(s batchwriter.IngestStats) {
+			log.Printf("%d workers, %.0f rows/s, %d errors\n", s.Workers, s.RowsPerSec, s.Errors)
+		},
+	})
 
-	// Close the batch channel once all items are processed
+	// Distribute 1000 example items; the channel blocks while every
+	// worker is busy.
+	batchChannel := make(chan batchwriter.BatchItem, 100)
 	go func() {
-		wg.Wait()
-		close(batchChannel)
+		defer close(batchChannel)
+		for i := 0; i < 1000; i++ {
+			batchChannel <- batchwriter.BatchItem{ID: i + 1, Name: fmt.Sprintf("Item %d", i+1)}
+		}
 	}()
 
-	// Distribute batch items to workers
-	for _, item := range batch {
-		batchChannel <- &item
+	ctx := context.Background()
+	if err := ingester.Ingest(ctx, batchChannel); err != nil {
+		log.Fatal(err)
 	}
-}
-
-func writeItem(db *sql.DB, item *BatchItem) error {
-	// Simulating a write operation
-	stmt, err := db.Prepare("INSERT INTO table_name (id, name) VALUES (?, ?)")
-	if err != nil {
-		return err
+	if err := w.Close(ctx); err != nil {
+		log.Fatal(err)
 	}
-	defer stmt.Close()
-
-	_, err = stmt.Exec(item.ID, item.Name)
-	return err
+	log.Printf("Wrote %d items, dead-lettered %d\n", w.Written(), w.DeadLettered())
 }
-
-// Channel to distribute batch items to worker Goroutines
-var batchChannel = make(chan *BatchItem, 100)
\ No newline at end of file
@@ -1,22 +1,15 @@
 package main
 
 import (
+	"context"
 	"database/sql"
-	"fmt"
 	"log"
-	"math/rand"
-	"sync"
 	"time"
 
 	_ "github.com/go-sql-driver/mysql"
-)
-
-type Record struct {
-	ID   int
-	Name string
-}
 
-const maxRetries = 3
+	"github.com/shailendra-s-123/golang_random_5/task_390258/batchwriter"
+)
 
 func main() {
 	dsn := "user:password@tcp(127.0.0.1:3306)/dbname"
@@ -30,68 +23,47 @@ func main() {
 	db.SetMaxIdleConns(5)
 	db.SetConnMaxLifetime(time.Minute * 3)
 
-	records := []Record{
+	w, err := batchwriter.New(db, batchwriter.Config{
+		Table:      "users",
+		Columns:    []string{"id", "name"},
+		Dialect:    batchwriter.MySQL,
+		MaxRows:    10,
+		MaxRetries: 3,
+		// Retries resume after the rows a failed attempt wrote; upserting
+		// on the key keeps a replayed dead letter from conflicting.
+		Mode:       batchwriter.ModeUpsert,
+		KeyColumns: []string{"id"},
+		DeadLetter: batchwriter.NewJSONLStore("users.deadletter.jsonl"),
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	records := []batchwriter.BatchItem{
 		{ID: 1, Name: "Alice"},
 		{ID: 2, Name: "Bob"},
 		// Add more records as needed
 	}
 
-	batchSize := 10
-	var wg sync.WaitGroup
-
-	for i := 0; i < len(records); i += batchSize {
-		end := i + batchSize
-		if end > len(records) {
-			end = len(records)
+	in := make(chan batchwriter.BatchItem)
+	go func() {
+		defer close(in)
+		for _, r := range records {
+			in <- r
 		}
-		batch := records[i:end]
-
-		wg.Add(1)
-		go func(recordsBatch []Record) {
-			defer wg.Done()
-			if err := writeBatchWithRetries(db, recordsBatch); err != nil {
-				log.Printf("Failed to write batch: %v\n", err)
-			}
-		}(batch)
+	}()
+
+	// Batches of MaxRows are written concurrently; a batch that still
+	// fails after MaxRetries is dead-lettered rather than lost.
+	ctx := context.Background()
+	ingester := batchwriter.NewIngester(w, batchwriter.IngestConfig{MaxWorkers: 10})
+	if err := ingester.Ingest(ctx, in); err != nil {
+		log.Printf("Failed to write batch: %v\n", err)
 	}
-
-	wg.Wait()
-}
-
-func writeBatchWithRetries(db *sql.DB, records []Record) error {
-	var err error
-	for attempt := 0; attempt < maxRetries; attempt++ {
-		if err = writeBatch(db, records); err == nil {
-			return nil
-		}
-		log.Printf("Retry attempt %d for batch failed: %v\n", attempt+1, err)
-		backoff(attempt)
-	}
-	return fmt.Errorf("all retry attempts failed: %w", err)
-}
-
-func writeBatch(db *sql.DB, records []Record) error {
-	tx, err := db.Begin()
-	if err != nil {
-		return err
-	}
-	defer tx.Rollback() // Ensure rollback on error
-
-	stmt, err := tx.Prepare("INSERT INTO users (id, name) VALUES (?, ?)")
-	if err != nil {
-		return err
+	if err := w.Close(ctx); err != nil {
+		log.Printf("Failed to close writer: %v\n", err)
 	}
-	defer stmt.Close()
-
-	for _, record := range records {
-		if _, err := stmt.Exec(record.ID, record.Name); err != nil {
-			return err
-		}
+	if n := w.DeadLettered(); n > 0 {
+		log.Printf("%d records dead-lettered\n", n)
 	}
-
-	return tx.Commit()
 }
-
-func backoff(attempt int) {
-	time.Sleep(time.Duration(rand.Intn(1<<attempt)) * time.Second)
-}
\ No newline at end of file
@@ -0,0 +1,368 @@
+package batchwriter
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DeadLetter is a row that could not be written, together with the driver
+// error that rejected it. Values round-trip through JSON, so []byte values
+// come back as base64 strings.
+type DeadLetter struct {
+	ID       string    `json:"id"`
+	Table    string    `json:"table"`
+	Columns  []string  `json:"columns"`
+	Values   []any     `json:"values"`
+	Error    string    `json:"error"`
+	FailedAt time.Time `json:"failed_at"`
+}
+
+// DeadLetterStore persists rows isolated by the writer and hands them back
+// for replay.
+type DeadLetterStore interface {
+	Put(ctx context.Context, letters []DeadLetter) error
+	Load(ctx context.Context) ([]DeadLetter, error)
+	// Remove deletes the letters with the given IDs.
+	Remove(ctx context.Context, ids []string) error
+}
+
+// isolate bisects rows that failed together with cause until every failing
+// row stands alone, writing the healthy halves along the way. It returns
+// the number of rows written and the dead letters for the rest. A transient
+// error aborts the search, since bisecting an outage would dead-letter
+// every row.
+func (w *BatchWriter) isolate(ctx context.Context, rows [][]any, cause error) (int, []DeadLetter, error) {
+	if len(rows) == 1 {
+		return 0, []DeadLetter{w.deadLetter(rows[0], cause)}, nil
+	}
+	var (
+		written int
+		letters []DeadLetter
+	)
+	mid := len(rows) / 2
+	for _, half := range [][][]any{rows[:mid], rows[mid:]} {
+		err := w.exec(ctx, nil, half)
+		if err == nil {
+			written += len(half)
+			continue
+		}
+		if w.cfg.Transient(err) {
+			return written, nil, err
+		}
+		n, dead, err := w.isolate(ctx, half, err)
+		written += n
+		if err != nil {
+			return written, nil, err
+		}
+		letters = append(letters, dead...)
+	}
+	return written, letters, nil
+}
+
+func (w *BatchWriter) deadLetter(values []any, cause error) DeadLetter {
+	// Report the driver's error rather than the writer's wrapping of it.
+	msg := cause.Error()
+	if inner := errors.Unwrap(cause); inner != nil {
+		msg = inner.Error()
+	}
+	return DeadLetter{
+		ID:       newLetterID(),
+		Table:    w.cfg.Table,
+		Columns:  w.columns,
+		Values:   values,
+		Error:    msg,
+		FailedAt: time.Now().UTC(),
+	}
+}
+
+// Replay re-submits every dead letter for w's table through w and removes
+// the replayed letters from store. Rows that fail again are dead-lettered
+// anew by w, so replay is at-least-once; combine it with ModeUpsert for
+// exactly-once results.
+func Replay(ctx context.Context, store DeadLetterStore, w *BatchWriter) (int, error) {
+	letters, err := store.Load(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("batchwriter: load dead letters: %w", err)
+	}
+	var ids []string
+	var rows [][]any
+	index := make(map[string]int)
+	for _, l := range letters {
+		if l.Table != w.cfg.Table || !equalColumns(l.Columns, w.columns) {
+			continue
+		}
+		if len(l.Values) != len(w.columns) {
+			return 0, fmt.Errorf("batchwriter: letter %s has %d values, want %d", l.ID, len(l.Values), len(w.columns))
+		}
+		values := normalizeValues(l.Values)
+		ids = append(ids, l.ID)
+		// As in the buffer, the latest letter for a key wins.
+		if w.keyIdx != nil {
+			key := w.dedupKey(values)
+			if i, ok := index[key]; ok {
+				rows[i] = values
+				continue
+			}
+			index[key] = len(rows)
+		}
+		rows = append(rows, values)
+	}
+	// Write outside the buffer, so a failed flush of other goroutines'
+	// rows cannot pass for a successful replay.
+	if err := w.writeValues(ctx, rows); err != nil {
+		return 0, err
+	}
+	if err := store.Remove(ctx, ids); err != nil {
+		return 0, fmt.Errorf("batchwriter: remove replayed letters: %w", err)
+	}
+	return len(ids), nil
+}
+
+// isTransient reports whether err is a connection-level failure that is
+// worth retrying as a whole rather than blaming individual rows.
+func isTransient(err error) bool {
+	var netErr net.Error
+	return errors.Is(err, driver.ErrBadConn) ||
+		errors.Is(err, sql.ErrConnDone) ||
+		errors.Is(err, context.Canceled) ||
+		errors.Is(err, context.DeadlineExceeded) ||
+		errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.As(err, &netErr)
+}
+
+// JSONLStore keeps dead letters as one JSON object per line in a file.
+type JSONLStore struct {
+	path string
+	mu   sync.Mutex
+}
+
+// NewJSONLStore returns a store backed by the file at path, which is
+// created on the first Put.
+func NewJSONLStore(path string) *JSONLStore {
+	return &JSONLStore{path: path}
+}
+
+// Put appends letters to the file.
+func (s *JSONLStore) Put(_ context.Context, letters []DeadLetter) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	f, err := os.OpenFile(s.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	enc := json.NewEncoder(f)
+	for _, l := range letters {
+		if err := enc.Encode(l); err != nil {
+			f.Close()
+			return err
+		}
+	}
+	return f.Close()
+}
+
+// Load reads every letter in the file. A missing file holds no letters.
+func (s *JSONLStore) Load(_ context.Context) ([]DeadLetter, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.load()
+}
+
+// Remove rewrites the file without the given letters.
+func (s *JSONLStore) Remove(_ context.Context, ids []string) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	letters, err := s.load()
+	if err != nil {
+		return err
+	}
+	drop := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		drop[id] = true
+	}
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	for _, l := range letters {
+		if drop[l.ID] {
+			continue
+		}
+		if err := enc.Encode(l); err != nil {
+			return err
+		}
+	}
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, buf.Bytes(), 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.path)
+}
+
+func (s *JSONLStore) load() ([]DeadLetter, error) {
+	f, err := os.Open(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var letters []DeadLetter
+	sc := bufio.NewScanner(f)
+	sc.Buffer(make([]byte, 64*1024), 16<<20)
+	for sc.Scan() {
+		if len(bytes.TrimSpace(sc.Bytes())) == 0 {
+			continue
+		}
+		l, err := decodeLetter(sc.Bytes())
+		if err != nil {
+			return nil, err
+		}
+		letters = append(letters, l)
+	}
+	return letters, sc.Err()
+}
+
+// TableStore keeps dead letters in a side table with the schema
+//
+//	CREATE TABLE <table> (
+//	    id           VARCHAR(32) PRIMARY KEY,
+//	    target_table VARCHAR(255) NOT NULL,
+//	    error        TEXT NOT NULL,
+//	    payload      TEXT NOT NULL
+//	)
+//
+// where payload is the JSON-encoded DeadLetter.
+type TableStore struct {
+	DB      *sql.DB
+	Table   string
+	Dialect Dialect
+}
+
+// Put inserts letters into the side table.
+func (s *TableStore) Put(ctx context.Context, letters []DeadLetter) error {
+	if len(letters) == 0 {
+		return nil
+	}
+	cols := []string{"id", "target_table", "error", "payload"}
+	chunk := s.Dialect.MaxParams() / len(cols)
+	for len(letters) > 0 {
+		n := min(chunk, len(letters))
+		args := make([]any, 0, n*len(cols))
+		for _, l := range letters[:n] {
+			payload, err := json.Marshal(l)
+			if err != nil {
+				return err
+			}
+			args = append(args, l.ID, l.Table, l.Error, string(payload))
+		}
+		if _, err := s.DB.ExecContext(ctx, insertSQL(s.Dialect, s.Table, cols, n), args...); err != nil {
+			return err
+		}
+		letters = letters[n:]
+	}
+	return nil
+}
+
+// Load reads every letter from the side table.
+func (s *TableStore) Load(ctx context.Context) ([]DeadLetter, error) {
+	rows, err := s.DB.QueryContext(ctx, "SELECT payload FROM "+s.Dialect.QuoteIdent(s.Table))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var letters []DeadLetter
+	for rows.Next() {
+		var payload string
+		if err := rows.Scan(&payload); err != nil {
+			return nil, err
+		}
+		l, err := decodeLetter([]byte(payload))
+		if err != nil {
+			return nil, err
+		}
+		letters = append(letters, l)
+	}
+	return letters, rows.Err()
+}
+
+// Remove deletes the given letters from the side table.
+func (s *TableStore) Remove(ctx context.Context, ids []string) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	marks := make([]string, len(ids))
+	args := make([]any, len(ids))
+	for i, id := range ids {
+		marks[i] = s.Dialect.Placeholder(i + 1)
+		args[i] = id
+	}
+	query := fmt.Sprintf("DELETE FROM %s WHERE id IN (%s)", s.Dialect.QuoteIdent(s.Table), strings.Join(marks, ", "))
+	_, err := s.DB.ExecContext(ctx, query, args...)
+	return err
+}
+
+func decodeLetter(data []byte) (DeadLetter, error) {
+	var l DeadLetter
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	if err := dec.Decode(&l); err != nil {
+		return DeadLetter{}, fmt.Errorf("batchwriter: decode dead letter: %w", err)
+	}
+	return l, nil
+}
+
+// normalizeValues turns JSON numbers back into int64 or float64.
+func normalizeValues(values []any) []any {
+	for i, v := range values {
+		n, ok := v.(json.Number)
+		if !ok {
+			continue
+		}
+		if iv, err := n.Int64(); err == nil {
+			values[i] = iv
+		} else if fv, err := n.Float64(); err == nil {
+			values[i] = fv
+		}
+	}
+	return values
+}
+
+func equalColumns(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func newLetterID() string {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return fmt.Sprintf("%032x", time.Now().UnixNano())
+	}
+	return hex.EncodeToString(b[:])
+}
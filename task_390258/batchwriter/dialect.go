@@ -0,0 +1,137 @@
+package batchwriter
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Dialect describes how a SQL backend spells identifiers and placeholders.
+type Dialect interface {
+	// Name returns a short identifier for the dialect, e.g. "mysql".
+	Name() string
+	// Placeholder returns the bind marker for the n-th argument (1-based).
+	Placeholder(n int) string
+	// QuoteIdent quotes a table or column name.
+	QuoteIdent(name string) string
+	// MaxParams is the largest number of bind arguments one statement may carry.
+	MaxParams() int
+	// Upsert returns the clause appended to an INSERT so that rows
+	// conflicting on keys update the update columns instead of failing.
+	Upsert(keys, update []string) string
+}
+
+// Built-in dialects
+var (
+	MySQL    Dialect = mysqlDialect{}
+	Postgres Dialect = postgresDialect{}
+	SQLite   Dialect = sqliteDialect{}
+)
+
+type mysqlDialect struct{}
+
+func (mysqlDialect) Name() string                  { return "mysql" }
+func (mysqlDialect) Placeholder(int) string        { return "?" }
+func (mysqlDialect) QuoteIdent(name string) string { return quote(name, '`') }
+func (mysqlDialect) MaxParams() int                { return 65535 }
+
+// Upsert ignores keys: MySQL resolves conflicts against every unique index.
+func (d mysqlDialect) Upsert(keys, update []string) string {
+	if len(update) == 0 {
+		// A self-assignment turns the duplicate into a no-op.
+		k := d.QuoteIdent(keys[0])
+		return " ON DUPLICATE KEY UPDATE " + k + " = " + k
+	}
+	var b strings.Builder
+	b.WriteString(" ON DUPLICATE KEY UPDATE ")
+	for i, c := range update {
+		if i > 0 {
+			b.WriteString(", ")
+		}
+		c = d.QuoteIdent(c)
+		b.WriteString(c + " = VALUES(" + c + ")")
+	}
+	return b.String()
+}
+
+type postgresDialect struct{}
+
+func (postgresDialect) Name() string                  { return "postgres" }
+func (postgresDialect) Placeholder(n int) string      { return "$" + strconv.Itoa(n) }
+func (postgresDialect) QuoteIdent(name string) string { return quote(name, '"') }
+func (postgresDialect) MaxParams() int                { return 65535 }
+
+func (d postgresDialect) Upsert(keys, update []string) string { return onConflict(d, keys, update) }
+
+type sqliteDialect struct{}
+
+func (sqliteDialect) Name() string                  { return "sqlite" }
+func (sqliteDialect) Placeholder(int) string        { return "?" }
+func (sqliteDialect) QuoteIdent(name string) string { return quote(name, '"') }
+
+// MaxParams uses the pre-3.32 SQLITE_MAX_VARIABLE_NUMBER so the writer is
+// safe on older builds as well.
+func (sqliteDialect) MaxParams() int { return 999 }
+
+func (d sqliteDialect) Upsert(keys, update []string) string { return onConflict(d, keys, update) }
+
+// onConflict renders the ON CONFLICT clause shared by PostgreSQL and SQLite.
+func onConflict(d Dialect, keys, update []string) string {
+	var b strings.Builder
+	b.WriteString(" ON CONFLICT (")
+	for i, k := range keys {
+		if i > 0 {
+			b.WriteString(", ")
+		}
+		b.WriteString(d.QuoteIdent(k))
+	}
+	if len(update) == 0 {
+		b.WriteString(") DO NOTHING")
+		return b.String()
+	}
+	b.WriteString(") DO UPDATE SET ")
+	for i, c := range update {
+		if i > 0 {
+			b.WriteString(", ")
+		}
+		c = d.QuoteIdent(c)
+		b.WriteString(c + " = excluded." + c)
+	}
+	return b.String()
+}
+
+func quote(name string, q byte) string {
+	s := string(q)
+	return s + strings.ReplaceAll(name, s, s+s) + s
+}
+
+// insertSQL builds a multi-row INSERT for the given number of rows.
+func insertSQL(d Dialect, table string, columns []string, rows int) string {
+	var b strings.Builder
+	b.WriteString("INSERT INTO ")
+	b.WriteString(d.QuoteIdent(table))
+	b.WriteString(" (")
+	for i, c := range columns {
+		if i > 0 {
+			b.WriteString(", ")
+		}
+		b.WriteString(d.QuoteIdent(c))
+	}
+	b.WriteString(") VALUES ")
+
+	n := 1
+	for r := 0; r < rows; r++ {
+		if r > 0 {
+			b.WriteString(", ")
+		}
+		b.WriteByte('(')
+		for c := range columns {
+			if c > 0 {
+				b.WriteString(", ")
+			}
+			b.WriteString(d.Placeholder(n))
+			n++
+		}
+		b.WriteByte(')')
+	}
+	return b.String()
+}
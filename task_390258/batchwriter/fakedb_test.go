@@ -0,0 +1,198 @@
+package batchwriter
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"strings"
+	"sync"
+)
+
+// fakeDB is an in-memory database/sql driver that understands just enough
+// of a multi-row INSERT to record what the writer sent.
+type fakeDB struct {
+	mu       sync.Mutex
+	prepares int
+	queries  []string
+	rows     [][]driver.Value
+
+	// failExec, when set, is consulted before each Exec is applied.
+	failExec func(query string, args []driver.Value) error
+	// killOnExec applies the n-th Exec (1-based) and then drops the
+	// connection, so the client sees an error for a committed statement.
+	killOnExec int
+	execs      int
+
+	isolation driver.IsolationLevel
+}
+
+func openFake(f *fakeDB) *sql.DB { return sql.OpenDB(fakeConnector{f}) }
+
+func (f *fakeDB) rowCount() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return len(f.rows)
+}
+
+type fakeConnector struct{ db *fakeDB }
+
+func (c fakeConnector) Connect(context.Context) (driver.Conn, error) { return &fakeConn{db: c.db}, nil }
+func (c fakeConnector) Driver() driver.Driver                        { return fakeDriver{} }
+
+type fakeDriver struct{}
+
+func (fakeDriver) Open(string) (driver.Conn, error) { return nil, errors.New("use fakeConnector") }
+
+type fakeConn struct {
+	db   *fakeDB
+	dead bool
+	tx   *fakeTx
+}
+
+// IsValid lets database/sql discard killed connections.
+func (c *fakeConn) IsValid() bool { return !c.dead }
+
+func (c *fakeConn) Prepare(query string) (driver.Stmt, error) {
+	if c.dead {
+		return nil, driver.ErrBadConn
+	}
+	c.db.mu.Lock()
+	c.db.prepares++
+	c.db.mu.Unlock()
+	return &fakeStmt{conn: c, query: query}, nil
+}
+
+func (c *fakeConn) Close() error { return nil }
+
+func (c *fakeConn) Begin() (driver.Tx, error) {
+	return c.BeginTx(context.Background(), driver.TxOptions{})
+}
+
+func (c *fakeConn) BeginTx(_ context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	c.db.mu.Lock()
+	defer c.db.mu.Unlock()
+	c.db.isolation = opts.Isolation
+	c.tx = &fakeTx{conn: c, savepoints: make(map[string]int)}
+	return c.tx, nil
+}
+
+// fakeTx stages writes until Commit.
+type fakeTx struct {
+	conn       *fakeConn
+	staged     []stagedRow
+	savepoints map[string]int
+}
+
+type stagedRow struct {
+	row []driver.Value
+	key int
+}
+
+func (t *fakeTx) Commit() error {
+	db := t.conn.db
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	for _, r := range t.staged {
+		db.upsert(r.row, r.key)
+	}
+	t.conn.tx = nil
+	return nil
+}
+
+func (t *fakeTx) Rollback() error {
+	t.conn.db.mu.Lock()
+	defer t.conn.db.mu.Unlock()
+	t.conn.tx = nil
+	return nil
+}
+
+type fakeStmt struct {
+	conn  *fakeConn
+	query string
+}
+
+func (s *fakeStmt) Close() error  { return nil }
+func (s *fakeStmt) NumInput() int { return -1 }
+
+func (s *fakeStmt) Query([]driver.Value) (driver.Rows, error) {
+	return nil, errors.New("fake: queries are not supported")
+}
+
+func (s *fakeStmt) Exec(args []driver.Value) (driver.Result, error) {
+	if s.conn.dead {
+		return nil, driver.ErrBadConn
+	}
+	db := s.conn.db
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	if db.failExec != nil {
+		if err := db.failExec(s.query, args); err != nil {
+			return nil, err
+		}
+	}
+	if tx := s.conn.tx; tx != nil {
+		if name, ok := strings.CutPrefix(s.query, "ROLLBACK TO SAVEPOINT "); ok {
+			tx.staged = tx.staged[:tx.savepoints[name]]
+			return driver.ResultNoRows, nil
+		}
+		if name, ok := strings.CutPrefix(s.query, "SAVEPOINT "); ok {
+			tx.savepoints[name] = len(tx.staged)
+			return driver.ResultNoRows, nil
+		}
+	}
+	cols := insertColumns(s.query)
+	if len(cols) == 0 || len(args)%len(cols) != 0 {
+		return nil, io.ErrUnexpectedEOF
+	}
+	key := -1
+	if i := strings.Index(s.query, "ON CONFLICT ("); i >= 0 {
+		target := s.query[i+len("ON CONFLICT ("):]
+		key = indexOf(cols, strings.Trim(target[:strings.IndexByte(target, ')')], `"`))
+	}
+
+	db.queries = append(db.queries, s.query)
+	for i := 0; i < len(args); i += len(cols) {
+		if tx := s.conn.tx; tx != nil {
+			tx.staged = append(tx.staged, stagedRow{args[i : i+len(cols)], key})
+			continue
+		}
+		db.upsert(args[i:i+len(cols)], key)
+	}
+
+	db.execs++
+	if db.execs == db.killOnExec {
+		s.conn.dead = true
+		return nil, errors.New("fake: connection reset by peer")
+	}
+	return driver.RowsAffected(len(args) / len(cols)), nil
+}
+
+// upsert appends row, or replaces the row sharing its key column.
+func (f *fakeDB) upsert(row []driver.Value, key int) {
+	if key >= 0 {
+		for i, r := range f.rows {
+			if r[key] == row[key] {
+				f.rows[i] = row
+				return
+			}
+		}
+	}
+	f.rows = append(f.rows, row)
+}
+
+// insertColumns returns the unquoted column list of an INSERT statement.
+func insertColumns(query string) []string {
+	open := strings.IndexByte(query, '(')
+	end := strings.IndexByte(query, ')')
+	if open < 0 || end < open {
+		return nil
+	}
+	cols := strings.Split(query[open+1:end], ",")
+	for i, c := range cols {
+		cols[i] = strings.Trim(strings.TrimSpace(c), "`\"")
+	}
+	return cols
+}
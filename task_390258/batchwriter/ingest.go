@@ -0,0 +1,312 @@
+package batchwriter
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// IngestConfig tunes the adaptive worker pool behind Ingester.
+type IngestConfig struct {
+	// MinWorkers and MaxWorkers bound the number of concurrent batch
+	// writes. MaxWorkers should not exceed the pool's SetMaxOpenConns.
+	MinWorkers int
+	MaxWorkers int
+	// BatchSize is the number of items per worker batch. It defaults to
+	// the writer's MaxRows.
+	BatchSize int
+	// Linger dispatches a partial batch once no new item has arrived for
+	// this long.
+	Linger time.Duration
+	// TargetLatency is the mean batch latency above which concurrency is
+	// halved.
+	TargetLatency time.Duration
+	// MaxErrorRate is the fraction of failed batch attempts above which
+	// concurrency is halved.
+	MaxErrorRate float64
+	// Interval is how often concurrency is adjusted and OnStats is called.
+	Interval time.Duration
+	// OnStats, when set, receives the stats of every interval.
+	OnStats func(IngestStats)
+}
+
+// IngestStats summarizes one Interval of ingestion.
+type IngestStats struct {
+	Workers     int
+	Rows        int
+	Attempts    int
+	Errors      int
+	RowsPerSec  float64
+	MeanLatency time.Duration
+	MaxLatency  time.Duration
+}
+
+// Ingester drains a channel of items into a BatchWriter with a worker pool
+// whose size follows AIMD: it grows by one worker per interval while the
+// pool is saturated and healthy, and halves when latency or the error rate
+// crosses its target.
+type Ingester struct {
+	w   *BatchWriter
+	cfg IngestConfig
+	lim *limiter
+
+	mu         sync.Mutex
+	stats      IngestStats
+	latencySum time.Duration
+	start      time.Time
+}
+
+// NewIngester returns an Ingester that writes through w.
+func NewIngester(w *BatchWriter, cfg IngestConfig) *Ingester {
+	if cfg.MinWorkers <= 0 {
+		cfg.MinWorkers = 1
+	}
+	if cfg.MaxWorkers < cfg.MinWorkers {
+		cfg.MaxWorkers = max(cfg.MinWorkers, 10)
+	}
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = w.cfg.MaxRows
+	}
+	if cfg.Linger <= 0 {
+		cfg.Linger = 50 * time.Millisecond
+	}
+	if cfg.TargetLatency <= 0 {
+		cfg.TargetLatency = 250 * time.Millisecond
+	}
+	if cfg.MaxErrorRate <= 0 {
+		cfg.MaxErrorRate = 0.05
+	}
+	if cfg.Interval <= 0 {
+		cfg.Interval = time.Second
+	}
+	return &Ingester{
+		w:   w,
+		cfg: cfg,
+		lim: newLimiter(cfg.MinWorkers),
+	}
+}
+
+// Ingest writes every item received from in until in is closed, ctx is
+// done or a batch fails for good. Items are only read while a worker slot
+// is free, so producers block instead of queueing without bound. A batch
+// that still fails after the writer's MaxRetries is dead-lettered when a
+// store is configured; otherwise Ingest stops and returns the error.
+func (g *Ingester) Ingest(ctx context.Context, in <-chan BatchItem) error {
+	ctx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+
+	g.mu.Lock()
+	g.stats, g.latencySum, g.start = IngestStats{}, 0, time.Now()
+	g.mu.Unlock()
+
+	var wg sync.WaitGroup
+	done := make(chan struct{})
+	go g.control(done)
+	defer close(done)
+
+	batch := make([]Row, 0, g.cfg.BatchSize)
+	linger := time.NewTimer(g.cfg.Linger)
+	linger.Stop()
+	defer linger.Stop()
+
+	dispatch := func() bool {
+		if len(batch) == 0 {
+			return true
+		}
+		if err := g.lim.acquire(ctx); err != nil {
+			return false
+		}
+		wg.Add(1)
+		go func(rows []Row) {
+			defer wg.Done()
+			defer g.lim.release()
+			if err := g.writeBatch(ctx, rows); err != nil {
+				cancel(err)
+			}
+		}(batch)
+		batch = make([]Row, 0, g.cfg.BatchSize)
+		return true
+	}
+
+loop:
+	for {
+		select {
+		case <-ctx.Done():
+			break loop
+		case item, ok := <-in:
+			if !ok {
+				dispatch()
+				break loop
+			}
+			batch = append(batch, item)
+			if len(batch) == 1 {
+				linger.Reset(g.cfg.Linger)
+			}
+			if len(batch) >= g.cfg.BatchSize {
+				linger.Stop()
+				if !dispatch() {
+					break loop
+				}
+			}
+		case <-linger.C:
+			if !dispatch() {
+				break loop
+			}
+		}
+	}
+	wg.Wait()
+	return context.Cause(ctx)
+}
+
+// writeBatch writes rows with retries, resuming after the rows already
+// written by a failed attempt.
+func (g *Ingester) writeBatch(ctx context.Context, rows []Row) error {
+	w := g.w
+	values, _, err := w.layout(rows)
+	if err != nil {
+		return err
+	}
+	for attempt := 0; ; attempt++ {
+		start := time.Now()
+		n, err := w.write(ctx, values)
+		g.record(time.Since(start), n, err)
+		values = values[n:]
+		if err == nil {
+			return nil
+		}
+		if ctx.Err() != nil {
+			return err
+		}
+		if attempt+1 >= w.cfg.MaxRetries {
+			if w.cfg.DeadLetter == nil {
+				return err
+			}
+			letters := make([]DeadLetter, len(values))
+			for i, v := range values {
+				letters[i] = w.deadLetter(v, err)
+			}
+			return w.putDeadLetters(ctx, letters)
+		}
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(w.cfg.Backoff(attempt)):
+		}
+	}
+}
+
+func (g *Ingester) record(latency time.Duration, rows int, err error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.stats.Attempts++
+	g.stats.Rows += rows
+	g.latencySum += latency
+	g.stats.MaxLatency = max(g.stats.MaxLatency, latency)
+	if err != nil {
+		g.stats.Errors++
+	}
+}
+
+// control runs the AIMD loop once per interval until done is closed.
+func (g *Ingester) control(done <-chan struct{}) {
+	ticker := time.NewTicker(g.cfg.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-done:
+			return
+		case now := <-ticker.C:
+			g.mu.Lock()
+			s, sum := g.stats, g.latencySum
+			elapsed := now.Sub(g.start)
+			g.stats, g.latencySum, g.start = IngestStats{}, 0, now
+			g.mu.Unlock()
+
+			if s.Attempts > 0 {
+				s.MeanLatency = sum / time.Duration(s.Attempts)
+			}
+			if elapsed > 0 {
+				s.RowsPerSec = float64(s.Rows) / elapsed.Seconds()
+			}
+			s.Workers = g.adjust(s)
+			if g.cfg.OnStats != nil {
+				g.cfg.OnStats(s)
+			}
+		}
+	}
+}
+
+// adjust applies one AIMD step and returns the new worker limit.
+func (g *Ingester) adjust(s IngestStats) int {
+	limit, saturated := g.lim.snapshot()
+	switch {
+	case s.Attempts > 0 && (float64(s.Errors)/float64(s.Attempts) > g.cfg.MaxErrorRate ||
+		s.MeanLatency > g.cfg.TargetLatency):
+		limit = max(g.cfg.MinWorkers, limit/2)
+	case saturated:
+		limit = min(g.cfg.MaxWorkers, limit+1)
+	}
+	g.lim.setLimit(limit)
+	return limit
+}
+
+// limiter is a counting semaphore whose size can change at runtime. It
+// remembers whether any acquire had to wait since the last snapshot.
+type limiter struct {
+	mu        sync.Mutex
+	limit     int
+	active    int
+	saturated bool
+	changed   chan struct{}
+}
+
+func newLimiter(limit int) *limiter {
+	return &limiter{limit: limit, changed: make(chan struct{})}
+}
+
+func (l *limiter) acquire(ctx context.Context) error {
+	for {
+		l.mu.Lock()
+		if l.active < l.limit {
+			l.active++
+			l.mu.Unlock()
+			return nil
+		}
+		l.saturated = true
+		changed := l.changed
+		l.mu.Unlock()
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-changed:
+		}
+	}
+}
+
+func (l *limiter) release() {
+	l.mu.Lock()
+	l.active--
+	l.broadcastLocked()
+	l.mu.Unlock()
+}
+
+func (l *limiter) setLimit(n int) {
+	l.mu.Lock()
+	l.limit = n
+	l.broadcastLocked()
+	l.mu.Unlock()
+}
+
+func (l *limiter) snapshot() (limit int, saturated bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	limit, saturated = l.limit, l.saturated || l.active >= l.limit
+	l.saturated = false
+	return limit, saturated
+}
+
+func (l *limiter) broadcastLocked() {
+	close(l.changed)
+	l.changed = make(chan struct{})
+}
@@ -0,0 +1,81 @@
+package batchwriter
+
+import (
+	"context"
+	"database/sql/driver"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestIngestWritesEveryItem(t *testing.T) {
+	fake := &fakeDB{failExec: func(string, []driver.Value) error {
+		time.Sleep(time.Millisecond)
+		return nil
+	}}
+	db := openFake(fake)
+	defer db.Close()
+
+	w, err := New(db, Config{Table: "table_name", Columns: []string{"id", "name"}, MaxRows: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var (
+		mu    sync.Mutex
+		stats []IngestStats
+	)
+	g := NewIngester(w, IngestConfig{
+		MaxWorkers: 4,
+		Interval:   5 * time.Millisecond,
+		OnStats: func(s IngestStats) {
+			mu.Lock()
+			stats = append(stats, s)
+			mu.Unlock()
+		},
+	})
+
+	in := make(chan BatchItem)
+	go func() {
+		defer close(in)
+		for i := 0; i < 1000; i++ {
+			in <- BatchItem{ID: i + 1, Name: fmt.Sprintf("Item %d", i+1)}
+		}
+	}()
+	if err := g.Ingest(context.Background(), in); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := fake.rowCount(); got != 1000 {
+		t.Fatalf("rows = %d, want 1000", got)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	peak := 0
+	for _, s := range stats {
+		peak = max(peak, s.Workers)
+	}
+	if peak < 2 {
+		t.Fatalf("concurrency never grew past %d across %d intervals", peak, len(stats))
+	}
+}
+
+func TestIngesterAIMD(t *testing.T) {
+	w := &BatchWriter{cfg: Config{MaxRows: 10}}
+	g := NewIngester(w, IngestConfig{MinWorkers: 1, MaxWorkers: 8, TargetLatency: 100 * time.Millisecond})
+	g.lim.setLimit(4)
+
+	g.lim.saturated = true
+	if got := g.adjust(IngestStats{Attempts: 10, MeanLatency: 10 * time.Millisecond}); got != 5 {
+		t.Fatalf("healthy saturated pool: limit = %d, want 5", got)
+	}
+	if got := g.adjust(IngestStats{Attempts: 10, MeanLatency: time.Second}); got != 2 {
+		t.Fatalf("slow pool: limit = %d, want 2", got)
+	}
+	if got := g.adjust(IngestStats{Attempts: 10, Errors: 5}); got != 1 {
+		t.Fatalf("failing pool: limit = %d, want 1", got)
+	}
+	if got := g.adjust(IngestStats{}); got != 1 {
+		t.Fatalf("idle pool: limit = %d, want 1", got)
+	}
+}
@@ -0,0 +1,135 @@
+package batchwriter
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+)
+
+// TxOptions configures WriteBatchTx.
+type TxOptions struct {
+	Isolation sql.IsolationLevel
+	// SavepointEvery places a savepoint after every N rows, so a failure
+	// only rolls back the rows written since the last savepoint. Zero
+	// makes the batch all-or-nothing.
+	SavepointEvery int
+}
+
+// BatchResult reports how much of a transactional batch was committed.
+type BatchResult struct {
+	Committed  int
+	RolledBack int
+	// Pending holds the rolled-back rows so the caller can retry them.
+	Pending []Row
+}
+
+// WriteBatchTx writes rows inside a single transaction, bypassing the
+// shared buffer. On failure the transaction is rolled back to the last
+// savepoint and the rows before it are committed; the returned error is
+// the cause and BatchResult tells which rows made it. If ctx is done the
+// whole transaction is rolled back, savepoints notwithstanding.
+//
+// In ModeUpsert rows sharing a key are collapsed first, so the counts
+// refer to distinct keys.
+func (w *BatchWriter) WriteBatchTx(ctx context.Context, rows []Row, opts TxOptions) (BatchResult, error) {
+	w.mu.Lock()
+	closed := w.closed
+	w.mu.Unlock()
+	if closed {
+		return BatchResult{}, ErrClosed
+	}
+
+	values, src, err := w.layout(rows)
+	if err != nil {
+		return BatchResult{}, err
+	}
+	res := BatchResult{RolledBack: len(values), Pending: src}
+	if len(values) == 0 {
+		return res, nil
+	}
+
+	tx, err := w.db.BeginTx(ctx, &sql.TxOptions{Isolation: opts.Isolation})
+	if err != nil {
+		return res, fmt.Errorf("batchwriter: begin: %w", err)
+	}
+
+	var done, saved, savepoints int
+	for done < len(values) {
+		n := min(w.cfg.MaxRows, len(values)-done)
+		if opts.SavepointEvery > 0 {
+			n = min(n, saved+opts.SavepointEvery-done)
+		}
+		if err := w.exec(ctx, tx, values[done:done+n]); err != nil {
+			return w.abortTx(ctx, tx, res, saved, savepoints, err)
+		}
+		done += n
+
+		if opts.SavepointEvery > 0 && done-saved == opts.SavepointEvery && done < len(values) {
+			if _, err := tx.ExecContext(ctx, "SAVEPOINT "+savepointName(savepoints+1)); err != nil {
+				return w.abortTx(ctx, tx, res, saved, savepoints, err)
+			}
+			savepoints++
+			saved = done
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return res, fmt.Errorf("batchwriter: commit: %w", err)
+	}
+	w.written.Add(int64(len(values)))
+	return BatchResult{Committed: len(values)}, nil
+}
+
+// abortTx keeps the first saved rows and discards the rest. Without a
+// savepoint, or once ctx is done, everything is rolled back.
+func (w *BatchWriter) abortTx(ctx context.Context, tx *sql.Tx, res BatchResult, saved, savepoints int, cause error) (BatchResult, error) {
+	if savepoints == 0 || ctx.Err() != nil {
+		tx.Rollback()
+		return res, cause
+	}
+	if _, err := tx.ExecContext(ctx, "ROLLBACK TO SAVEPOINT "+savepointName(savepoints)); err != nil {
+		tx.Rollback()
+		return res, errors.Join(cause, err)
+	}
+	if err := tx.Commit(); err != nil {
+		return res, errors.Join(cause, fmt.Errorf("batchwriter: commit: %w", err))
+	}
+	w.written.Add(int64(saved))
+	return BatchResult{
+		Committed:  saved,
+		RolledBack: res.RolledBack - saved,
+		Pending:    res.Pending[saved:],
+	}, cause
+}
+
+// layout converts rows to column values, collapsing duplicate keys in
+// ModeUpsert. It returns the values alongside the rows they came from.
+func (w *BatchWriter) layout(rows []Row) ([][]any, []Row, error) {
+	values := make([][]any, 0, len(rows))
+	src := make([]Row, 0, len(rows))
+	var index map[string]int
+	if w.keyIdx != nil {
+		index = make(map[string]int, len(rows))
+	}
+	for _, r := range rows {
+		v, err := w.values(r)
+		if err != nil {
+			return nil, nil, err
+		}
+		if index != nil {
+			key := w.dedupKey(v)
+			if i, ok := index[key]; ok {
+				values[i], src[i] = v, r
+				continue
+			}
+			index[key] = len(values)
+		}
+		values = append(values, v)
+		src = append(src, r)
+	}
+	return values, src, nil
+}
+
+func savepointName(n int) string {
+	return fmt.Sprintf("batchwriter_sp_%d", n)
+}
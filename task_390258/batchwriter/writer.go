@@ -0,0 +1,498 @@
+// Package batchwriter buffers rows and writes them as multi-row INSERT
+// statements, replacing the one-prepare-per-row writeItem approach.
+package batchwriter
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math/rand"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Defaults applied by New when the corresponding Config field is zero.
+const (
+	DefaultMaxRows           = 500
+	DefaultMaxBytes          = 1 << 20
+	DefaultMaxRetries        = 3
+	DefaultIdempotencyColumn = "idempotency_key"
+)
+
+// Mode selects how conflicting rows are handled.
+type Mode int
+
+const (
+	// ModeInsert issues plain INSERTs; a conflicting row fails the batch.
+	ModeInsert Mode = iota
+	// ModeUpsert turns conflicts on the key columns into updates, so a
+	// retried batch converges to the same final state.
+	ModeUpsert
+)
+
+// ErrClosed is returned when a closed BatchWriter is used.
+var ErrClosed = errors.New("batchwriter: writer is closed")
+
+// Row is anything that can be flattened into column values, in the
+// order of Config.Columns.
+type Row interface {
+	Values() []any
+}
+
+// BatchItem is the row shape distributed to the worker pool.
+type BatchItem struct {
+	ID   int
+	Name string
+}
+
+// Values implements Row.
+func (b BatchItem) Values() []any { return []any{b.ID, b.Name} }
+
+// Record is the row shape written by the batch retry loop.
+type Record struct {
+	ID   int
+	Name string
+}
+
+// Values implements Row.
+func (r Record) Values() []any { return []any{r.ID, r.Name} }
+
+// Config controls the target table and when buffered rows are flushed.
+type Config struct {
+	Table   string
+	Columns []string
+	Dialect Dialect
+
+	// MaxRows flushes the buffer once it holds this many rows. It is
+	// capped so a single statement never exceeds Dialect.MaxParams.
+	MaxRows int
+	// MaxBytes flushes the buffer once the estimated payload reaches
+	// this size.
+	MaxBytes int
+
+	// Mode selects plain inserts or upserts. ModeUpsert requires
+	// KeyColumns or IdempotencyKey.
+	Mode Mode
+	// KeyColumns is the conflict target for upserts, e.g. the primary key.
+	KeyColumns []string
+	// IdempotencyKey, when set, derives a client-side key for every row.
+	// The key is written to IdempotencyColumn, which must carry a unique
+	// index, and becomes the conflict target instead of KeyColumns.
+	IdempotencyKey func(Row) string
+	// IdempotencyColumn defaults to DefaultIdempotencyColumn.
+	IdempotencyColumn string
+
+	// MaxRetries bounds the attempts made by WriteBatchWithRetries.
+	MaxRetries int
+	// Backoff returns the pause before retry attempt n (0-based). The
+	// default is jittered exponential backoff starting at 100ms.
+	Backoff func(attempt int) time.Duration
+
+	// DeadLetter, when set, enables poison-record isolation: a failed
+	// flush is bisected until the offending rows are found, the rest are
+	// written and the offenders are moved to the store.
+	DeadLetter DeadLetterStore
+	// Transient reports errors that should fail the flush as a whole
+	// instead of being bisected. The default recognizes dropped
+	// connections, context errors and network errors.
+	Transient func(error) bool
+}
+
+// BatchWriter buffers rows and flushes them as multi-row INSERTs. Prepared
+// statements are cached per batch shape (row count), so steady-state
+// flushes reuse a single statement. It is safe for concurrent use.
+//
+// Add, Flush and Close share one buffer: a flush writes the rows every
+// goroutine has added, and a failure is reported only to the call that
+// triggered it. Goroutines that each need to know whether their own rows
+// were written should use WriteBatch, which bypasses the buffer.
+//
+// In ModeUpsert the buffer is deduplicated by key: a row whose key is
+// already buffered replaces the earlier row, since PostgreSQL rejects a
+// statement that touches the same conflict target twice.
+type BatchWriter struct {
+	db      *sql.DB
+	cfg     Config
+	columns []string
+	keyIdx  []int
+	suffix  string
+
+	mu     sync.Mutex
+	buf    [][]any
+	sizes  []int
+	index  map[string]int
+	size   int
+	closed bool
+
+	written atomic.Int64
+	dead    atomic.Int64
+
+	stmtMu sync.Mutex
+	stmts  map[int]*sql.Stmt
+}
+
+// New validates cfg and returns a writer bound to db.
+func New(db *sql.DB, cfg Config) (*BatchWriter, error) {
+	if db == nil {
+		return nil, errors.New("batchwriter: nil db")
+	}
+	if cfg.Table == "" || len(cfg.Columns) == 0 {
+		return nil, errors.New("batchwriter: table and columns are required")
+	}
+	if cfg.Dialect == nil {
+		cfg.Dialect = MySQL
+	}
+	if cfg.MaxBytes <= 0 {
+		cfg.MaxBytes = DefaultMaxBytes
+	}
+	if cfg.MaxRetries <= 0 {
+		cfg.MaxRetries = DefaultMaxRetries
+	}
+	if cfg.Backoff == nil {
+		cfg.Backoff = defaultBackoff
+	}
+	if cfg.Transient == nil {
+		cfg.Transient = isTransient
+	}
+
+	w := &BatchWriter{
+		db:      db,
+		cfg:     cfg,
+		columns: cfg.Columns,
+		index:   make(map[string]int),
+		stmts:   make(map[int]*sql.Stmt),
+	}
+	keys := cfg.KeyColumns
+	if cfg.IdempotencyKey != nil {
+		if cfg.Mode != ModeUpsert {
+			return nil, errors.New("batchwriter: IdempotencyKey requires ModeUpsert")
+		}
+		if cfg.IdempotencyColumn == "" {
+			w.cfg.IdempotencyColumn = DefaultIdempotencyColumn
+		}
+		w.columns = append(append([]string(nil), cfg.Columns...), w.cfg.IdempotencyColumn)
+		keys = []string{w.cfg.IdempotencyColumn}
+	}
+	if cfg.Mode == ModeUpsert {
+		if len(keys) == 0 {
+			return nil, errors.New("batchwriter: ModeUpsert requires KeyColumns or IdempotencyKey")
+		}
+		var update []string
+		for _, c := range cfg.Columns {
+			if !contains(keys, c) {
+				update = append(update, c)
+			}
+		}
+		for _, k := range keys {
+			i := indexOf(w.columns, k)
+			if i < 0 {
+				return nil, fmt.Errorf("batchwriter: key column %q is not a column", k)
+			}
+			w.keyIdx = append(w.keyIdx, i)
+		}
+		w.suffix = cfg.Dialect.Upsert(keys, update)
+	}
+
+	if w.cfg.MaxRows <= 0 {
+		w.cfg.MaxRows = DefaultMaxRows
+	}
+	if limit := cfg.Dialect.MaxParams() / len(w.columns); w.cfg.MaxRows > limit {
+		w.cfg.MaxRows = limit
+	}
+	return w, nil
+}
+
+// Add buffers a row, flushing first if the row would push the buffer past
+// MaxBytes and afterwards if the buffer reached MaxRows.
+func (w *BatchWriter) Add(ctx context.Context, row Row) error {
+	values, err := w.values(row)
+	if err != nil {
+		return err
+	}
+	return w.addValues(ctx, values)
+}
+
+// values lays row out in w.columns order.
+func (w *BatchWriter) values(row Row) ([]any, error) {
+	values := row.Values()
+	if len(values) != len(w.cfg.Columns) {
+		return nil, fmt.Errorf("batchwriter: row has %d values, want %d", len(values), len(w.cfg.Columns))
+	}
+	if w.cfg.IdempotencyKey != nil {
+		values = append(values, w.cfg.IdempotencyKey(row))
+	}
+	return values, nil
+}
+
+// addValues buffers a row already laid out in w.columns order.
+func (w *BatchWriter) addValues(ctx context.Context, values []any) error {
+	if len(values) != len(w.columns) {
+		return fmt.Errorf("batchwriter: row has %d values, want %d", len(values), len(w.columns))
+	}
+	size := estimateSize(values)
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.closed {
+		return ErrClosed
+	}
+
+	var key string
+	if w.keyIdx != nil {
+		key = w.dedupKey(values)
+		if i, ok := w.index[key]; ok {
+			w.size += size - w.sizes[i]
+			w.buf[i], w.sizes[i] = values, size
+			return nil
+		}
+	}
+
+	if len(w.buf) > 0 && w.size+size > w.cfg.MaxBytes {
+		if err := w.flushLocked(ctx); err != nil {
+			return err
+		}
+	}
+	if w.keyIdx != nil {
+		w.index[key] = len(w.buf)
+	}
+	w.buf = append(w.buf, values)
+	w.sizes = append(w.sizes, size)
+	w.size += size
+	if len(w.buf) >= w.cfg.MaxRows || w.size >= w.cfg.MaxBytes {
+		return w.flushLocked(ctx)
+	}
+	return nil
+}
+
+// Flush writes all buffered rows. The buffer is cleared even on error; the
+// caller owns retrying.
+func (w *BatchWriter) Flush(ctx context.Context) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.closed {
+		return ErrClosed
+	}
+	return w.flushLocked(ctx)
+}
+
+// WriteBatch writes rows and returns the first error. It does not use the
+// buffer, so the error concerns rows alone: rows added by other goroutines
+// are neither written nor lost with it.
+func (w *BatchWriter) WriteBatch(ctx context.Context, rows []Row) error {
+	values, _, err := w.layout(rows)
+	if err != nil {
+		return err
+	}
+	return w.writeValues(ctx, values)
+}
+
+// writeValues writes rows laid out in w.columns order outside the buffer.
+func (w *BatchWriter) writeValues(ctx context.Context, rows [][]any) error {
+	w.mu.Lock()
+	closed := w.closed
+	w.mu.Unlock()
+	if closed {
+		return ErrClosed
+	}
+	_, err := w.write(ctx, rows)
+	return err
+}
+
+// WriteBatchWithRetries re-sends the whole batch until it succeeds or
+// MaxRetries attempts fail. Some statements of a failed attempt may already
+// be committed, so only ModeUpsert makes the retried result exactly-once.
+func (w *BatchWriter) WriteBatchWithRetries(ctx context.Context, rows []Row) error {
+	var err error
+	for attempt := 0; attempt < w.cfg.MaxRetries; attempt++ {
+		if attempt > 0 {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(w.cfg.Backoff(attempt - 1)):
+			}
+		}
+		if err = w.WriteBatch(ctx, rows); err == nil || errors.Is(err, ErrClosed) {
+			return err
+		}
+	}
+	return fmt.Errorf("batchwriter: all %d attempts failed: %w", w.cfg.MaxRetries, err)
+}
+
+// Written reports the number of rows successfully flushed so far.
+func (w *BatchWriter) Written() int64 {
+	return w.written.Load()
+}
+
+// DeadLettered reports the number of rows moved to the dead-letter store.
+func (w *BatchWriter) DeadLettered() int64 {
+	return w.dead.Load()
+}
+
+// Close flushes outstanding rows and releases cached statements.
+func (w *BatchWriter) Close(ctx context.Context) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.closed {
+		return nil
+	}
+	err := w.flushLocked(ctx)
+	w.closed = true
+
+	w.stmtMu.Lock()
+	defer w.stmtMu.Unlock()
+	for n, stmt := range w.stmts {
+		if cerr := stmt.Close(); cerr != nil && err == nil {
+			err = cerr
+		}
+		delete(w.stmts, n)
+	}
+	return err
+}
+
+func (w *BatchWriter) flushLocked(ctx context.Context) error {
+	if len(w.buf) == 0 {
+		return nil
+	}
+	rows := w.buf
+	w.buf, w.sizes = nil, nil
+	w.size = 0
+	clear(w.index)
+
+	_, err := w.write(ctx, rows)
+	return err
+}
+
+// write inserts rows in statements of at most MaxRows rows and MaxBytes
+// outside the buffer, isolating poison rows when a dead-letter store is
+// configured. It returns how many leading rows were fully handled, so a
+// retry can resume there.
+func (w *BatchWriter) write(ctx context.Context, rows [][]any) (int, error) {
+	done := 0
+	for done < len(rows) {
+		end, size := done+1, estimateSize(rows[done])
+		for end < len(rows) && end-done < w.cfg.MaxRows {
+			if size += estimateSize(rows[end]); size > w.cfg.MaxBytes {
+				break
+			}
+			end++
+		}
+		chunk := rows[done:end]
+		if err := w.writeChunk(ctx, chunk); err != nil {
+			return done, err
+		}
+		done += len(chunk)
+	}
+	return done, nil
+}
+
+func (w *BatchWriter) writeChunk(ctx context.Context, rows [][]any) error {
+	err := w.exec(ctx, nil, rows)
+	if err == nil {
+		w.written.Add(int64(len(rows)))
+		return nil
+	}
+	if w.cfg.DeadLetter == nil || w.cfg.Transient(err) {
+		return err
+	}
+
+	n, letters, err := w.isolate(ctx, rows, err)
+	w.written.Add(int64(n))
+	if err != nil {
+		return err
+	}
+	return w.putDeadLetters(ctx, letters)
+}
+
+func (w *BatchWriter) putDeadLetters(ctx context.Context, letters []DeadLetter) error {
+	if err := w.cfg.DeadLetter.Put(ctx, letters); err != nil {
+		return fmt.Errorf("batchwriter: dead-letter %d rows: %w", len(letters), err)
+	}
+	w.dead.Add(int64(len(letters)))
+	return nil
+}
+
+// exec inserts rows with the cached statement for their shape, bound to tx
+// when one is given.
+func (w *BatchWriter) exec(ctx context.Context, tx *sql.Tx, rows [][]any) error {
+	stmt, err := w.stmt(ctx, len(rows))
+	if err != nil {
+		return err
+	}
+	if tx != nil {
+		stmt = tx.StmtContext(ctx, stmt)
+		defer stmt.Close()
+	}
+	args := make([]any, 0, len(rows)*len(w.columns))
+	for _, r := range rows {
+		args = append(args, r...)
+	}
+	if _, err := stmt.ExecContext(ctx, args...); err != nil {
+		return fmt.Errorf("batchwriter: insert %d rows: %w", len(rows), err)
+	}
+	return nil
+}
+
+// stmt returns the cached prepared statement for a batch of n rows.
+func (w *BatchWriter) stmt(ctx context.Context, n int) (*sql.Stmt, error) {
+	w.stmtMu.Lock()
+	defer w.stmtMu.Unlock()
+	if stmt, ok := w.stmts[n]; ok {
+		return stmt, nil
+	}
+	query := insertSQL(w.cfg.Dialect, w.cfg.Table, w.columns, n) + w.suffix
+	stmt, err := w.db.PrepareContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("batchwriter: prepare %d-row insert: %w", n, err)
+	}
+	w.stmts[n] = stmt
+	return stmt, nil
+}
+
+func (w *BatchWriter) dedupKey(values []any) string {
+	if len(w.keyIdx) == 1 {
+		return fmt.Sprint(values[w.keyIdx[0]])
+	}
+	parts := make([]any, len(w.keyIdx))
+	for i, k := range w.keyIdx {
+		parts[i] = values[k]
+	}
+	return fmt.Sprintf("%#v", parts)
+}
+
+func defaultBackoff(attempt int) time.Duration {
+	d := 100 * time.Millisecond << attempt
+	return d/2 + time.Duration(rand.Int63n(int64(d/2)+1))
+}
+
+func contains(list []string, s string) bool { return indexOf(list, s) >= 0 }
+
+func indexOf(list []string, s string) int {
+	for i, v := range list {
+		if v == s {
+			return i
+		}
+	}
+	return -1
+}
+
+// estimateSize approximates the wire size of a row's values.
+func estimateSize(values []any) int {
+	n := 0
+	for _, v := range values {
+		switch v := v.(type) {
+		case string:
+			n += len(v)
+		case []byte:
+			n += len(v)
+		case nil:
+			n++
+		case time.Time:
+			n += 24
+		default:
+			n += 8
+		}
+	}
+	return n
+}
@@ -0,0 +1,349 @@
+package batchwriter
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBatchWriterFlushesByRowCount(t *testing.T) {
+	fake := &fakeDB{}
+	db := openFake(fake)
+	defer db.Close()
+
+	w, err := New(db, Config{Table: "table_name", Columns: []string{"id", "name"}, MaxRows: 100})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	for i := 0; i < 1050; i++ {
+		if err := w.Add(ctx, BatchItem{ID: i + 1, Name: fmt.Sprintf("Item %d", i+1)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := fake.rowCount(); got != 1050 {
+		t.Fatalf("rows = %d, want 1050", got)
+	}
+	if got := len(fake.queries); got != 11 {
+		t.Fatalf("statements = %d, want 11", got)
+	}
+	// One shape for the full batches and one for the 50-row tail.
+	if fake.prepares != 2 {
+		t.Fatalf("prepares = %d, want 2", fake.prepares)
+	}
+	if w.Written() != 1050 {
+		t.Fatalf("Written = %d, want 1050", w.Written())
+	}
+}
+
+func TestBatchWriterFlushesByByteSize(t *testing.T) {
+	fake := &fakeDB{}
+	db := openFake(fake)
+	defer db.Close()
+
+	w, err := New(db, Config{Table: "users", Columns: []string{"id", "name"}, MaxBytes: 100})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	name := strings.Repeat("x", 42) // 50 bytes per row with the id
+	for i := 0; i < 4; i++ {
+		if err := w.Add(ctx, Record{ID: i, Name: name}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got := len(fake.queries); got != 2 {
+		t.Fatalf("statements = %d, want 2", got)
+	}
+}
+
+// gatedRow holds up the writer that lays it out until release is closed.
+type gatedRow struct {
+	Record
+	entered, release chan struct{}
+}
+
+func (r gatedRow) Values() []any {
+	close(r.entered)
+	<-r.release
+	return r.Record.Values()
+}
+
+func TestConcurrentWriteBatchesFailIndependently(t *testing.T) {
+	fake := &fakeDB{failExec: func(_ string, args []driver.Value) error {
+		for _, a := range args {
+			if a == "bad" {
+				return errors.New("CHECK constraint failed: name")
+			}
+		}
+		return nil
+	}}
+	db := openFake(fake)
+	defer db.Close()
+
+	w, err := New(db, Config{Table: "users", Columns: []string{"id", "name"}, Dialect: SQLite, MaxRows: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	gate := gatedRow{Record{ID: 5, Name: "good"}, make(chan struct{}), make(chan struct{})}
+	good := []Row{Record{ID: 1, Name: "good"}, Record{ID: 2, Name: "good"}, Record{ID: 3, Name: "good"}, Record{ID: 4, Name: "good"}, gate}
+	bad := []Row{Record{ID: 6, Name: "ok"}, Record{ID: 7, Name: "bad"}}
+
+	// The bad batch runs to completion while the good one is half laid out.
+	goodErr := make(chan error, 1)
+	go func() { goodErr <- w.WriteBatch(ctx, good) }()
+	<-gate.entered
+	if err := w.WriteBatch(ctx, bad); err == nil {
+		t.Fatal("bad batch succeeded")
+	}
+	close(gate.release)
+	if err := <-goodErr; err != nil {
+		t.Fatalf("good batch failed with the bad batch's error: %v", err)
+	}
+	if got := fake.rowCount(); got != len(good) {
+		t.Fatalf("rows = %d, want the %d of the good batch", got, len(good))
+	}
+}
+
+func TestInsertSQLPlaceholders(t *testing.T) {
+	tests := []struct {
+		dialect Dialect
+		want    string
+	}{
+		{MySQL, "INSERT INTO `t` (`id`, `name`) VALUES (?, ?), (?, ?)"},
+		{Postgres, `INSERT INTO "t" ("id", "name") VALUES ($1, $2), ($3, $4)`},
+		{SQLite, `INSERT INTO "t" ("id", "name") VALUES (?, ?), (?, ?)`},
+	}
+	for _, tt := range tests {
+		if got := insertSQL(tt.dialect, "t", []string{"id", "name"}, 2); got != tt.want {
+			t.Errorf("%s: got %q, want %q", tt.dialect.Name(), got, tt.want)
+		}
+	}
+}
+
+func TestRetriedBatchAfterConnectionKill(t *testing.T) {
+	records := make([]Row, 35)
+	for i := range records {
+		records[i] = Record{ID: i + 1, Name: fmt.Sprintf("name-%d", i+1)}
+	}
+	noBackoff := func(int) time.Duration { return 0 }
+
+	tests := []struct {
+		name string
+		cfg  Config
+		want int
+	}{
+		{
+			// The third statement commits before the connection dies, so
+			// a plain retry duplicates the first 30 rows.
+			name: "insert",
+			cfg:  Config{Mode: ModeInsert},
+			want: 65,
+		},
+		{
+			name: "upsert by key",
+			cfg:  Config{Mode: ModeUpsert, KeyColumns: []string{"id"}},
+			want: 35,
+		},
+		{
+			name: "upsert by idempotency key",
+			cfg: Config{Mode: ModeUpsert, IdempotencyKey: func(r Row) string {
+				return fmt.Sprintf("record-%d", r.(Record).ID)
+			}},
+			want: 35,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := &fakeDB{killOnExec: 3}
+			db := openFake(fake)
+			defer db.Close()
+
+			cfg := tt.cfg
+			cfg.Table, cfg.Columns, cfg.Dialect = "users", []string{"id", "name"}, SQLite
+			cfg.MaxRows, cfg.Backoff = 10, noBackoff
+			w, err := New(db, cfg)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := w.WriteBatchWithRetries(context.Background(), records); err != nil {
+				t.Fatal(err)
+			}
+			if got := fake.rowCount(); got != tt.want {
+				t.Fatalf("rows = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUpsertDedupsBufferedRows(t *testing.T) {
+	fake := &fakeDB{}
+	db := openFake(fake)
+	defer db.Close()
+
+	w, err := New(db, Config{
+		Table: "users", Columns: []string{"id", "name"}, Dialect: Postgres,
+		Mode: ModeUpsert, KeyColumns: []string{"id"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows := []Row{Record{1, "a"}, Record{2, "b"}, Record{1, "c"}}
+	if err := w.WriteBatch(context.Background(), rows); err != nil {
+		t.Fatal(err)
+	}
+	want := `INSERT INTO "users" ("id", "name") VALUES ($1, $2), ($3, $4) ON CONFLICT ("id") DO UPDATE SET "name" = excluded."name"`
+	if len(fake.queries) != 1 || fake.queries[0] != want {
+		t.Fatalf("queries = %q, want [%q]", fake.queries, want)
+	}
+	if got := fake.rows[0][1]; got != "c" {
+		t.Fatalf("row 1 name = %v, want last write to win", got)
+	}
+}
+
+func TestPoisonRowsAreDeadLetteredAndReplayed(t *testing.T) {
+	fake := &fakeDB{failExec: func(_ string, args []driver.Value) error {
+		for _, a := range args {
+			if a == "poison" {
+				return errors.New("CHECK constraint failed: name")
+			}
+		}
+		return nil
+	}}
+	db := openFake(fake)
+	defer db.Close()
+
+	store := NewJSONLStore(filepath.Join(t.TempDir(), "dead.jsonl"))
+	w, err := New(db, Config{
+		Table: "users", Columns: []string{"id", "name"}, Dialect: SQLite,
+		MaxRows: 20, DeadLetter: store,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	rows := make([]Row, 20)
+	for i := range rows {
+		name := fmt.Sprintf("name-%d", i)
+		if i == 3 || i == 17 {
+			name = "poison"
+		}
+		rows[i] = Record{ID: i, Name: name}
+	}
+	if err := w.WriteBatch(ctx, rows); err != nil {
+		t.Fatal(err)
+	}
+	if got := fake.rowCount(); got != 18 {
+		t.Fatalf("rows = %d, want 18", got)
+	}
+
+	letters, err := store.Load(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(letters) != 2 || w.DeadLettered() != 2 {
+		t.Fatalf("dead letters = %d (counter %d), want 2", len(letters), w.DeadLettered())
+	}
+	if letters[0].Error != "CHECK constraint failed: name" {
+		t.Fatalf("letter error = %q", letters[0].Error)
+	}
+
+	// Once the constraint is fixed the letters replay cleanly.
+	fake.failExec = nil
+	n, err := Replay(ctx, store, w)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 2 || fake.rowCount() != 20 {
+		t.Fatalf("replayed %d, rows = %d; want 2 and 20", n, fake.rowCount())
+	}
+	if left, _ := store.Load(ctx); len(left) != 0 {
+		t.Fatalf("%d letters left after replay", len(left))
+	}
+}
+
+func TestWriteBatchTxRollsBackToLastSavepoint(t *testing.T) {
+	fake := &fakeDB{}
+	db := openFake(fake)
+	defer db.Close()
+
+	w, err := New(db, Config{Table: "users", Columns: []string{"id", "name"}, Dialect: Postgres, MaxRows: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows := make([]Row, 25)
+	for i := range rows {
+		rows[i] = Record{ID: i + 1, Name: fmt.Sprintf("name-%d", i+1)}
+	}
+	fake.failExec = func(_ string, args []driver.Value) error {
+		for _, a := range args {
+			if a == "name-22" {
+				return errors.New("deadlock detected")
+			}
+		}
+		return nil
+	}
+
+	res, err := w.WriteBatchTx(context.Background(), rows, TxOptions{
+		Isolation:      sql.LevelSerializable,
+		SavepointEvery: 10,
+	})
+	if err == nil {
+		t.Fatal("expected the tail to fail")
+	}
+	if res.Committed != 20 || res.RolledBack != 5 || len(res.Pending) != 5 {
+		t.Fatalf("result = %+v, want 20 committed and 5 rolled back", res)
+	}
+	if got := fake.rowCount(); got != 20 {
+		t.Fatalf("rows = %d, want 20", got)
+	}
+	if fake.isolation != driver.IsolationLevel(sql.LevelSerializable) {
+		t.Fatalf("isolation = %v, want serializable", fake.isolation)
+	}
+}
+
+func TestWriteBatchTxAbortsOnCancel(t *testing.T) {
+	fake := &fakeDB{}
+	db := openFake(fake)
+	defer db.Close()
+
+	w, err := New(db, Config{Table: "users", Columns: []string{"id", "name"}, MaxRows: 5})
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows := make([]Row, 20)
+	for i := range rows {
+		rows[i] = Record{ID: i + 1, Name: "n"}
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	inserts := 0
+	fake.failExec = func(query string, _ []driver.Value) error {
+		if strings.HasPrefix(query, "INSERT") {
+			if inserts++; inserts == 3 {
+				cancel()
+				return context.Canceled
+			}
+		}
+		return nil
+	}
+
+	res, err := w.WriteBatchTx(ctx, rows, TxOptions{SavepointEvery: 5})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("err = %v, want context.Canceled", err)
+	}
+	if res.Committed != 0 || res.RolledBack != 20 || fake.rowCount() != 0 {
+		t.Fatalf("result = %+v with %d rows, want a full rollback", res, fake.rowCount())
+	}
+}
@@ -6,31 +6,35 @@ package main
 import (
 	"errors"
 	"log"
+	"myapp/errorreporter" // Replace with actual module path
 	"net/http"
-	"nain/errorreporter"
 )
 
 func main() {
 	// Initialize Sentry with your DSN
-	err := errorreporter.Init("https://examplePublicKey@o123456.ingest.sentry.io/987654")
+	sentrySink, err := errorreporter.NewSentrySink("https://examplePublicKey@o123456.ingest.sentry.io/987654")
 	if err != nil {
 		log.Fatalf("Failed to initialize Sentry: %v", err)
 	}
-	defer errorreporter.Flush(2 * time.Second)
+	reporter := &errorreporter.Reporter{}
+	if err := reporter.Init(sentrySink); err != nil {
+		log.Fatalf("Failed to initialize error reporter: %v", err)
+	}
+	defer reporter.Close()
 
-	http.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
-		// Simulate an error
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		// Simulate an error; the middleware captures it with the path,
+		// status, latency and request ID, so only the user is added here.
 		err := errors.New("an example error occurred")
-		context := map[string]interface{}{
-			"user_id": "12345",
-			"path":    r.URL.Path,
-		}
-		errorreporter.CaptureError(err, context)
+		scope := errorreporter.ScopeFrom(r.Context())
+		scope.SetUser("12345")
+		scope.RecordError(err)
 
 		w.WriteHeader(http.StatusInternalServerError)
 		w.Write([]byte("Error captured and reported."))
 	})
 
 	log.Println("Server is running on http://localhost:8080")
-	log.Fatal(http.ListenAndServe(":8080", nil))
+	log.Fatal(http.ListenAndServe(":8080", reporter.HTTPMiddleware(errorreporter.HTTPOptions{})(mux)))
 }
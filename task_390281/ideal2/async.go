@@ -0,0 +1,373 @@
+package errorreporter
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// DropPolicy decides which event is lost when the queue is full.
+type DropPolicy int
+
+const (
+	// DropNewest rejects the event being captured.
+	DropNewest DropPolicy = iota
+	// DropOldest evicts the oldest queued event to make room.
+	DropOldest
+)
+
+// AsyncOptions configures an AsyncSink.
+type AsyncOptions struct {
+	// QueueSize bounds the in-memory queue. Defaults to 1000.
+	QueueSize int
+	Drop      DropPolicy
+	// SpoolDir, when set, receives events that could not be delivered.
+	// They are retried every RetryInterval and replayed on restart.
+	SpoolDir string
+	// RetryInterval defaults to 30 seconds.
+	RetryInterval time.Duration
+	// CloseTimeout bounds how long Close waits for a send in flight.
+	// Defaults to 5 seconds.
+	CloseTimeout time.Duration
+}
+
+// AsyncSink decouples Capture from a slow or unreachable backend. Events
+// are queued in memory and delivered by a background goroutine; failed
+// deliveries are spooled to disk when SpoolDir is set.
+type AsyncSink struct {
+	inner Sink
+	opts  AsyncOptions
+	spool string
+
+	mu      sync.Mutex
+	queue   []*Event
+	down    bool
+	pending bool // spool holds events
+	closed  bool
+
+	notify  chan struct{}
+	flush   chan chan struct{}
+	done    chan struct{}
+	stopped chan struct{}
+
+	dropped atomic.Uint64
+}
+
+// NewAsyncSink wraps inner and starts its sender. Events left in the spool
+// by a previous process are replayed first.
+func NewAsyncSink(inner Sink, opts AsyncOptions) (*AsyncSink, error) {
+	if opts.QueueSize <= 0 {
+		opts.QueueSize = 1000
+	}
+	if opts.RetryInterval <= 0 {
+		opts.RetryInterval = 30 * time.Second
+	}
+	if opts.CloseTimeout <= 0 {
+		opts.CloseTimeout = 5 * time.Second
+	}
+	s := &AsyncSink{
+		inner:   inner,
+		opts:    opts,
+		notify:  make(chan struct{}, 1),
+		flush:   make(chan chan struct{}),
+		done:    make(chan struct{}),
+		stopped: make(chan struct{}),
+	}
+	if opts.SpoolDir != "" {
+		if err := os.MkdirAll(opts.SpoolDir, 0o755); err != nil {
+			return nil, err
+		}
+		s.spool = filepath.Join(opts.SpoolDir, spoolName(inner.Name())+".jsonl")
+		if info, err := os.Stat(s.spool); err == nil && info.Size() > 0 {
+			s.pending = true
+		}
+	}
+	go s.run()
+	return s, nil
+}
+
+// Name implements Sink.
+func (s *AsyncSink) Name() string { return "async:" + s.inner.Name() }
+
+// Send implements Sink. It never blocks; when the queue is full an event
+// is dropped according to the policy and counted.
+func (s *AsyncSink) Send(event *Event) error {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		s.dropped.Add(1)
+		return errors.New("async sink is closed")
+	}
+	if len(s.queue) >= s.opts.QueueSize {
+		s.dropped.Add(1)
+		if s.opts.Drop == DropNewest {
+			s.mu.Unlock()
+			return nil
+		}
+		s.queue = s.queue[1:]
+	}
+	s.queue = append(s.queue, event)
+	s.mu.Unlock()
+
+	select {
+	case s.notify <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// Dropped reports how many events were lost to the queue limit or Close.
+func (s *AsyncSink) Dropped() uint64 { return s.dropped.Load() }
+
+// Flush retries the spool, waits for the queue to drain and reports
+// whether every event has reached the backend.
+func (s *AsyncSink) Flush(timeout time.Duration) bool {
+	deadline := time.NewTimer(timeout)
+	defer deadline.Stop()
+
+	ack := make(chan struct{})
+	select {
+	case s.flush <- ack:
+	case <-s.stopped:
+		return false
+	case <-deadline.C:
+		return false
+	}
+	select {
+	case <-ack:
+	case <-deadline.C:
+		return false
+	}
+
+	s.mu.Lock()
+	delivered := len(s.queue) == 0 && !s.pending
+	s.mu.Unlock()
+	return delivered && s.inner.Flush(timeout)
+}
+
+// Close stops the sender without delivering the rest of the queue: it
+// waits up to CloseTimeout for the event in flight, spools the events
+// still queued, or drops and counts them without a spool, and closes the
+// wrapped sink. Call Flush first to deliver them.
+func (s *AsyncSink) Close() error {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return nil
+	}
+	s.closed = true
+	s.mu.Unlock()
+
+	close(s.done)
+	var err error
+	timer := time.NewTimer(s.opts.CloseTimeout)
+	select {
+	case <-s.stopped:
+		timer.Stop()
+	case <-timer.C:
+		// The sender is stuck in the backend; it stops after that send.
+		err = fmt.Errorf("async sink %s: send still in flight after %v", s.inner.Name(), s.opts.CloseTimeout)
+	}
+
+	s.mu.Lock()
+	rest := s.queue
+	s.queue = nil
+	s.mu.Unlock()
+	if len(rest) > 0 {
+		if s.spool == "" {
+			s.dropped.Add(uint64(len(rest)))
+		} else if err := s.appendSpool(rest); err != nil {
+			s.dropped.Add(uint64(len(rest)))
+			log.Printf("errorreporter: spool %s: %v", s.spool, err)
+		}
+	}
+	return errors.Join(err, s.inner.Close())
+}
+
+func (s *AsyncSink) run() {
+	defer close(s.stopped)
+	ticker := time.NewTicker(s.opts.RetryInterval)
+	defer ticker.Stop()
+
+	// Replay whatever a previous process left behind.
+	s.retrySpool()
+	for {
+		select {
+		case <-s.done:
+			return
+		case <-s.notify:
+			s.drain()
+		case ack := <-s.flush:
+			s.drain()
+			if s.retrySpool() {
+				s.drain()
+			}
+			close(ack)
+		case <-ticker.C:
+			if s.retrySpool() {
+				s.drain()
+			}
+		}
+	}
+}
+
+// drain delivers queued events until the queue is empty or Close is called.
+func (s *AsyncSink) drain() {
+	for {
+		select {
+		case <-s.done:
+			return
+		default:
+		}
+		s.mu.Lock()
+		if len(s.queue) == 0 {
+			s.mu.Unlock()
+			return
+		}
+		event := s.queue[0]
+		s.queue = s.queue[1:]
+		down := s.down
+		s.mu.Unlock()
+
+		// While the backend is known to be down, go straight to the spool
+		// to keep ordering and avoid hammering it.
+		if !down || s.spool == "" {
+			err := s.inner.Send(event)
+			if err == nil {
+				continue
+			}
+			if s.spool == "" {
+				log.Printf("errorreporter: sink %s: %v", s.inner.Name(), err)
+				continue
+			}
+			s.mu.Lock()
+			s.down = true
+			s.mu.Unlock()
+		}
+		if err := s.appendSpool([]*Event{event}); err != nil {
+			s.dropped.Add(1)
+			log.Printf("errorreporter: spool %s: %v", s.spool, err)
+		}
+	}
+}
+
+// retrySpool re-sends spooled events in order, keeping the ones after the
+// first failure. It reports whether the spool was emptied.
+func (s *AsyncSink) retrySpool() bool {
+	s.mu.Lock()
+	pending := s.pending
+	s.mu.Unlock()
+	if !pending {
+		return false
+	}
+
+	events, err := readSpool(s.spool)
+	if err != nil {
+		log.Printf("errorreporter: spool %s: %v", s.spool, err)
+		return false
+	}
+	sent := 0
+	for _, e := range events {
+		select {
+		case <-s.done:
+			// Close keeps the rest in the spool for the next process.
+		default:
+			if s.inner.Send(e) == nil {
+				sent++
+				continue
+			}
+		}
+		break
+	}
+	recovered := sent == len(events)
+	if err := writeSpool(s.spool, events[sent:]); err != nil {
+		log.Printf("errorreporter: spool %s: %v", s.spool, err)
+	}
+
+	s.mu.Lock()
+	s.down = !recovered
+	s.pending = !recovered
+	s.mu.Unlock()
+	return recovered
+}
+
+func (s *AsyncSink) appendSpool(events []*Event) error {
+	f, err := os.OpenFile(s.spool, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	enc := json.NewEncoder(f)
+	for _, e := range events {
+		if err := enc.Encode(e); err != nil {
+			f.Close()
+			return err
+		}
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	s.pending = true
+	s.mu.Unlock()
+	return nil
+}
+
+func readSpool(path string) ([]*Event, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var events []*Event
+	sc := bufio.NewScanner(bytes.NewReader(data))
+	sc.Buffer(make([]byte, 64*1024), 16<<20)
+	for sc.Scan() {
+		var e Event
+		if err := json.Unmarshal(sc.Bytes(), &e); err != nil {
+			continue // a torn write from a crash; skip it
+		}
+		e.Err = errors.New(e.Message)
+		events = append(events, &e)
+	}
+	return events, sc.Err()
+}
+
+// writeSpool atomically replaces the spool with events.
+func writeSpool(path string, events []*Event) error {
+	if len(events) == 0 {
+		err := os.Remove(path)
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return err
+	}
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	for _, e := range events {
+		if err := enc.Encode(e); err != nil {
+			return err
+		}
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, buf.Bytes(), 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+var unsafeChars = regexp.MustCompile(`[^A-Za-z0-9_.-]+`)
+
+func spoolName(sink string) string {
+	return unsafeChars.ReplaceAllString(sink, "_")
+}
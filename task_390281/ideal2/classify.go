@@ -0,0 +1,51 @@
+package errorreporter
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"net"
+)
+
+// Classifier maps an error to one of the Category constants. It returns
+// false when it does not recognize the error.
+type Classifier func(err error) (category string, ok bool)
+
+// MatchIs classifies errors that match target under errors.Is.
+func MatchIs(target error, category string) Classifier {
+	return func(err error) (string, bool) {
+		return category, errors.Is(err, target)
+	}
+}
+
+// MatchAs classifies errors that have a T in their chain under errors.As.
+func MatchAs[T error](category string) Classifier {
+	return func(err error) (string, bool) {
+		var target T
+		return category, errors.As(err, &target)
+	}
+}
+
+// DefaultClassifiers recognize the standard library's network, database
+// and context errors. They are consulted in order.
+var DefaultClassifiers = []Classifier{
+	MatchIs(context.Canceled, CategoryClient),
+	MatchIs(context.DeadlineExceeded, CategoryNetwork),
+	MatchIs(sql.ErrNoRows, CategoryDatabase),
+	MatchIs(sql.ErrConnDone, CategoryDatabase),
+	MatchIs(sql.ErrTxDone, CategoryDatabase),
+	MatchIs(driver.ErrBadConn, CategoryDatabase),
+	MatchAs[net.Error](CategoryNetwork),
+}
+
+// Classify returns the category of the first classifier that recognizes
+// err, or CategoryUnknown.
+func Classify(err error, classifiers []Classifier) string {
+	for _, c := range classifiers {
+		if category, ok := c(err); ok {
+			return category
+		}
+	}
+	return CategoryUnknown
+}
@@ -0,0 +1,52 @@
+
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+
+	errorreporter "github.com/shailendra-s-123/golang_random_5/task_390281/ideal2"
+)
+
+func main() {
+	// Initialize the error reporting service (Sentry)
+	sentrySink, err := errorreporter.NewSentrySink("https://examplePublicKey@o123456.ingest.sentry.io/987654") // Use your actual DSN here
+	if err != nil {
+		log.Fatalf("Failed to initialize Sentry: %v", err)
+	}
+	reporter := &errorreporter.Reporter{}
+	if err := reporter.Init(sentrySink, errorreporter.NewStdoutSink()); err != nil {
+		log.Fatalf("Failed to initialize error reporter: %v", err)
+	}
+	defer reporter.Close()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		// Simulate an application error
+		err := errors.New("an example application error")
+		scope := errorreporter.ScopeFrom(r.Context())
+		scope.SetUser("user_12345")
+		scope.Set("client_ip", r.RemoteAddr)
+
+		// The middleware reports the recorded error with the request context
+		scope.RecordError(err)
+
+		// Respond to the client
+		w.WriteHeader(http.StatusInternalServerError)
+		fmt.Fprintln(w, "An error occurred and has been reported.")
+	})
+	handler := reporter.HTTPMiddleware(errorreporter.HTTPOptions{})(mux)
+
+	log.Println("Server is running on http://localhost:8080")
+	if err := http.ListenAndServe(":8080", handler); err != nil {
+		reporter.Capture(err, nil, errorreporter.CategoryNetwork)
+		log.Fatalf("Server failed: %v", err)
+	}
+}
+
+
+
+
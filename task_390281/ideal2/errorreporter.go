@@ -3,59 +3,168 @@
 package errorreporter
 
 import (
+	"context"
+	"errors"
 	"fmt"
-	"github.com/getsentry/sentry-go"
 	"log"
+	"sync"
 	"time"
 )
 
 // Reporter is the structure for error reporting
 type Reporter struct {
-	dsn string
+	// CloseTimeout bounds the flush done by Close. Defaults to 2 seconds.
+	CloseTimeout time.Duration
+	// RateLimit caps events per fingerprint; the zero value disables it.
+	RateLimit RateLimit
+	// Classifiers pick the category when Capture is given none. Defaults
+	// to DefaultClassifiers.
+	Classifiers []Classifier
+	// Scrubber redacts events before any sink sees them. Defaults to
+	// DefaultScrubRules; set an empty Scrubber to disable scrubbing.
+	Scrubber *Scrubber
+	// MaxBreadcrumbs bounds the trail kept by AddBreadcrumb. Defaults to 50.
+	MaxBreadcrumbs int
+
+	mu     sync.RWMutex
+	sinks  []Sink
+	groups groups
+	now    func() time.Time
+
+	crumbMu sync.Mutex
+	crumbs  []Breadcrumb
 }
 
-// Init initializes the error reporter with a given DSN.
-func (r *Reporter) Init(dsn string) error {
-	r.dsn = dsn
-	if err := sentry.Init(sentry.ClientOptions{
-		Dsn: r.dsn,
-	}); err != nil {
-		return fmt.Errorf("Sentry initialization failed: %w", err)
+// Init initializes the error reporter with the sinks every event fans out to.
+func (r *Reporter) Init(sinks ...Sink) error {
+	if len(sinks) == 0 {
+		return errors.New("errorreporter: at least one sink is required")
 	}
-	log.Println("Sentry initialized successfully.")
+	r.mu.Lock()
+	r.sinks = sinks
+	r.mu.Unlock()
+	log.Printf("Error reporter initialized with %d sink(s).", len(sinks))
 	return nil
 }
 
-// Flush sends any buffered events to the monitoring service.
+// Flush sends any buffered events to the monitoring services.
 func (r *Reporter) Flush(timeout time.Duration) {
-	sentry.Flush(timeout)
+	r.each(func(s Sink) error {
+		if !s.Flush(timeout) {
+			return fmt.Errorf("flush timed out after %v", timeout)
+		}
+		return nil
+	})
+}
+
+// AddBreadcrumb records a step that is attached to subsequent events.
+// Only the most recent MaxBreadcrumbs are kept.
+func (r *Reporter) AddBreadcrumb(b Breadcrumb) {
+	if b.Timestamp.IsZero() {
+		b.Timestamp = time.Now().UTC()
+	}
+	limit := r.MaxBreadcrumbs
+	if limit <= 0 {
+		limit = defaultMaxBreadcrumbs
+	}
+	r.crumbMu.Lock()
+	defer r.crumbMu.Unlock()
+	r.crumbs = appendBreadcrumb(r.crumbs, b, limit)
 }
 
-// Capture categorizes and captures an error with metadata.
+// Capture categorizes and captures an error with metadata. An empty or
+// unknown category is replaced by the first matching classifier's.
 func (r *Reporter) Capture(err error, context map[string]interface{}, category string) {
+	r.capture(err, context, category, nil)
+}
+
+// CaptureContext is Capture with the data and breadcrumbs of the scope
+// carried by ctx. Entries in extra take precedence over scope data.
+func (r *Reporter) CaptureContext(ctx context.Context, err error, extra map[string]interface{}, category string) {
+	r.capture(err, extra, category, ScopeFrom(ctx))
+}
+
+func (r *Reporter) capture(err error, extra map[string]interface{}, category string, scope *Scope) {
 	if err == nil {
 		return
 	}
+	if category == "" || category == CategoryUnknown {
+		classifiers := r.Classifiers
+		if classifiers == nil {
+			classifiers = DefaultClassifiers
+		}
+		category = Classify(err, classifiers)
+	}
+
+	data, scopeCrumbs := scope.snapshot()
+	if data == nil {
+		data = extra
+	} else {
+		for k, v := range extra {
+			data[k] = v
+		}
+	}
 
-	// Start a new scope to attach metadata
-	sentry.WithScope(func(scope *sentry.Scope) {
-		for key, value := range context {
-			scope.SetExtra(key, value)
+	event := newEvent(err, data, category)
+	event.Fingerprint = Fingerprint(err, category, callerFrames())
+	if r.RateLimit.Rate > 0 {
+		now := time.Now
+		if r.now != nil {
+			now = r.now
 		}
+		ok, suppressed := r.groups.allow(r.RateLimit, event.Fingerprint, now())
+		if !ok {
+			return
+		}
+		event.Suppressed = suppressed
+	}
 
-		// Add the error category as a tag
-		scope.SetTag("category", category)
+	r.crumbMu.Lock()
+	event.Breadcrumbs = append([]Breadcrumb(nil), r.crumbs...)
+	r.crumbMu.Unlock()
+	event.Breadcrumbs = append(event.Breadcrumbs, scopeCrumbs...)
 
-		// Capture the exception
-		sentry.CaptureException(err)
-	})
+	scrubber := r.Scrubber
+	if scrubber == nil {
+		scrubber = defaultScrubber
+	}
+	scrubber.Scrub(event)
+	r.each(func(s Sink) error { return s.Send(event) })
 
-	log.Printf("Error captured (%s): %v\n", category, err)
+	log.Printf("Error captured (%s): %s\n", category, event.Message)
 }
 
 // Close cleans up the reporter on application exit.
 func (r *Reporter) Close() {
-	r.Flush(2 * time.Second)
+	timeout := r.CloseTimeout
+	if timeout <= 0 {
+		timeout = 2 * time.Second
+	}
+	r.Flush(timeout)
+	r.each(Sink.Close)
+}
+
+// each calls fn for every sink. A sink that fails or panics is logged and
+// does not affect the others.
+func (r *Reporter) each(fn func(Sink) error) {
+	r.mu.RLock()
+	sinks := r.sinks
+	r.mu.RUnlock()
+
+	for _, s := range sinks {
+		if err := safeCall(s, fn); err != nil {
+			log.Printf("errorreporter: sink %s: %v", s.Name(), err)
+		}
+	}
+}
+
+func safeCall(s Sink, fn func(Sink) error) (err error) {
+	defer func() {
+		if rec := recover(); rec != nil {
+			err = fmt.Errorf("panic: %v", rec)
+		}
+	}()
+	return fn(s)
 }
 
 
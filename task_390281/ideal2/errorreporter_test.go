@@ -0,0 +1,408 @@
+package errorreporter
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/getsentry/sentry-go"
+)
+
+type failingSink struct {
+	MemorySink
+	panics bool
+}
+
+func (s *failingSink) Name() string { return "failing" }
+
+func (s *failingSink) Send(*Event) error {
+	if s.panics {
+		panic("sink exploded")
+	}
+	return errors.New("backend unavailable")
+}
+
+func TestCaptureFansOutAndIsolatesSinkFailures(t *testing.T) {
+	first, last := &MemorySink{}, &MemorySink{}
+	r := &Reporter{}
+	if err := r.Init(first, &failingSink{}, &failingSink{panics: true}, last); err != nil {
+		t.Fatal(err)
+	}
+
+	r.Capture(errors.New("boom"), map[string]interface{}{"user_id": "42"}, CategoryDatabase)
+	r.Flush(time.Second)
+
+	for _, s := range []*MemorySink{first, last} {
+		events := s.Events()
+		if len(events) != 1 {
+			t.Fatalf("sink got %d events, want 1", len(events))
+		}
+		if e := events[0]; e.Message != "boom" || e.Category != CategoryDatabase || e.Context["user_id"] != "42" {
+			t.Fatalf("unexpected event %+v", e)
+		}
+	}
+}
+
+func TestInitRequiresSink(t *testing.T) {
+	if err := (&Reporter{}).Init(); err == nil {
+		t.Fatal("expected an error without sinks")
+	}
+}
+
+// fakeIngest is an httptest ingest endpoint that can be taken down.
+type fakeIngest struct {
+	*httptest.Server
+	up       atomic.Bool
+	mu       sync.Mutex
+	received []string
+}
+
+func newFakeIngest(t *testing.T) *fakeIngest {
+	f := &fakeIngest{}
+	f.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !f.up.Load() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		var e Event
+		if err := json.NewDecoder(r.Body).Decode(&e); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		f.mu.Lock()
+		f.received = append(f.received, e.Message)
+		f.mu.Unlock()
+	}))
+	t.Cleanup(f.Close)
+	return f
+}
+
+func (f *fakeIngest) messages() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]string(nil), f.received...)
+}
+
+func TestAsyncSinkSpoolsAndReplaysAfterRestart(t *testing.T) {
+	ingest := newFakeIngest(t)
+	spool := t.TempDir()
+	opts := AsyncOptions{SpoolDir: spool, RetryInterval: time.Hour}
+
+	sink, err := NewAsyncSink(&HTTPSink{URL: ingest.URL}, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := &Reporter{}
+	r.Init(sink)
+	for i := 0; i < 3; i++ {
+		r.Capture(fmt.Errorf("event %d", i), nil, CategoryNetwork)
+	}
+	if sink.Flush(time.Second) {
+		t.Fatal("Flush reported delivery while the backend is down")
+	}
+	r.Close()
+	if got := len(ingest.messages()); got != 0 {
+		t.Fatalf("backend received %d events while down", got)
+	}
+
+	// The backend recovers and the process restarts.
+	ingest.up.Store(true)
+	sink, err = NewAsyncSink(&HTTPSink{URL: ingest.URL}, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sink.Close()
+	if !sink.Flush(time.Second) {
+		t.Fatal("Flush did not deliver the spooled events")
+	}
+	want := []string{"event 0", "event 1", "event 2"}
+	if got := ingest.messages(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("received %q, want %q", got, want)
+	}
+	if entries, _ := os.ReadDir(spool); len(entries) != 0 {
+		t.Fatalf("spool not emptied: %v", entries)
+	}
+}
+
+// blockingSink holds the sender inside Send until release is closed.
+type blockingSink struct {
+	MemorySink
+	started chan struct{}
+	release chan struct{}
+}
+
+func (s *blockingSink) Send(e *Event) error {
+	select {
+	case s.started <- struct{}{}:
+	default:
+	}
+	<-s.release
+	return s.MemorySink.Send(e)
+}
+
+func TestAsyncSinkDropPolicies(t *testing.T) {
+	tests := []struct {
+		policy DropPolicy
+		want   []string
+	}{
+		{DropNewest, []string{"0", "1", "2"}},
+		{DropOldest, []string{"0", "3", "4"}},
+	}
+	for _, tt := range tests {
+		inner := &blockingSink{started: make(chan struct{}, 1), release: make(chan struct{})}
+		sink, err := NewAsyncSink(inner, AsyncOptions{QueueSize: 2, Drop: tt.policy})
+		if err != nil {
+			t.Fatal(err)
+		}
+		sink.Send(&Event{Message: "0"})
+		<-inner.started // "0" is in flight, the queue is empty
+		for _, m := range []string{"1", "2", "3", "4"} {
+			sink.Send(&Event{Message: m})
+		}
+		close(inner.release)
+		sink.Flush(time.Second)
+		sink.Close()
+
+		var got []string
+		for _, e := range inner.Events() {
+			got = append(got, e.Message)
+		}
+		if !reflect.DeepEqual(got, tt.want) || sink.Dropped() != 2 {
+			t.Fatalf("policy %d: delivered %q with %d dropped, want %q and 2", tt.policy, got, sink.Dropped(), tt.want)
+		}
+	}
+}
+
+func TestAsyncSinkCloseIsBounded(t *testing.T) {
+	spool := t.TempDir()
+	inner := &blockingSink{started: make(chan struct{}, 1), release: make(chan struct{})}
+	defer close(inner.release)
+	sink, err := NewAsyncSink(inner, AsyncOptions{SpoolDir: spool, CloseTimeout: 50 * time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sink.Send(&Event{Message: "0"})
+	<-inner.started // "0" hangs in the backend
+	for _, m := range []string{"1", "2", "3"} {
+		sink.Send(&Event{Message: m})
+	}
+	start := time.Now()
+	if err := sink.Close(); err == nil {
+		t.Fatal("Close hid the send in flight")
+	}
+	if d := time.Since(start); d > time.Second {
+		t.Fatalf("Close took %v", d)
+	}
+	events, err := readSpool(sink.spool)
+	if err != nil || len(events) != 3 || events[0].Message != "1" {
+		t.Fatalf("spooled %v, %v", events, err)
+	}
+}
+
+// slowSink takes a while to deliver each event.
+type slowSink struct {
+	MemorySink
+}
+
+func (s *slowSink) Send(e *Event) error {
+	time.Sleep(10 * time.Millisecond)
+	return s.MemorySink.Send(e)
+}
+
+func TestAsyncSinkCloseStopsDraining(t *testing.T) {
+	inner := &slowSink{}
+	sink, err := NewAsyncSink(inner, AsyncOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 100; i++ {
+		sink.Send(&Event{Message: fmt.Sprint(i)})
+	}
+	time.Sleep(25 * time.Millisecond)
+	start := time.Now()
+	sink.Close()
+	if d := time.Since(start); d > 200*time.Millisecond {
+		t.Fatalf("Close took %v, sending the whole queue", d)
+	}
+	if sent := len(inner.Events()); sent+int(sink.Dropped()) != 100 || sent > 20 {
+		t.Fatalf("sent %d and dropped %d of 100", sent, sink.Dropped())
+	}
+}
+
+func TestFingerprintMasksVolatileParts(t *testing.T) {
+	wrap := func(id int) error {
+		return fmt.Errorf("load user: %w", &net.OpError{Op: "dial", Err: fmt.Errorf("user %d not found at 0x%x", id, id*7)})
+	}
+	frames := []string{"main.handler"}
+
+	a := Fingerprint(wrap(123), CategoryNetwork, frames)
+	if b := Fingerprint(wrap(98765), CategoryNetwork, frames); a != b {
+		t.Fatalf("fingerprints differ for the same failure: %s vs %s", a, b)
+	}
+	if b := Fingerprint(wrap(123), CategoryDatabase, frames); a == b {
+		t.Fatal("category does not feed the fingerprint")
+	}
+	if b := Fingerprint(errors.New("user 123 not found at 0x35"), CategoryNetwork, frames); a == b {
+		t.Fatal("error chain types do not feed the fingerprint")
+	}
+	if b := Fingerprint(wrap(123), CategoryNetwork, []string{"main.other"}); a == b {
+		t.Fatal("caller frames do not feed the fingerprint")
+	}
+}
+
+func TestCaptureRateLimitsHotLoop(t *testing.T) {
+	mem := &MemorySink{}
+	now := time.Unix(0, 0)
+	r := &Reporter{RateLimit: RateLimit{Rate: 1, Burst: 2}, now: func() time.Time { return now }}
+	r.Init(mem)
+
+	capture := func(i int) { r.Capture(fmt.Errorf("query %d failed", i), nil, CategoryDatabase) }
+	for i := 0; i < 100; i++ {
+		capture(i)
+	}
+	if got := len(mem.Events()); got != 2 {
+		t.Fatalf("delivered %d events, want the burst of 2", got)
+	}
+
+	now = now.Add(time.Second)
+	capture(100)
+	events := mem.Events()
+	if len(events) != 3 {
+		t.Fatalf("delivered %d events after refill, want 3", len(events))
+	}
+	if last := events[2]; last.Suppressed != 98 || last.Fingerprint != events[0].Fingerprint {
+		t.Fatalf("summary event = %+v, want 98 suppressed in the same group", last)
+	}
+
+	r.Capture(errors.New("another failure"), nil, CategoryDatabase)
+	if got := len(mem.Events()); got != 4 {
+		t.Fatal("a different fingerprint was rate limited")
+	}
+}
+
+func TestSentrySinkSendsContextAndSuppressedCount(t *testing.T) {
+	var got []*sentry.Event
+	client, err := sentry.NewClient(sentry.ClientOptions{
+		BeforeSend: func(e *sentry.Event, _ *sentry.EventHint) *sentry.Event {
+			got = append(got, e)
+			return nil
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sink := &SentrySink{hub: sentry.NewHub(client, sentry.NewScope())}
+	sink.Send(&Event{
+		Err:         errors.New("query failed"),
+		Category:    CategoryDatabase,
+		Context:     map[string]interface{}{"table": "users"},
+		Fingerprint: "abc",
+		Suppressed:  98,
+	})
+
+	if len(got) != 1 {
+		t.Fatalf("captured %d events", len(got))
+	}
+	e := got[0]
+	extra := e.Contexts["extra"]
+	if extra["table"] != "users" || extra["suppressed_occurrences"] != 98 {
+		t.Fatalf("extra context = %v", extra)
+	}
+	if e.Tags["category"] != CategoryDatabase || !reflect.DeepEqual(e.Fingerprint, []string{"abc"}) {
+		t.Fatalf("tags %v, fingerprint %v", e.Tags, e.Fingerprint)
+	}
+}
+
+func TestCaptureClassifiesUncategorizedErrors(t *testing.T) {
+	sink := &MemorySink{}
+	r := &Reporter{}
+	r.Init(sink)
+
+	dnsErr := &net.DNSError{Err: "no such host", Name: "db.internal"}
+	r.Capture(fmt.Errorf("dial: %w", dnsErr), nil, "")
+	r.Capture(fmt.Errorf("load user: %w", sql.ErrNoRows), nil, CategoryUnknown)
+	r.Capture(context.DeadlineExceeded, nil, "")
+	r.Capture(errors.New("invariant broken"), nil, "")
+	r.Capture(sql.ErrNoRows, nil, CategoryLogic)
+
+	want := []string{CategoryNetwork, CategoryDatabase, CategoryNetwork, CategoryUnknown, CategoryLogic}
+	events := sink.Events()
+	if len(events) != len(want) {
+		t.Fatalf("got %d events, want %d", len(events), len(want))
+	}
+	for i, e := range events {
+		if e.Category != want[i] {
+			t.Errorf("event %d (%s): category %q, want %q", i, e.Message, e.Category, want[i])
+		}
+	}
+}
+
+func TestCaptureScrubsBeforeSinks(t *testing.T) {
+	sink := &MemorySink{}
+	r := &Reporter{}
+	r.Init(sink)
+
+	ctx := map[string]interface{}{
+		"password": "hunter2",
+		"request": map[string]interface{}{
+			"Authorization": "Bearer abc.def",
+			"note":          "card 4111 1111 1111 1111, order 1234567890123",
+		},
+		"recipients": []string{"ann@example.com"},
+	}
+	r.AddBreadcrumb(Breadcrumb{Message: "login jane@example.com", Data: map[string]interface{}{"api_key": "k"}})
+	r.Capture(fmt.Errorf("notify jane@example.com: %w", errors.New("smtp down")), ctx, CategoryNetwork)
+
+	e := sink.Events()[0]
+	if e.Message != "notify [REDACTED]: smtp down" || e.Err.Error() != e.Message || errors.Unwrap(e.Err) != nil {
+		t.Fatalf("message not scrubbed: %q / %v", e.Message, e.Err)
+	}
+	req := e.Context["request"].(map[string]interface{})
+	if e.Context["password"] != Redacted || req["Authorization"] != Redacted {
+		t.Fatalf("secret keys not scrubbed: %+v", e.Context)
+	}
+	if got := req["note"]; got != "card [REDACTED], order 1234567890123" {
+		t.Fatalf("note = %q", got)
+	}
+	if got := e.Context["recipients"].([]string)[0]; got != Redacted {
+		t.Fatalf("recipient = %q", got)
+	}
+	if b := e.Breadcrumbs[0]; b.Message != "login [REDACTED]" || b.Data["api_key"] != Redacted {
+		t.Fatalf("breadcrumb not scrubbed: %+v", b)
+	}
+	if ctx["password"] != "hunter2" {
+		t.Fatal("caller's context was modified")
+	}
+}
+
+func TestScrubKeysMatchWholeSegments(t *testing.T) {
+	redacted := []string{"password", "Authorization", "api_key", "apiKey", "X-Api-Key", "access_token", "refreshToken", "HTTPCookie", "Set-Cookie", "session_id", "db.secret", "cardNumber"}
+	kept := []string{"monkey", "tokenizer_version", "author", "passenger", "sessions_total", "keyboard", "turkey", "cardinality"}
+	ctx := map[string]interface{}{}
+	for _, k := range append(redacted, kept...) {
+		ctx[k] = "v"
+	}
+	event := &Event{Context: ctx}
+	defaultScrubber.Scrub(event)
+	for _, k := range redacted {
+		if event.Context[k] != Redacted {
+			t.Errorf("%s not redacted", k)
+		}
+	}
+	for _, k := range kept {
+		if event.Context[k] != "v" {
+			t.Errorf("%s redacted", k)
+		}
+	}
+}
@@ -0,0 +1,193 @@
+package errorreporter
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"regexp"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+)
+
+// maxFrames is the number of caller frames that feed the fingerprint.
+const maxFrames = 3
+
+var (
+	uuidPattern   = regexp.MustCompile(`(?i)\b[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}\b`)
+	hexPattern    = regexp.MustCompile(`(?i)\b0x[0-9a-f]+\b|\b[0-9a-f]{16,}\b`)
+	numberPattern = regexp.MustCompile(`\d+(\.\d+)?`)
+	quotedPattern = regexp.MustCompile(`"[^"]*"|'[^']*'`)
+)
+
+// Fingerprint returns a stable grouping key for err. It combines the
+// category, the types along the error chain, the message with volatile
+// parts (ids, numbers, quoted values) masked, and the given caller frames.
+func Fingerprint(err error, category string, frames []string) string {
+	h := sha1.New()
+	fmt.Fprintf(h, "%s\n", category)
+	for _, t := range chainTypes(err) {
+		fmt.Fprintf(h, "%s\n", t)
+	}
+	fmt.Fprintf(h, "%s\n", normalizeMessage(err.Error()))
+	for _, f := range frames {
+		fmt.Fprintf(h, "%s\n", f)
+	}
+	return hex.EncodeToString(h.Sum(nil))[:16]
+}
+
+// chainTypes lists the dynamic types of err and everything it wraps,
+// depth first, following both Unwrap() error and Unwrap() []error.
+func chainTypes(err error) []string {
+	var types []string
+	var walk func(error)
+	walk = func(err error) {
+		for err != nil {
+			types = append(types, fmt.Sprintf("%T", err))
+			if multi, ok := err.(interface{ Unwrap() []error }); ok {
+				for _, e := range multi.Unwrap() {
+					walk(e)
+				}
+				return
+			}
+			err = errors.Unwrap(err)
+		}
+	}
+	walk(err)
+	return types
+}
+
+func normalizeMessage(msg string) string {
+	msg = quotedPattern.ReplaceAllString(msg, "<str>")
+	msg = uuidPattern.ReplaceAllString(msg, "<uuid>")
+	msg = hexPattern.ReplaceAllString(msg, "<hex>")
+	msg = numberPattern.ReplaceAllString(msg, "<n>")
+	return msg
+}
+
+// callerFrames returns the functions that called into the reporter,
+// innermost first. The reporter's own frames, its middleware included, and
+// those of the runtime, net/http and gRPC are skipped: they are the same
+// for every request, or differ only by transport, and would split groups
+// that belong together.
+func callerFrames() []string {
+	pcs := make([]uintptr, 32)
+	n := runtime.Callers(2, pcs)
+	frames := runtime.CallersFrames(pcs[:n])
+
+	var out []string
+	for len(out) < maxFrames {
+		f, more := frames.Next()
+		if !isReporterFrame(f.Function) && !isFrameworkFrame(f.Function) {
+			out = append(out, f.Function)
+		}
+		if !more {
+			break
+		}
+	}
+	return out
+}
+
+// frameworkPrefixes name the packages whose frames never identify the
+// failing code.
+var frameworkPrefixes = []string{
+	"runtime.",
+	"net/http.",
+	"golang.org/x/net/http2.",
+	"google.golang.org/grpc.",
+	"google.golang.org/grpc/",
+}
+
+func isFrameworkFrame(function string) bool {
+	for _, p := range frameworkPrefixes {
+		if strings.HasPrefix(function, p) {
+			return true
+		}
+	}
+	return false
+}
+
+// pkgPath is this package's import path, taken from a function name such
+// as "myapp/errorreporter.init.func1".
+var pkgPath = func() string {
+	pc, _, _, _ := runtime.Caller(0)
+	name := runtime.FuncForPC(pc).Name()
+	slash := strings.LastIndex(name, "/")
+	return name[:slash+1+strings.Index(name[slash+1:], ".")]
+}()
+
+func isReporterFrame(function string) bool {
+	return strings.HasPrefix(function, pkgPath+".")
+}
+
+// RateLimit caps how many events per fingerprint reach the sinks.
+type RateLimit struct {
+	// Rate is the sustained number of events per second per fingerprint.
+	// Zero disables rate limiting.
+	Rate float64
+	// Burst is the number of events let through at once. Defaults to 1.
+	Burst int
+}
+
+// maxGroups bounds the number of fingerprints tracked at once.
+const maxGroups = 10000
+
+// group is a token bucket for one fingerprint.
+type group struct {
+	tokens     float64
+	last       time.Time
+	suppressed int
+}
+
+// groups rate-limits events by fingerprint.
+type groups struct {
+	mu    sync.Mutex
+	byKey map[string]*group
+}
+
+// allow spends a token for fingerprint. When the event is let through it
+// returns the number of occurrences suppressed since the previous one.
+func (g *groups) allow(limit RateLimit, fingerprint string, now time.Time) (bool, int) {
+	burst := float64(max(limit.Burst, 1))
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.byKey == nil {
+		g.byKey = make(map[string]*group)
+	}
+	b, ok := g.byKey[fingerprint]
+	if !ok {
+		if len(g.byKey) >= maxGroups {
+			g.evict(limit, burst, now)
+		}
+		b = &group{tokens: burst, last: now}
+		g.byKey[fingerprint] = b
+	}
+
+	b.tokens = min(burst, b.tokens+now.Sub(b.last).Seconds()*limit.Rate)
+	b.last = now
+	if b.tokens < 1 {
+		b.suppressed++
+		return false, 0
+	}
+	b.tokens--
+	suppressed := b.suppressed
+	b.suppressed = 0
+	return true, suppressed
+}
+
+// evict forgets groups whose bucket has refilled and that hold no
+// suppressed count, i.e. groups indistinguishable from new ones. If none
+// qualify, the map is reset rather than allowed to grow.
+func (g *groups) evict(limit RateLimit, burst float64, now time.Time) {
+	for key, b := range g.byKey {
+		if b.suppressed == 0 && b.tokens+now.Sub(b.last).Seconds()*limit.Rate >= burst {
+			delete(g.byKey, key)
+		}
+	}
+	if len(g.byKey) >= maxGroups {
+		clear(g.byKey)
+	}
+}
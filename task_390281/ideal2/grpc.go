@@ -0,0 +1,121 @@
+package errorreporter
+
+import (
+	"context"
+	"fmt"
+	"runtime/debug"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// GRPCOptions configures the gRPC interceptors.
+type GRPCOptions struct {
+	// RequestIDKey is the metadata key carrying the request ID. Defaults
+	// to "x-request-id"; missing IDs are generated.
+	RequestIDKey string
+	// UserID extracts the user from the call context, e.g. from auth
+	// metadata.
+	UserID func(context.Context) string
+	// Report decides which returned errors are captured. Defaults to
+	// server-side codes: Unknown, DeadlineExceeded, Unimplemented,
+	// Internal, Unavailable and DataLoss.
+	Report func(codes.Code) bool
+}
+
+// UnaryServerInterceptor recovers panics and reports failed calls, tagged
+// with the method, status code, latency, request ID and user ID. Handlers
+// find a Scope in their context.
+func (r *Reporter) UnaryServerInterceptor(opts GRPCOptions) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp interface{}, err error) {
+		ctx, done := r.startCall(ctx, info.FullMethod, opts)
+		defer func() { err = done(recover(), err) }()
+		return handler(ctx, req)
+	}
+}
+
+// StreamServerInterceptor is the streaming counterpart of
+// UnaryServerInterceptor. The stream's context carries the Scope.
+func (r *Reporter) StreamServerInterceptor(opts GRPCOptions) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) (err error) {
+		ctx, done := r.startCall(ss.Context(), info.FullMethod, opts)
+		defer func() { err = done(recover(), err) }()
+		return handler(srv, &scopedStream{ServerStream: ss, ctx: ctx})
+	}
+}
+
+// startCall sets up the call's scope. The returned function, given the
+// recovered panic value and the handler's error, reports the outcome and
+// returns the error to send to the client.
+func (r *Reporter) startCall(ctx context.Context, method string, opts GRPCOptions) (context.Context, func(interface{}, error) error) {
+	start := time.Now()
+	key := opts.RequestIDKey
+	if key == "" {
+		key = "x-request-id"
+	}
+	report := opts.Report
+	if report == nil {
+		report = serverCode
+	}
+
+	ctx, scope := NewScope(ctx)
+	requestID := ""
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if ids := md.Get(key); len(ids) > 0 {
+			requestID = ids[0]
+		}
+	}
+	if requestID == "" {
+		requestID = newRequestID()
+	}
+	scope.Set("request_id", requestID)
+	scope.Set("method", method)
+	if opts.UserID != nil {
+		if id := opts.UserID(ctx); id != "" {
+			scope.SetUser(id)
+		}
+	}
+
+	return ctx, func(rec interface{}, err error) error {
+		category := ""
+		if rec != nil {
+			scope.Set("stack", string(debug.Stack()))
+			category = CategoryLogic
+			// The panic value and stack go to the captured event only;
+			// the client learns nothing about the server's internals.
+			err = status.Error(codes.Internal, "internal error")
+		}
+		code := status.Code(err)
+		if err == nil || (rec == nil && !report(code)) {
+			return err
+		}
+		scope.Set("status", code.String())
+		scope.Set("latency_ms", time.Since(start).Milliseconds())
+		captured := err
+		if rec != nil {
+			captured = fmt.Errorf("panic: %v", rec)
+		}
+		r.CaptureContext(ctx, captured, nil, category)
+		return err
+	}
+}
+
+func serverCode(code codes.Code) bool {
+	switch code {
+	case codes.Unknown, codes.DeadlineExceeded, codes.Unimplemented,
+		codes.Internal, codes.Unavailable, codes.DataLoss:
+		return true
+	}
+	return false
+}
+
+// scopedStream replaces a stream's context with one carrying the Scope.
+type scopedStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *scopedStream) Context() context.Context { return s.ctx }
@@ -0,0 +1,125 @@
+package errorreporter
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"runtime/debug"
+	"time"
+)
+
+// HTTPOptions configures Reporter.HTTPMiddleware.
+type HTTPOptions struct {
+	// RequestIDHeader is read for an incoming request ID and set on the
+	// response. Defaults to "X-Request-ID"; missing IDs are generated.
+	RequestIDHeader string
+	// UserID extracts the user from the request. Handlers can also call
+	// Scope.SetUser once they have authenticated the caller.
+	UserID func(*http.Request) string
+}
+
+// HTTPMiddleware recovers panics and reports them, along with every 5xx
+// response, tagged with the method, route, status, latency, request ID and
+// user ID. Each request gets a Scope in its context; handlers use it to add
+// breadcrumbs and to RecordError the cause of a failure.
+func (r *Reporter) HTTPMiddleware(opts HTTPOptions) func(http.Handler) http.Handler {
+	header := opts.RequestIDHeader
+	if header == "" {
+		header = "X-Request-ID"
+	}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			start := time.Now()
+			ctx, scope := NewScope(req.Context())
+			requestID := req.Header.Get(header)
+			if requestID == "" {
+				requestID = newRequestID()
+			}
+			w.Header().Set(header, requestID)
+			scope.Set("request_id", requestID)
+			scope.Set("method", req.Method)
+			if opts.UserID != nil {
+				if id := opts.UserID(req); id != "" {
+					scope.SetUser(id)
+				}
+			}
+
+			sw := &statusWriter{ResponseWriter: w}
+			req = req.WithContext(ctx)
+			defer func() {
+				rec := recover()
+				if rec == http.ErrAbortHandler {
+					panic(rec)
+				}
+				var err error
+				category := ""
+				if rec != nil {
+					err, category = fmt.Errorf("panic: %v", rec), CategoryLogic
+					scope.Set("stack", string(debug.Stack()))
+					if !sw.wroteHeader {
+						http.Error(sw, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+					}
+				} else if sw.status() >= 500 {
+					if err = scope.Err(); err == nil {
+						err = fmt.Errorf("%s %s responded %d", req.Method, route(req), sw.status())
+					}
+				}
+				if err == nil {
+					return
+				}
+				scope.Set("route", route(req))
+				scope.Set("status", sw.status())
+				scope.Set("latency_ms", time.Since(start).Milliseconds())
+				r.CaptureContext(ctx, err, nil, category)
+			}()
+			next.ServeHTTP(sw, req)
+		})
+	}
+}
+
+// route prefers the ServeMux pattern that matched, which groups requests
+// for different IDs under one route.
+func route(req *http.Request) string {
+	if req.Pattern != "" {
+		return req.Pattern
+	}
+	return req.URL.Path
+}
+
+// statusWriter records the status written by a handler.
+type statusWriter struct {
+	http.ResponseWriter
+	code        int
+	wroteHeader bool
+}
+
+func (w *statusWriter) WriteHeader(code int) {
+	if !w.wroteHeader {
+		w.code, w.wroteHeader = code, true
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *statusWriter) Write(b []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *statusWriter) status() int {
+	if !w.wroteHeader {
+		return http.StatusOK
+	}
+	return w.code
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer.
+func (w *statusWriter) Unwrap() http.ResponseWriter { return w.ResponseWriter }
+
+func newRequestID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,163 @@
+package errorreporter
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+func TestHTTPMiddleware(t *testing.T) {
+	sink := &MemorySink{}
+	r := &Reporter{}
+	r.Init(sink)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /users/{id}", func(w http.ResponseWriter, req *http.Request) {
+		scope := ScopeFrom(req.Context())
+		scope.SetUser(req.PathValue("id"))
+		scope.AddBreadcrumb(Breadcrumb{Category: "db", Message: "select user"})
+		scope.RecordError(fmt.Errorf("load user: %w", sql.ErrConnDone))
+		w.WriteHeader(http.StatusServiceUnavailable)
+	})
+	mux.HandleFunc("GET /panic", func(http.ResponseWriter, *http.Request) { panic("nil map") })
+	mux.HandleFunc("GET /ok", func(w http.ResponseWriter, _ *http.Request) { w.Write([]byte("ok")) })
+	srv := httptest.NewServer(r.HTTPMiddleware(HTTPOptions{})(mux))
+	defer srv.Close()
+
+	get := func(path, requestID string) *http.Response {
+		req, _ := http.NewRequest(http.MethodGet, srv.URL+path, nil)
+		if requestID != "" {
+			req.Header.Set("X-Request-ID", requestID)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		return resp
+	}
+
+	get("/ok", "")
+	if resp := get("/users/7", "req-1"); resp.Header.Get("X-Request-ID") != "req-1" {
+		t.Fatalf("request ID not echoed: %v", resp.Header)
+	}
+	if resp := get("/panic", ""); resp.StatusCode != http.StatusInternalServerError {
+		t.Fatalf("panic answered %d", resp.StatusCode)
+	}
+
+	events := sink.Events()
+	if len(events) != 2 {
+		t.Fatalf("got %d events, want 2", len(events))
+	}
+	e := events[0]
+	if e.Category != CategoryDatabase || e.Message != "load user: sql: connection is already closed" {
+		t.Fatalf("unexpected event %+v", e)
+	}
+	for k, want := range map[string]interface{}{
+		"route": "GET /users/{id}", "status": 503, "request_id": "req-1", "user_id": "7", "method": "GET",
+	} {
+		if e.Context[k] != want {
+			t.Errorf("context[%s] = %v, want %v", k, e.Context[k], want)
+		}
+	}
+	if _, ok := e.Context["latency_ms"]; !ok {
+		t.Error("latency missing")
+	}
+	if len(e.Breadcrumbs) != 1 || e.Breadcrumbs[0].Message != "select user" {
+		t.Errorf("breadcrumbs = %+v", e.Breadcrumbs)
+	}
+	if p := events[1]; p.Message != "panic: nil map" || p.Category != CategoryLogic || p.Context["stack"] == nil {
+		t.Fatalf("unexpected panic event %+v", p)
+	}
+}
+
+type fakeServerStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *fakeServerStream) Context() context.Context { return s.ctx }
+
+func TestCallerFramesSkipFrameworks(t *testing.T) {
+	r := &Reporter{}
+	r.Init(&MemorySink{})
+
+	var frames []string
+	h := r.HTTPMiddleware(HTTPOptions{})(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {
+		frames = callerFrames()
+	}))
+	srv := httptest.NewServer(h)
+	defer srv.Close()
+	resp, err := http.Get(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	// The handler and middleware belong to this package and the rest of
+	// the stack to net/http and the runtime, so nothing is left.
+	if len(frames) != 0 {
+		t.Fatalf("frames %v", frames)
+	}
+}
+
+func TestGRPCInterceptors(t *testing.T) {
+	sink := &MemorySink{}
+	r := &Reporter{}
+	r.Init(sink)
+	opts := GRPCOptions{UserID: func(ctx context.Context) string {
+		md, _ := metadata.FromIncomingContext(ctx)
+		return md.Get("user")[0]
+	}}
+	unary := r.UnaryServerInterceptor(opts)
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs("x-request-id", "abc", "user", "u1"))
+	info := &grpc.UnaryServerInfo{FullMethod: "/svc.Users/Get"}
+
+	_, err := unary(ctx, nil, info, func(ctx context.Context, _ interface{}) (interface{}, error) {
+		return nil, status.Error(codes.NotFound, "no such user")
+	})
+	if status.Code(err) != codes.NotFound {
+		t.Fatalf("err = %v", err)
+	}
+	_, err = unary(ctx, nil, info, func(ctx context.Context, _ interface{}) (interface{}, error) {
+		ScopeFrom(ctx).AddBreadcrumb(Breadcrumb{Message: "cache miss"})
+		return nil, status.Error(codes.Unavailable, "backend down")
+	})
+	if status.Code(err) != codes.Unavailable {
+		t.Fatalf("err = %v", err)
+	}
+
+	stream := r.StreamServerInterceptor(opts)
+	err = stream(nil, &fakeServerStream{ctx: ctx}, &grpc.StreamServerInfo{FullMethod: "/svc.Users/Watch"},
+		func(_ interface{}, ss grpc.ServerStream) error {
+			if ScopeFrom(ss.Context()) == nil {
+				return errors.New("stream context has no scope")
+			}
+			panic("stream broke")
+		})
+	if s := status.Convert(err); s.Code() != codes.Internal || strings.Contains(s.Message(), "stream broke") {
+		t.Fatalf("panic returned %v", err)
+	}
+
+	events := sink.Events()
+	if len(events) != 2 {
+		t.Fatalf("got %d events, want 2", len(events))
+	}
+	if e := events[0]; e.Context["method"] != "/svc.Users/Get" || e.Context["status"] != "Unavailable" ||
+		e.Context["request_id"] != "abc" || e.Context["user_id"] != "u1" || len(e.Breadcrumbs) != 1 {
+		t.Fatalf("unexpected event %+v", e)
+	}
+	if e := events[1]; e.Message != "panic: stream broke" || e.Context["method"] != "/svc.Users/Watch" {
+		t.Fatalf("unexpected panic event %+v", e)
+	}
+}
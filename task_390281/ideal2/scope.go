@@ -0,0 +1,112 @@
+package errorreporter
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// defaultMaxBreadcrumbs bounds breadcrumb trails unless configured.
+const defaultMaxBreadcrumbs = 50
+
+// Scope carries per-request data through a context.Context so nested calls
+// can add detail to whatever the request eventually reports. All methods
+// are safe on a nil *Scope, which ignores them.
+type Scope struct {
+	mu     sync.Mutex
+	data   map[string]interface{}
+	crumbs []Breadcrumb
+	err    error
+}
+
+type scopeKey struct{}
+
+// NewScope returns a context carrying a new scope. The scope starts with a
+// copy of the data and breadcrumbs of any scope already in ctx.
+func NewScope(ctx context.Context) (context.Context, *Scope) {
+	s := &Scope{data: make(map[string]interface{})}
+	if parent := ScopeFrom(ctx); parent != nil {
+		parent.mu.Lock()
+		for k, v := range parent.data {
+			s.data[k] = v
+		}
+		s.crumbs = append([]Breadcrumb(nil), parent.crumbs...)
+		parent.mu.Unlock()
+	}
+	return context.WithValue(ctx, scopeKey{}, s), s
+}
+
+// ScopeFrom returns the scope carried by ctx, or nil.
+func ScopeFrom(ctx context.Context) *Scope {
+	s, _ := ctx.Value(scopeKey{}).(*Scope)
+	return s
+}
+
+// Set attaches a value to every event captured with this scope.
+func (s *Scope) Set(key string, value interface{}) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.data[key] = value
+}
+
+// SetUser records the user the request acts for.
+func (s *Scope) SetUser(id string) { s.Set("user_id", id) }
+
+// AddBreadcrumb records a step, keeping the most recent ones.
+func (s *Scope) AddBreadcrumb(b Breadcrumb) {
+	if s == nil {
+		return
+	}
+	if b.Timestamp.IsZero() {
+		b.Timestamp = time.Now().UTC()
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.crumbs = appendBreadcrumb(s.crumbs, b, defaultMaxBreadcrumbs)
+}
+
+// RecordError notes the error behind a failed request. Middleware reports
+// it in place of a generic status error when the request fails.
+func (s *Scope) RecordError(err error) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.err = err
+}
+
+// Err returns the error passed to RecordError.
+func (s *Scope) Err() error {
+	if s == nil {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.err
+}
+
+// snapshot returns copies of the scope's data and breadcrumbs.
+func (s *Scope) snapshot() (map[string]interface{}, []Breadcrumb) {
+	if s == nil {
+		return nil, nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	data := make(map[string]interface{}, len(s.data))
+	for k, v := range s.data {
+		data[k] = v
+	}
+	return data, append([]Breadcrumb(nil), s.crumbs...)
+}
+
+func appendBreadcrumb(crumbs []Breadcrumb, b Breadcrumb, limit int) []Breadcrumb {
+	crumbs = append(crumbs, b)
+	if n := len(crumbs); n > limit {
+		crumbs = append([]Breadcrumb(nil), crumbs[n-limit:]...)
+	}
+	return crumbs
+}
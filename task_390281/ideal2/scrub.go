@@ -0,0 +1,193 @@
+package errorreporter
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// Redacted replaces scrubbed data unless a rule sets its own replacement.
+const Redacted = "[REDACTED]"
+
+// ScrubRule redacts sensitive data. A rule with Key redacts the whole value
+// stored under a matching key; a rule with Value redacts every matching
+// substring of string values. Validate, when set, must also accept a
+// Value match before it is redacted.
+//
+// Key is matched against the key in lower snake case, so apiKey, API-Key
+// and api.key all read api_key.
+type ScrubRule struct {
+	Name        string
+	Key         *regexp.Regexp
+	Value       *regexp.Regexp
+	Validate    func(match string) bool
+	Replacement string
+}
+
+// DefaultScrubRules cover credentials, emails, bearer tokens, JWTs and
+// card numbers. Secret key names must be whole segments of the key:
+// access_token is redacted, tokenizer_version and monkey are not.
+var DefaultScrubRules = []ScrubRule{
+	{Name: "secret-keys", Key: regexp.MustCompile(`^(?:.*_)?(?:pass(?:word|wd)?|secrets?|tokens?|api_?keys?|auth(?:orization)?|cookies?|session(?:_?id)?|credit_card|card_number|cvv|ssn)(?:_.*)?$`)},
+	{Name: "email", Value: regexp.MustCompile(`[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}`)},
+	{Name: "bearer-token", Value: regexp.MustCompile(`(?i)bearer\s+[A-Za-z0-9._~+/-]+=*`)},
+	{Name: "jwt", Value: regexp.MustCompile(`eyJ[A-Za-z0-9_-]+\.[A-Za-z0-9_-]+\.[A-Za-z0-9_-]+`)},
+	{Name: "card-number", Value: regexp.MustCompile(`\b(?:\d[ -]?){12,18}\d\b`), Validate: luhn},
+}
+
+// Scrubber applies scrub rules to events before any sink sees them.
+type Scrubber struct {
+	Rules []ScrubRule
+}
+
+var defaultScrubber = &Scrubber{Rules: DefaultScrubRules}
+
+// Scrub redacts the event's message, context and breadcrumbs in place.
+// Context maps are copied, so the caller's maps are left untouched.
+func (s *Scrubber) Scrub(event *Event) {
+	if msg := s.scrubString(event.Message); msg != event.Message {
+		event.Message = msg
+		// The original error would leak the message through Err.
+		event.Err = &scrubbedError{msg: msg}
+	}
+	event.Context = s.scrubMap(event.Context)
+	crumbs := make([]Breadcrumb, len(event.Breadcrumbs))
+	for i, b := range event.Breadcrumbs {
+		b.Message = s.scrubString(b.Message)
+		b.Data = s.scrubMap(b.Data)
+		crumbs[i] = b
+	}
+	if event.Breadcrumbs != nil {
+		event.Breadcrumbs = crumbs
+	}
+}
+
+func (s *Scrubber) scrubMap(m map[string]interface{}) map[string]interface{} {
+	if m == nil {
+		return nil
+	}
+	out := make(map[string]interface{}, len(m))
+	for k, v := range m {
+		out[k] = s.scrubValue(k, v)
+	}
+	return out
+}
+
+func (s *Scrubber) scrubValue(key string, v interface{}) interface{} {
+	name := keyName(key)
+	for _, r := range s.Rules {
+		if r.Key != nil && r.Key.MatchString(name) {
+			return r.replacement()
+		}
+	}
+	switch v := v.(type) {
+	case string:
+		return s.scrubString(v)
+	case map[string]interface{}:
+		return s.scrubMap(v)
+	case map[string]string:
+		out := make(map[string]interface{}, len(v))
+		for k, val := range v {
+			out[k] = s.scrubValue(k, val)
+		}
+		return out
+	case []interface{}:
+		out := make([]interface{}, len(v))
+		for i, val := range v {
+			out[i] = s.scrubValue(key, val)
+		}
+		return out
+	case []string:
+		out := make([]string, len(v))
+		for i, val := range v {
+			out[i] = s.scrubString(val)
+		}
+		return out
+	case error:
+		return s.scrubString(v.Error())
+	case fmt.Stringer:
+		return s.scrubString(v.String())
+	}
+	return v
+}
+
+func (s *Scrubber) scrubString(str string) string {
+	for _, r := range s.Rules {
+		if r.Value == nil {
+			continue
+		}
+		str = r.Value.ReplaceAllStringFunc(str, func(match string) string {
+			if r.Validate != nil && !r.Validate(match) {
+				return match
+			}
+			return r.replacement()
+		})
+	}
+	return str
+}
+
+// keyName turns key into lower snake case, splitting it at punctuation,
+// spaces and camel-case humps: X-AuthToken and xAuthToken become
+// x_auth_token, HTTPCookie becomes http_cookie.
+func keyName(key string) string {
+	rs := []rune(key)
+	var b strings.Builder
+	sep := false
+	for i, r := range rs {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			sep = b.Len() > 0
+			continue
+		}
+		if unicode.IsUpper(r) && i > 0 {
+			prev := rs[i-1]
+			nextLower := i+1 < len(rs) && unicode.IsLower(rs[i+1])
+			if unicode.IsLower(prev) || unicode.IsDigit(prev) || unicode.IsUpper(prev) && nextLower {
+				sep = true
+			}
+		}
+		if sep && b.Len() > 0 {
+			b.WriteByte('_')
+		}
+		sep = false
+		b.WriteRune(unicode.ToLower(r))
+	}
+	return b.String()
+}
+
+func (r ScrubRule) replacement() string {
+	if r.Replacement != "" {
+		return r.Replacement
+	}
+	return Redacted
+}
+
+// scrubbedError stands in for an error whose message had to be redacted.
+// It deliberately does not unwrap to the original.
+type scrubbedError struct {
+	msg string
+}
+
+func (e *scrubbedError) Error() string { return e.msg }
+
+// luhn reports whether the digits in s pass the Luhn checksum, which
+// filters out most digit runs that are not card numbers.
+func luhn(s string) bool {
+	digits := strings.Map(func(r rune) rune {
+		if r >= '0' && r <= '9' {
+			return r
+		}
+		return -1
+	}, s)
+	sum := 0
+	for i := len(digits) - 1; i >= 0; i-- {
+		d := int(digits[i] - '0')
+		if (len(digits)-i)%2 == 0 {
+			if d *= 2; d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+	}
+	return len(digits) >= 13 && sum%10 == 0
+}
@@ -0,0 +1,239 @@
+package errorreporter
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/getsentry/sentry-go"
+)
+
+// Event is a captured error as handed to every sink.
+type Event struct {
+	Timestamp time.Time              `json:"timestamp"`
+	Category  string                 `json:"category"`
+	Message   string                 `json:"message"`
+	ErrorType string                 `json:"error_type"`
+	Context   map[string]interface{} `json:"context,omitempty"`
+
+	// Fingerprint groups events caused by the same failure.
+	Fingerprint string `json:"fingerprint"`
+	// Suppressed counts occurrences of this fingerprint dropped by rate
+	// limiting since the previous event that got through.
+	Suppressed int `json:"suppressed,omitempty"`
+
+	// Breadcrumbs are the steps recorded before the error, oldest first.
+	Breadcrumbs []Breadcrumb `json:"breadcrumbs,omitempty"`
+
+	// Err is the original error. It is not serialized.
+	Err error `json:"-"`
+}
+
+// Breadcrumb is a step recorded before an error, giving it context.
+type Breadcrumb struct {
+	Timestamp time.Time              `json:"timestamp"`
+	Category  string                 `json:"category,omitempty"`
+	Message   string                 `json:"message"`
+	Data      map[string]interface{} `json:"data,omitempty"`
+}
+
+func newEvent(err error, context map[string]interface{}, category string) *Event {
+	return &Event{
+		Timestamp: time.Now().UTC(),
+		Category:  category,
+		Message:   err.Error(),
+		ErrorType: fmt.Sprintf("%T", err),
+		Context:   context,
+		Err:       err,
+	}
+}
+
+// Sink delivers events to one backend. Events are shared between sinks
+// and must not be modified.
+type Sink interface {
+	Name() string
+	Send(event *Event) error
+	// Flush waits up to timeout for buffered events to be delivered and
+	// reports whether everything was delivered.
+	Flush(timeout time.Duration) bool
+	Close() error
+}
+
+// SentrySink sends events to Sentry through its own client, so several
+// reporters can coexist in one process.
+type SentrySink struct {
+	hub *sentry.Hub
+}
+
+// NewSentrySink creates a Sentry client for the given DSN.
+func NewSentrySink(dsn string) (*SentrySink, error) {
+	client, err := sentry.NewClient(sentry.ClientOptions{Dsn: dsn})
+	if err != nil {
+		return nil, fmt.Errorf("Sentry initialization failed: %w", err)
+	}
+	return &SentrySink{hub: sentry.NewHub(client, sentry.NewScope())}, nil
+}
+
+// Name implements Sink.
+func (s *SentrySink) Name() string { return "sentry" }
+
+// Send implements Sink.
+func (s *SentrySink) Send(event *Event) error {
+	s.hub.WithScope(func(scope *sentry.Scope) {
+		// Arbitrary values go in the "extra" context; scopes have no
+		// extras in the pinned sentry-go.
+		extra := sentry.Context{}
+		for key, value := range event.Context {
+			extra[key] = value
+		}
+		if event.Suppressed > 0 {
+			extra["suppressed_occurrences"] = event.Suppressed
+		}
+		scope.SetContext("extra", extra)
+		scope.SetTag("category", event.Category)
+		if event.Fingerprint != "" {
+			scope.SetFingerprint([]string{event.Fingerprint})
+		}
+		for _, b := range event.Breadcrumbs {
+			scope.AddBreadcrumb(&sentry.Breadcrumb{
+				Timestamp: b.Timestamp,
+				Category:  b.Category,
+				Message:   b.Message,
+				Data:      b.Data,
+			}, len(event.Breadcrumbs))
+		}
+		s.hub.CaptureException(event.Err)
+	})
+	return nil
+}
+
+// Flush implements Sink.
+func (s *SentrySink) Flush(timeout time.Duration) bool { return s.hub.Flush(timeout) }
+
+// Close implements Sink.
+func (s *SentrySink) Close() error { return nil }
+
+// HTTPSink posts each event as JSON to an ingest endpoint. Any non-2xx
+// response is reported as a delivery failure.
+type HTTPSink struct {
+	URL string
+	// Client defaults to one with a 10 second timeout, so a hung backend
+	// cannot hold up the sender forever.
+	Client *http.Client
+}
+
+var defaultHTTPClient = &http.Client{Timeout: 10 * time.Second}
+
+// Name implements Sink.
+func (s *HTTPSink) Name() string { return "http:" + s.URL }
+
+// Send implements Sink.
+func (s *HTTPSink) Send(event *Event) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	client := s.Client
+	if client == nil {
+		client = defaultHTTPClient
+	}
+	resp, err := client.Post(s.URL, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("ingest responded %s", resp.Status)
+	}
+	return nil
+}
+
+// Flush implements Sink. Sends are synchronous.
+func (s *HTTPSink) Flush(time.Duration) bool { return true }
+
+// Close implements Sink.
+func (s *HTTPSink) Close() error { return nil }
+
+// WriterSink writes one JSON object per event to an io.Writer.
+type WriterSink struct {
+	name string
+	mu   sync.Mutex
+	w    io.Writer
+	c    io.Closer
+}
+
+// NewStdoutSink returns a sink that prints events to standard output.
+func NewStdoutSink() *WriterSink {
+	return &WriterSink{name: "stdout", w: os.Stdout}
+}
+
+// NewFileSink returns a sink that appends events to a JSONL file.
+func NewFileSink(path string) (*WriterSink, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	return &WriterSink{name: "file:" + path, w: f, c: f}, nil
+}
+
+// Name implements Sink.
+func (s *WriterSink) Name() string { return s.name }
+
+// Send implements Sink.
+func (s *WriterSink) Send(event *Event) error {
+	line, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, err = s.w.Write(append(line, '\n'))
+	return err
+}
+
+// Flush implements Sink. Writes are unbuffered.
+func (s *WriterSink) Flush(time.Duration) bool { return true }
+
+// Close implements Sink.
+func (s *WriterSink) Close() error {
+	if s.c == nil {
+		return nil
+	}
+	return s.c.Close()
+}
+
+// MemorySink records events in memory, for tests.
+type MemorySink struct {
+	mu     sync.Mutex
+	events []*Event
+}
+
+// Name implements Sink.
+func (s *MemorySink) Name() string { return "memory" }
+
+// Send implements Sink.
+func (s *MemorySink) Send(event *Event) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.events = append(s.events, event)
+	return nil
+}
+
+// Flush implements Sink.
+func (s *MemorySink) Flush(time.Duration) bool { return true }
+
+// Close implements Sink.
+func (s *MemorySink) Close() error { return nil }
+
+// Events returns the events recorded so far.
+func (s *MemorySink) Events() []*Event {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]*Event(nil), s.events...)
+}
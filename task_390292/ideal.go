@@ -1,19 +1,21 @@
-
 package main
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
 	"time"
+
+	"github.com/shailendra-s-123/golang_random_5/task_390292/pipeline"
 )
 
 // Data represents a single unit of data in the pipeline.
 type Data struct {
-	Raw         string
-	Processed   string
-	Errors      []error
+	Raw       string
+	Processed string
 }
 
 // Fetcher defines a method for fetching data from an API.
@@ -21,95 +23,73 @@ type Fetcher struct {
 	Url string
 }
 
-func (f *Fetcher) Fetch(ctx context.Context, out chan<- Data, errCh chan<- error) {
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, f.Url, nil)
-	if err != nil {
-		errCh <- fmt.Errorf("fetch: %v", err)
-		return
+// Fetch streams every item of every page, honoring rate limits and 429s.
+func (f *Fetcher) Fetch(ctx context.Context, emit func(Data) error) error {
+	source := &pipeline.HTTPSource{
+		URL:               f.Url,
+		Pagination:        pipeline.LinkPagination{},
+		RequestsPerSecond: 5,
 	}
-
-	resp, err := http.DefaultClient.Do(req)
-	if err != nil {
-		errCh <- fmt.Errorf("fetch: %v", err)
-		return
-	}
-	defer resp.Body.Close()
-
-	// Simulate reading response, here we just send a dummy data
-	out <- Data{Raw: "some raw data"}
+	return pipeline.Pages[json.RawMessage](source)(ctx, "", func(_ string, raw json.RawMessage) error {
+		return emit(Data{Raw: string(raw)})
+	})
 }
 
 // Processor defines a stage in the processing pipeline.
 type Processor struct {
 }
 
-func (p *Processor) Process(in <-chan Data, out chan<- Data, errCh chan<- error) {
-	for data := range in {
-		// Simulate processing
-		time.Sleep(time.Millisecond * 100) // simulate processing delay
-		data.Processed = fmt.Sprintf("processed: %s", data.Raw)
-		if data.Processed == "" {
-			errCh <- fmt.Errorf("processing error for data: %v", data)
-			continue
-		}
-		out <- data
+func (p *Processor) Process(ctx context.Context, data Data) (Data, error) {
+	// Simulate processing
+	time.Sleep(time.Millisecond * 100) // simulate processing delay
+	data.Processed = fmt.Sprintf("processed: %s", data.Raw)
+	if data.Processed == "" {
+		return data, fmt.Errorf("processing error for data: %v", data)
 	}
+	return data, nil
 }
 
 // Writer defines a stage for sending data to a database.
 type Writer struct {
 }
 
-func (w *Writer) Write(in <-chan Data, errCh chan<- error) {
-	for data := range in {
-		if len(data.Errors) > 0 {
-			errCh <- fmt.Errorf("error processing data: %v", data.Errors)
-			continue
-		}
-		// Simulate writing to a database
-		fmt.Printf("writing to database: %s\n", data.Processed)
-	}
+func (w *Writer) Write(ctx context.Context, data Data) (Data, error) {
+	// Simulate writing to a database
+	fmt.Printf("writing to database: %s\n", data.Processed)
+	return data, nil
 }
 
 func main() {
-	ctx, cancel := context.WithCancel(context.Background())
+	// The demo runs for two seconds; hitting that deadline is how it stops.
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*2)
 	defer cancel()
 
-	// Error channel to propagate errors through the pipeline
-	errCh := make(chan error)
-
 	// Create pipeline stages
 	fetcher := &Fetcher{Url: "http://example.com/api/data"}
 	processor := &Processor{}
 	writer := &Writer{}
 
-	// Create channels for each stage
-	dataChan := make(chan Data)
-	processedChan := make(chan Data)
-
-	// Start goroutines for each pipeline stage
-	go func() {
-		fetcher.Fetch(ctx, dataChan, errCh)
-	}()
-
-	go processor.Process(dataChan, processedChan, errCh)
-
-	go writer.Write(processedChan, errCh)
-
-	// Wait for errors or completion
+	// Serve live stage counters to spot the bottleneck stage
+	metrics := pipeline.NewMetrics()
+	http.Handle("/debug/pipeline", metrics.Handler())
 	go func() {
-		for err := range errCh {
-			log.Printf("Error in pipeline: %v", err)
-		}
+		log.Println(http.ListenAndServe("localhost:9090", nil))
 	}()
 
-	// Let the pipeline run for some time, simulating data streaming
-	time.Sleep(time.Second * 2)
+	// Each stage closes its own output channel when it is done
+	p := pipeline.From("fetch", fetcher.Fetch).
+		Then(pipeline.Stage[Data, Data]{Name: "process", Workers: 4, Fn: processor.Process}).
+		Then(pipeline.Stage[Data, Data]{Name: "write", Fn: writer.Write}).
+		WithMetrics(metrics)
 
-	// Cleanly close channels
-	close(dataChan)
-	close(processedChan)
-	close(errCh)
+	err := p.Run(ctx)
+	if errors.Is(err, context.DeadlineExceeded) {
+		log.Println("Pipeline stopped at the deadline.")
+		return
+	}
+	if err != nil {
+		log.Fatalf("Error in pipeline: %v", err)
+	}
 
 	log.Println("Pipeline finished.")
 }
@@ -0,0 +1,280 @@
+package pipeline
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// CheckpointStore remembers, per source, the offset up to which every
+// item has been fully processed.
+type CheckpointStore interface {
+	// Load returns the committed offset, or "" if there is none.
+	Load(ctx context.Context, source string) (string, error)
+	Save(ctx context.Context, source, offset string) error
+}
+
+// OffsetSource reads the items after offset from ("" means from the
+// start), passing each to emit along with its own offset. Offsets are
+// opaque to the pipeline: a position, a cursor or a timestamp.
+type OffsetSource[T any] func(ctx context.Context, from string, emit func(offset string, item T) error) error
+
+// CheckpointOptions configures FromCheckpoint.
+type CheckpointOptions struct {
+	// Interval is the minimum time between saves while running. The final
+	// offset is always saved when the run ends. Zero saves on every
+	// advance.
+	Interval time.Duration
+}
+
+// FromCheckpoint starts a pipeline with a source that resumes after the
+// offset committed by the previous run. An item's offset is committed once
+// it and every item emitted before it have left the pipeline, so a crash
+// re-delivers at most the items that were in flight. Pair it with an
+// idempotent sink (see WriteTo) for effectively exactly-once delivery.
+func FromCheckpoint[T any](name string, store CheckpointStore, opts CheckpointOptions, source OffsetSource[T]) *Pipeline[T] {
+	return &Pipeline[T]{name: name, start: func(ctx context.Context, g *group) <-chan msg[T] {
+		out := make(chan msg[T])
+		sm := g.metrics.stage(name, "", 0, nil)
+		t := &tracker{name: name, store: store, interval: opts.Interval}
+		g.onFinish(func(ctx context.Context, _ error) error {
+			return t.commit(ctx, true)
+		})
+		g.Go(func() error {
+			defer close(out)
+			from, err := store.Load(ctx, name)
+			if err != nil {
+				return fmt.Errorf("pipeline: source %s: load checkpoint: %w", name, err)
+			}
+			t.committed, t.saved = from, from
+			err = source(ctx, from, func(offset string, item T) error {
+				m := msg[T]{item: item, meta: g.newItem(name, t.track(ctx, offset))}
+				select {
+				case out <- m:
+					sm.add(countOut, 1)
+					return nil
+				case <-ctx.Done():
+					return ctx.Err()
+				}
+			})
+			if err != nil {
+				return fmt.Errorf("pipeline: source %s: %w", name, err)
+			}
+			return nil
+		})
+		return out
+	}}
+}
+
+// Offset returns the source offset of the item a stage function is
+// processing, if it came from a checkpointed source.
+func Offset(ctx context.Context) (string, bool) {
+	m, _ := ctx.Value(metaKey{}).(*meta)
+	if m == nil || m.offset == "" {
+		return "", false
+	}
+	return m.offset, true
+}
+
+// tracker turns out-of-order acks into a committed offset: the offset of
+// the last item whose predecessors are all done.
+type tracker struct {
+	name     string
+	store    CheckpointStore
+	interval time.Duration
+
+	mu        sync.Mutex
+	offsets   []string // in emission order, from base
+	acked     []bool
+	base      int
+	committed string
+	saved     string
+	lastSave  time.Time
+}
+
+func (t *tracker) track(ctx context.Context, offset string) *meta {
+	t.mu.Lock()
+	seq := t.base + len(t.offsets)
+	t.offsets = append(t.offsets, offset)
+	t.acked = append(t.acked, false)
+	t.mu.Unlock()
+	return &meta{offset: offset, ack: func() { t.ack(ctx, seq) }}
+}
+
+func (t *tracker) ack(ctx context.Context, seq int) {
+	t.mu.Lock()
+	t.acked[seq-t.base] = true
+	n := 0
+	for n < len(t.acked) && t.acked[n] {
+		n++
+	}
+	if n == 0 {
+		t.mu.Unlock()
+		return
+	}
+	t.committed = t.offsets[n-1]
+	t.offsets = t.offsets[n:]
+	t.acked = t.acked[n:]
+	t.base += n
+	t.mu.Unlock()
+
+	// A failed save is retried by the next commit; the run only fails if
+	// the final one does.
+	t.commit(ctx, false)
+}
+
+// commit saves the committed offset if it moved and, unless final, if the
+// interval has passed since the last save.
+func (t *tracker) commit(ctx context.Context, final bool) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.committed == t.saved || (!final && time.Since(t.lastSave) < t.interval) {
+		return nil
+	}
+	if err := t.store.Save(ctx, t.name, t.committed); err != nil {
+		return fmt.Errorf("pipeline: source %s: save checkpoint: %w", t.name, err)
+	}
+	t.saved, t.lastSave = t.committed, time.Now()
+	return nil
+}
+
+// IdempotentSink is the contract for a sink that makes a checkpointed
+// pipeline effectively exactly-once: writing an item under a key that was
+// already written must leave the destination unchanged.
+type IdempotentSink[T any] interface {
+	Write(ctx context.Context, key string, item T) error
+}
+
+// WriteTo returns a stage that writes each item to sink, keyed by its
+// source name and offset. Items from sources without offsets fail the
+// stage.
+func WriteTo[T any](name, source string, sink IdempotentSink[T]) Stage[T, T] {
+	return Stage[T, T]{
+		Name: name,
+		Fn: func(ctx context.Context, item T) (T, error) {
+			offset, ok := Offset(ctx)
+			if !ok {
+				return item, errors.New("item has no source offset")
+			}
+			return item, sink.Write(ctx, source+"/"+offset, item)
+		},
+	}
+}
+
+// FileCheckpointStore keeps offsets in a JSON file, replaced atomically on
+// every save.
+type FileCheckpointStore struct {
+	path string
+	mu   sync.Mutex
+}
+
+// NewFileCheckpointStore returns a store backed by the file at path.
+func NewFileCheckpointStore(path string) *FileCheckpointStore {
+	return &FileCheckpointStore{path: path}
+}
+
+// Load implements CheckpointStore.
+func (s *FileCheckpointStore) Load(_ context.Context, source string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	offsets, err := s.read()
+	return offsets[source], err
+}
+
+// Save implements CheckpointStore.
+func (s *FileCheckpointStore) Save(_ context.Context, source, offset string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	offsets, err := s.read()
+	if err != nil {
+		return err
+	}
+	offsets[source] = offset
+	data, err := json.Marshal(offsets)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.CreateTemp(filepath.Dir(s.path), filepath.Base(s.path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	// Sync before the rename so a crash cannot leave an empty checkpoint.
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), s.path)
+}
+
+func (s *FileCheckpointStore) read() (map[string]string, error) {
+	offsets := make(map[string]string)
+	data, err := os.ReadFile(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return offsets, nil
+	}
+	if err != nil {
+		return offsets, err
+	}
+	return offsets, json.Unmarshal(data, &offsets)
+}
+
+// SQLiteCheckpointStore keeps offsets in a SQLite table with the schema
+//
+//	CREATE TABLE <table> (
+//	    source     TEXT PRIMARY KEY,
+//	    position   TEXT NOT NULL,
+//	    updated_at TIMESTAMP NOT NULL
+//	)
+//
+// It works with any database/sql SQLite driver.
+type SQLiteCheckpointStore struct {
+	DB    *sql.DB
+	Table string
+}
+
+// NewSQLiteCheckpointStore creates the table if needed.
+func NewSQLiteCheckpointStore(ctx context.Context, db *sql.DB, table string) (*SQLiteCheckpointStore, error) {
+	_, err := db.ExecContext(ctx, fmt.Sprintf(`CREATE TABLE IF NOT EXISTS "%s" (
+	source     TEXT PRIMARY KEY,
+	position   TEXT NOT NULL,
+	updated_at TIMESTAMP NOT NULL
+)`, table))
+	if err != nil {
+		return nil, err
+	}
+	return &SQLiteCheckpointStore{DB: db, Table: table}, nil
+}
+
+// Load implements CheckpointStore.
+func (s *SQLiteCheckpointStore) Load(ctx context.Context, source string) (string, error) {
+	var offset string
+	err := s.DB.QueryRowContext(ctx,
+		fmt.Sprintf(`SELECT position FROM "%s" WHERE source = ?`, s.Table), source).Scan(&offset)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", nil
+	}
+	return offset, err
+}
+
+// Save implements CheckpointStore.
+func (s *SQLiteCheckpointStore) Save(ctx context.Context, source, offset string) error {
+	_, err := s.DB.ExecContext(ctx, fmt.Sprintf(`INSERT INTO "%s" (source, position, updated_at) VALUES (?, ?, ?)
+ON CONFLICT (source) DO UPDATE SET position = excluded.position, updated_at = excluded.updated_at`, s.Table),
+		source, offset, time.Now().UTC())
+	return err
+}
@@ -0,0 +1,140 @@
+package pipeline
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"testing"
+
+	_ "modernc.org/sqlite"
+)
+
+// keyedSink is an IdempotentSink that counts how often each key arrives.
+type keyedSink struct {
+	mu     sync.Mutex
+	rows   map[string]int
+	writes int
+}
+
+func (s *keyedSink) Write(_ context.Context, key string, n int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.writes++
+	if _, ok := s.rows[key]; !ok {
+		s.rows[key] = n
+	}
+	return nil
+}
+
+// counter emits 1..total with the number itself as offset.
+func counter(total int) OffsetSource[int] {
+	return func(ctx context.Context, from string, emit func(string, int) error) error {
+		start := 1
+		if from != "" {
+			n, err := strconv.Atoi(from)
+			if err != nil {
+				return err
+			}
+			start = n + 1
+		}
+		for n := start; n <= total; n++ {
+			if err := emit(strconv.Itoa(n), n); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+func TestCheckpointResume(t *testing.T) {
+	db, err := sql.Open("sqlite", filepath.Join(t.TempDir(), "checkpoints.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	sqlite, err := NewSQLiteCheckpointStore(context.Background(), db, "checkpoints")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stores := map[string]CheckpointStore{
+		"file":   NewFileCheckpointStore(filepath.Join(t.TempDir(), "checkpoints.json")),
+		"sqlite": sqlite,
+	}
+	for name, store := range stores {
+		t.Run(name, func(t *testing.T) {
+			const total = 200
+			crash := errors.New("crash")
+			sink := &keyedSink{rows: map[string]int{}}
+			run := func(crashAt int) error {
+				return FromCheckpoint("counter", store, CheckpointOptions{}, counter(total)).
+					Then(Stage[int, int]{
+						Name:    "double",
+						Workers: 4,
+						Fn: func(_ context.Context, n int) (int, error) {
+							if n == crashAt {
+								return 0, crash
+							}
+							if n%10 == 0 {
+								return 0, ErrSkip
+							}
+							return 2 * n, nil
+						},
+					}).
+					Then(WriteTo("store", "counter", sink)).
+					Run(context.Background())
+			}
+
+			if err := run(120); !errors.Is(err, crash) {
+				t.Fatalf("first run = %v", err)
+			}
+			committed, err := store.Load(context.Background(), "counter")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if n, _ := strconv.Atoi(committed); n < 1 || n >= 120 {
+				t.Fatalf("committed offset %q", committed)
+			}
+
+			if err := run(-1); err != nil {
+				t.Fatalf("resumed run = %v", err)
+			}
+			if committed, _ := store.Load(context.Background(), "counter"); committed != strconv.Itoa(total) {
+				t.Fatalf("committed offset %q after resume", committed)
+			}
+			if len(sink.rows) != total-total/10 {
+				t.Fatalf("sink holds %d rows, want %d", len(sink.rows), total-total/10)
+			}
+			if sink.rows["counter/7"] != 14 {
+				t.Fatalf("row 7 = %d", sink.rows["counter/7"])
+			}
+			// Only items in flight during the crash were written twice.
+			if dup := sink.writes - len(sink.rows); dup > 20 {
+				t.Fatalf("%d duplicate writes", dup)
+			}
+		})
+	}
+}
+
+func TestTrackerCommitsContiguousPrefix(t *testing.T) {
+	store := NewFileCheckpointStore(filepath.Join(t.TempDir(), "c.json"))
+	tr := &tracker{name: "src", store: store}
+	ctx := context.Background()
+	a, b, c := tr.track(ctx, "a"), tr.track(ctx, "b"), tr.track(ctx, "c")
+
+	c.done()
+	if got, _ := store.Load(ctx, "src"); got != "" {
+		t.Fatalf("committed %q before a and b were done", got)
+	}
+	a.done()
+	if got, _ := store.Load(ctx, "src"); got != "a" {
+		t.Fatalf("committed %q, want a", got)
+	}
+	b.done()
+	if got, _ := store.Load(ctx, "src"); got != "c" {
+		t.Fatalf("committed %q, want c", got)
+	}
+}
@@ -0,0 +1,298 @@
+package pipeline
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+)
+
+// Letter is an item a stage gave up on, with enough detail to diagnose
+// and replay it.
+type Letter struct {
+	ID    string `json:"id"`
+	Stage string `json:"stage"`
+	// Attempts is the number of times the stage function was called.
+	Attempts int `json:"attempts"`
+	// Item is the stage's input, JSON-encoded.
+	Item json.RawMessage `json:"item"`
+	// Causes is the error chain, outermost first.
+	Causes   []Cause   `json:"causes"`
+	FailedAt time.Time `json:"failed_at"`
+}
+
+// Cause is one error in a Letter's chain.
+type Cause struct {
+	Type    string `json:"type"`
+	Message string `json:"message"`
+}
+
+// DeadLetterStore persists letters until they are replayed.
+type DeadLetterStore interface {
+	Put(ctx context.Context, letters []Letter) error
+	Load(ctx context.Context) ([]Letter, error)
+	Remove(ctx context.Context, ids []string) error
+}
+
+// WithDeadLetters returns a copy of p whose stages send dead-lettered
+// items to store. Without a store, the DeadLetter action aborts.
+func (p *Pipeline[T]) WithDeadLetters(store DeadLetterStore) *Pipeline[T] {
+	return &Pipeline[T]{name: p.name, start: func(ctx context.Context, g *group) <-chan msg[T] {
+		g.deadLetters = store
+		return p.start(ctx, g)
+	}}
+}
+
+// FromDeadLetters starts a pipeline that replays the letters store holds
+// for stage; compose it with that stage again. Replayed letters are
+// removed once the run succeeds. Items that fail again are dead-lettered
+// anew.
+func FromDeadLetters[T any](store DeadLetterStore, stage string) *Pipeline[T] {
+	return From("dead-letters:"+stage, func(ctx context.Context, emit func(T) error) error {
+		letters, err := store.Load(ctx)
+		if err != nil {
+			return err
+		}
+		var replayed []string
+		for _, l := range letters {
+			if l.Stage != stage {
+				continue
+			}
+			var item T
+			if err := json.Unmarshal(l.Item, &item); err != nil {
+				return fmt.Errorf("letter %s: %w", l.ID, err)
+			}
+			if err := emit(item); err != nil {
+				return err
+			}
+			replayed = append(replayed, l.ID)
+		}
+		groupFrom(ctx).onFinish(func(ctx context.Context, err error) error {
+			if err != nil {
+				return nil
+			}
+			return store.Remove(ctx, replayed)
+		})
+		return nil
+	})
+}
+
+// deadLetter stores item on behalf of a stage.
+func (g *group) deadLetter(ctx context.Context, stage string, item any, attempts int, cause error) error {
+	if g.deadLetters == nil {
+		return fmt.Errorf("no dead-letter store: %w", cause)
+	}
+	raw, err := json.Marshal(item)
+	if err != nil {
+		return fmt.Errorf("encode dead letter: %w", err)
+	}
+	l := Letter{
+		ID:       newLetterID(),
+		Stage:    stage,
+		Attempts: attempts,
+		Item:     raw,
+		Causes:   causeChain(cause),
+		FailedAt: time.Now().UTC(),
+	}
+	if err := g.deadLetters.Put(ctx, []Letter{l}); err != nil {
+		return fmt.Errorf("store dead letter: %w", err)
+	}
+	return nil
+}
+
+// causeChain flattens err and everything it wraps, depth first.
+func causeChain(err error) []Cause {
+	var causes []Cause
+	var walk func(error)
+	walk = func(err error) {
+		for err != nil {
+			causes = append(causes, Cause{Type: fmt.Sprintf("%T", err), Message: err.Error()})
+			if multi, ok := err.(interface{ Unwrap() []error }); ok {
+				for _, e := range multi.Unwrap() {
+					walk(e)
+				}
+				return
+			}
+			err = errors.Unwrap(err)
+		}
+	}
+	walk(err)
+	return causes
+}
+
+// newLetterID returns a random ID, so letters from separate runs can
+// share a durable store.
+func newLetterID() string {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return fmt.Sprintf("%032x", time.Now().UnixNano())
+	}
+	return hex.EncodeToString(b[:])
+}
+
+// idSet returns ids as a set for Remove.
+func idSet(ids []string) map[string]bool {
+	set := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		set[id] = true
+	}
+	return set
+}
+
+// MemoryStore keeps letters in memory, for tests and for replaying within
+// one process. Use JSONLStore to keep letters across restarts.
+type MemoryStore struct {
+	mu      sync.Mutex
+	letters []Letter
+}
+
+// NewMemoryStore returns an empty store.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{}
+}
+
+// Put appends letters to the store.
+func (s *MemoryStore) Put(_ context.Context, letters []Letter) error {
+	s.mu.Lock()
+	s.letters = append(s.letters, letters...)
+	s.mu.Unlock()
+	return nil
+}
+
+// Load returns every letter in the store.
+func (s *MemoryStore) Load(_ context.Context) ([]Letter, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return slices.Clone(s.letters), nil
+}
+
+// Remove drops the given letters.
+func (s *MemoryStore) Remove(_ context.Context, ids []string) error {
+	drop := idSet(ids)
+	s.mu.Lock()
+	s.letters = slices.DeleteFunc(s.letters, func(l Letter) bool { return drop[l.ID] })
+	s.mu.Unlock()
+	return nil
+}
+
+// JSONLStore keeps letters as one JSON object per line in a file, so they
+// survive restarts and can be replayed by a later run.
+type JSONLStore struct {
+	path string
+	mu   sync.Mutex
+}
+
+// NewJSONLStore returns a store backed by the file at path, which is
+// created on the first Put.
+func NewJSONLStore(path string) *JSONLStore {
+	return &JSONLStore{path: path}
+}
+
+// Put appends letters to the file.
+func (s *JSONLStore) Put(_ context.Context, letters []Letter) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	f, err := os.OpenFile(s.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	enc := json.NewEncoder(f)
+	for _, l := range letters {
+		if err := enc.Encode(l); err != nil {
+			f.Close()
+			return err
+		}
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// Load reads every letter in the file. A missing file holds no letters.
+func (s *JSONLStore) Load(_ context.Context) ([]Letter, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.read()
+}
+
+// Remove replaces the file atomically with one without the given letters.
+func (s *JSONLStore) Remove(_ context.Context, ids []string) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	letters, err := s.read()
+	if err != nil {
+		return err
+	}
+	drop := idSet(ids)
+	f, err := os.CreateTemp(filepath.Dir(s.path), filepath.Base(s.path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	w := bufio.NewWriter(f)
+	enc := json.NewEncoder(w)
+	for _, l := range letters {
+		if drop[l.ID] {
+			continue
+		}
+		if err := enc.Encode(l); err != nil {
+			f.Close()
+			return err
+		}
+	}
+	if err := w.Flush(); err != nil {
+		f.Close()
+		return err
+	}
+	// Sync before the rename so a crash cannot lose the remaining letters.
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), s.path)
+}
+
+func (s *JSONLStore) read() ([]Letter, error) {
+	f, err := os.Open(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var letters []Letter
+	sc := bufio.NewScanner(f)
+	sc.Buffer(make([]byte, 64*1024), 16<<20)
+	for sc.Scan() {
+		if len(bytes.TrimSpace(sc.Bytes())) == 0 {
+			continue
+		}
+		var l Letter
+		if err := json.Unmarshal(sc.Bytes(), &l); err != nil {
+			return nil, err
+		}
+		letters = append(letters, l)
+	}
+	return letters, sc.Err()
+}
@@ -0,0 +1,434 @@
+package pipeline
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// HTTPSource reads JSON items from a paginated HTTP API. Pages are
+// decoded as a stream, so large arrays are never held in memory.
+type HTTPSource struct {
+	// URL is the first page.
+	URL    string
+	Client *http.Client
+	Header http.Header
+	// Pagination finds the next page. Nil reads a single page.
+	Pagination Paginator
+	// ItemsField names the array of items when a page is a JSON object.
+	// Defaults to "items". Pages that are JSON arrays are used as is.
+	ItemsField string
+	// RequestsPerSecond caps the request rate. Zero means no limit.
+	RequestsPerSecond float64
+	// MaxRetries bounds retries of network errors, 429s and 5xx responses.
+	// Defaults to 5.
+	MaxRetries int
+
+	mu         sync.Mutex
+	next       time.Time // earliest time for the next request
+	validators map[string]validator
+}
+
+// validator holds what a server returned for conditional requests.
+type validator struct {
+	etag, lastModified string
+}
+
+// Page describes a fetched page to a Paginator.
+type Page struct {
+	URL    *url.URL
+	Header http.Header
+	// Items is the number of items on the page.
+	Items int
+	// Fields holds the page's top-level fields other than the items, when
+	// the page is a JSON object.
+	Fields map[string]json.RawMessage
+}
+
+// Paginator returns the URL of the page after page, or "" after the last.
+type Paginator interface {
+	Next(page *Page) (string, error)
+}
+
+// CursorPagination follows a cursor found in each page's body.
+type CursorPagination struct {
+	// Field holds the next cursor in the page; null or "" ends the walk.
+	Field string
+	// Param is the query parameter the cursor is sent in.
+	Param string
+}
+
+// Next implements Paginator.
+func (p CursorPagination) Next(page *Page) (string, error) {
+	raw, ok := page.Fields[p.Field]
+	if !ok {
+		return "", nil
+	}
+	var cursor any
+	if err := json.Unmarshal(raw, &cursor); err != nil {
+		return "", err
+	}
+	var value string
+	switch c := cursor.(type) {
+	case nil:
+		return "", nil
+	case string:
+		value = c
+	default:
+		value = string(raw)
+	}
+	if value == "" {
+		return "", nil
+	}
+	return withQuery(page.URL, p.Param, value), nil
+}
+
+// OffsetPagination pages with an offset query parameter. The first URL
+// should carry the limit; a page with fewer than Limit items is the last.
+type OffsetPagination struct {
+	Param string // defaults to "offset"
+	Limit int
+}
+
+// Next implements Paginator.
+func (p OffsetPagination) Next(page *Page) (string, error) {
+	if page.Items == 0 || (p.Limit > 0 && page.Items < p.Limit) {
+		return "", nil
+	}
+	param := p.Param
+	if param == "" {
+		param = "offset"
+	}
+	offset := 0
+	if v := page.URL.Query().Get(param); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return "", fmt.Errorf("bad %s %q: %w", param, v, err)
+		}
+		offset = n
+	}
+	return withQuery(page.URL, param, strconv.Itoa(offset+page.Items)), nil
+}
+
+// LinkPagination follows the rel="next" entry of the Link header.
+type LinkPagination struct{}
+
+// Next implements Paginator.
+func (LinkPagination) Next(page *Page) (string, error) {
+	for _, header := range page.Header.Values("Link") {
+		for _, link := range strings.Split(header, ",") {
+			target, params, ok := strings.Cut(strings.TrimSpace(link), ";")
+			if !ok || !hasRel(params, "next") {
+				continue
+			}
+			target = strings.Trim(strings.TrimSpace(target), "<>")
+			u, err := page.URL.Parse(target)
+			if err != nil {
+				return "", err
+			}
+			return u.String(), nil
+		}
+	}
+	return "", nil
+}
+
+func hasRel(params, rel string) bool {
+	for _, p := range strings.Split(params, ";") {
+		key, value, _ := strings.Cut(strings.TrimSpace(p), "=")
+		if strings.EqualFold(key, "rel") {
+			for _, r := range strings.Fields(strings.Trim(value, `"`)) {
+				if r == rel {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+func withQuery(u *url.URL, key, value string) string {
+	next := *u
+	q := next.Query()
+	q.Set(key, value)
+	next.RawQuery = q.Encode()
+	return next.String()
+}
+
+// StatusError is an unexpected HTTP response.
+type StatusError struct {
+	URL        string
+	StatusCode int
+	Body       string
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("GET %s: %d %s", e.URL, e.StatusCode, e.Body)
+}
+
+// FromHTTP starts a pipeline that emits every item of every page.
+func FromHTTP[T any](name string, s *HTTPSource) *Pipeline[T] {
+	pages := Pages[T](s)
+	return From(name, func(ctx context.Context, emit func(T) error) error {
+		return pages(ctx, "", func(_ string, item T) error { return emit(item) })
+	})
+}
+
+// Pages adapts s to FromCheckpoint. An item's offset is its page URL and
+// index, so a resumed run refetches the page it stopped in and skips the
+// items already committed.
+//
+// Pages seen before are fetched conditionally: a page answered with 304
+// Not Modified has no new items and ends the walk.
+func Pages[T any](s *HTTPSource) OffsetSource[T] {
+	return func(ctx context.Context, from string, emit func(string, T) error) error {
+		page, skip := s.URL, -1
+		if from != "" {
+			i := strings.LastIndex(from, "#")
+			n, err := strconv.Atoi(from[i+1:])
+			if i < 0 || err != nil {
+				return fmt.Errorf("bad offset %q", from)
+			}
+			page, skip = from[:i], n
+		}
+		for page != "" {
+			next, err := fetchPage(ctx, s, page, skip < 0, func(i int, item T) error {
+				if i <= skip {
+					return nil
+				}
+				return emit(page+"#"+strconv.Itoa(i), item)
+			})
+			if err != nil {
+				return err
+			}
+			page, skip = next, -1
+		}
+		return nil
+	}
+}
+
+// fetchPage streams one page's items to emit and returns the next page.
+func fetchPage[T any](ctx context.Context, s *HTTPSource, pageURL string, conditional bool, emit func(int, T) error) (string, error) {
+	resp, err := s.get(ctx, pageURL, conditional)
+	if err != nil || resp == nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	page := &Page{URL: resp.Request.URL, Header: resp.Header, Fields: make(map[string]json.RawMessage)}
+	dec := json.NewDecoder(resp.Body)
+	tok, err := dec.Token()
+	if err != nil {
+		return "", fmt.Errorf("GET %s: %w", pageURL, err)
+	}
+	switch tok {
+	case json.Delim('['):
+		page.Items, err = decodeItems(dec, emit)
+	case json.Delim('{'):
+		page.Items, err = decodeObject(dec, s.itemsField(), page.Fields, emit)
+	default:
+		err = fmt.Errorf("unexpected %v", tok)
+	}
+	if err != nil {
+		return "", fmt.Errorf("GET %s: %w", pageURL, err)
+	}
+	s.remember(pageURL, resp.Header)
+
+	if s.Pagination == nil {
+		return "", nil
+	}
+	return s.Pagination.Next(page)
+}
+
+// decodeItems decodes array elements up to and including the closing
+// bracket.
+func decodeItems[T any](dec *json.Decoder, emit func(int, T) error) (int, error) {
+	n := 0
+	for ; dec.More(); n++ {
+		var item T
+		if err := dec.Decode(&item); err != nil {
+			return n, err
+		}
+		if err := emit(n, item); err != nil {
+			return n, err
+		}
+	}
+	_, err := dec.Token()
+	return n, err
+}
+
+// decodeObject streams the items field of an object and keeps the other
+// fields, in whatever order they come.
+func decodeObject[T any](dec *json.Decoder, itemsField string, fields map[string]json.RawMessage, emit func(int, T) error) (int, error) {
+	n := 0
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return n, err
+		}
+		key, _ := tok.(string)
+		if key != itemsField {
+			var raw json.RawMessage
+			if err := dec.Decode(&raw); err != nil {
+				return n, err
+			}
+			fields[key] = raw
+			continue
+		}
+		tok, err = dec.Token()
+		if err != nil {
+			return n, err
+		}
+		if tok == nil {
+			continue
+		}
+		if tok != json.Delim('[') {
+			return n, fmt.Errorf("%s is not an array", itemsField)
+		}
+		if n, err = decodeItems(dec, emit); err != nil {
+			return n, err
+		}
+	}
+	_, err := dec.Token()
+	return n, err
+}
+
+// get fetches url, waiting for the rate limit and retrying failures. A
+// conditional request returns a nil response for 304 Not Modified.
+func (s *HTTPSource) get(ctx context.Context, pageURL string, conditional bool) (*http.Response, error) {
+	client := s.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	retries := s.MaxRetries
+	if retries <= 0 {
+		retries = 5
+	}
+
+	for attempt := 0; ; attempt++ {
+		if err := s.wait(ctx); err != nil {
+			return nil, err
+		}
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, pageURL, nil)
+		if err != nil {
+			return nil, err
+		}
+		for k, v := range s.Header {
+			req.Header[k] = v
+		}
+		req.Header.Set("Accept", "application/json")
+		var v validator
+		if conditional {
+			s.mu.Lock()
+			v = s.validators[pageURL]
+			s.mu.Unlock()
+		}
+		if v.etag != "" {
+			req.Header.Set("If-None-Match", v.etag)
+		}
+		if v.lastModified != "" {
+			req.Header.Set("If-Modified-Since", v.lastModified)
+		}
+
+		resp, err := client.Do(req)
+		var delay time.Duration
+		switch {
+		case err != nil:
+			if ctx.Err() != nil || attempt >= retries {
+				return nil, err
+			}
+			delay = Policy{}.backoff(attempt + 1)
+		case resp.StatusCode == http.StatusNotModified:
+			resp.Body.Close()
+			return nil, nil
+		case resp.StatusCode >= 200 && resp.StatusCode < 300:
+			return resp, nil
+		case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500:
+			body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+			resp.Body.Close()
+			if attempt >= retries {
+				return nil, &StatusError{URL: pageURL, StatusCode: resp.StatusCode, Body: string(body)}
+			}
+			delay = retryAfter(resp.Header, time.Now(), maxBackoff)
+			if delay <= 0 {
+				delay = Policy{}.backoff(attempt + 1)
+			}
+			// Hold every request, not just this retry, until the server
+			// is ready again.
+			s.delay(delay)
+			delay = 0
+		default:
+			body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+			resp.Body.Close()
+			return nil, &StatusError{URL: pageURL, StatusCode: resp.StatusCode, Body: string(body)}
+		}
+		if err := sleep(ctx, delay); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// wait blocks until the rate limit allows the next request.
+func (s *HTTPSource) wait(ctx context.Context) error {
+	s.mu.Lock()
+	now := time.Now()
+	at := s.next
+	if at.Before(now) {
+		at = now
+	}
+	if s.RequestsPerSecond > 0 {
+		s.next = at.Add(time.Duration(float64(time.Second) / s.RequestsPerSecond))
+	}
+	s.mu.Unlock()
+	return sleep(ctx, at.Sub(now))
+}
+
+func (s *HTTPSource) delay(d time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if at := time.Now().Add(d); at.After(s.next) {
+		s.next = at
+	}
+}
+
+func (s *HTTPSource) remember(pageURL string, h http.Header) {
+	v := validator{etag: h.Get("ETag"), lastModified: h.Get("Last-Modified")}
+	if v == (validator{}) {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.validators == nil {
+		s.validators = make(map[string]validator)
+	}
+	s.validators[pageURL] = v
+}
+
+func (s *HTTPSource) itemsField() string {
+	if s.ItemsField == "" {
+		return "items"
+	}
+	return s.ItemsField
+}
+
+// retryAfter parses a Retry-After header given in seconds or as a date,
+// capped at limit so a bogus or hostile header cannot stall the source.
+func retryAfter(h http.Header, now time.Time, limit time.Duration) time.Duration {
+	v := h.Get("Retry-After")
+	if v == "" {
+		return 0
+	}
+	var d time.Duration
+	if secs, err := strconv.Atoi(v); err == nil {
+		d = time.Duration(secs) * time.Second
+	} else if at, err := http.ParseTime(v); err == nil {
+		d = at.Sub(now)
+	}
+	return min(d, limit)
+}
@@ -0,0 +1,228 @@
+package pipeline
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type record struct {
+	ID int `json:"id"`
+}
+
+func records(from, to int) []record {
+	var out []record
+	for i := from; i < to; i++ {
+		out = append(out, record{ID: i})
+	}
+	return out
+}
+
+func collectHTTP(t *testing.T, s *HTTPSource) []int {
+	t.Helper()
+	var ids []int
+	err := FromHTTP[record]("api", s).Each(context.Background(), func(r record) error {
+		ids = append(ids, r.ID)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ids
+}
+
+func wantIDs(t *testing.T, got []int, n int) {
+	t.Helper()
+	if len(got) != n {
+		t.Fatalf("got %d items, want %d: %v", len(got), n, got)
+	}
+	for i, id := range got {
+		if id != i {
+			t.Fatalf("item %d has id %d", i, id)
+		}
+	}
+}
+
+func TestHTTPSourcePagination(t *testing.T) {
+	const total = 25
+	mux := http.NewServeMux()
+	// The cursor comes after the items to exercise streaming.
+	mux.HandleFunc("/cursor", func(w http.ResponseWriter, r *http.Request) {
+		start, _ := strconv.Atoi(r.URL.Query().Get("after"))
+		end := min(start+10, total)
+		var next any
+		if end < total {
+			next = strconv.Itoa(end)
+		}
+		json.NewEncoder(w).Encode(map[string]any{"data": records(start, end), "next": next})
+	})
+	mux.HandleFunc("/offset", func(w http.ResponseWriter, r *http.Request) {
+		offset, _ := strconv.Atoi(r.URL.Query().Get("offset"))
+		limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+		json.NewEncoder(w).Encode(records(offset, min(offset+limit, total)))
+	})
+	mux.HandleFunc("/link", func(w http.ResponseWriter, r *http.Request) {
+		page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+		if (page+1)*10 < total {
+			w.Header().Add("Link", fmt.Sprintf(`</link?page=%d>; rel="next", </link?page=0>; rel="first"`, page+1))
+		}
+		json.NewEncoder(w).Encode(records(page*10, min(page*10+10, total)))
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	t.Run("cursor", func(t *testing.T) {
+		wantIDs(t, collectHTTP(t, &HTTPSource{
+			URL:        srv.URL + "/cursor",
+			ItemsField: "data",
+			Pagination: CursorPagination{Field: "next", Param: "after"},
+		}), total)
+	})
+	t.Run("offset", func(t *testing.T) {
+		wantIDs(t, collectHTTP(t, &HTTPSource{
+			URL:        srv.URL + "/offset?limit=10",
+			Pagination: OffsetPagination{Limit: 10},
+		}), total)
+	})
+	t.Run("link", func(t *testing.T) {
+		wantIDs(t, collectHTTP(t, &HTTPSource{URL: srv.URL + "/link", Pagination: LinkPagination{}}), total)
+	})
+}
+
+func TestRetryAfterIsCapped(t *testing.T) {
+	now := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+	for value, want := range map[string]time.Duration{
+		"":                              0,
+		"soon":                          0,
+		"30":                            30 * time.Second,
+		"86400":                         time.Minute,
+		"Thu, 02 Jan 2025 03:04:35 GMT": 30 * time.Second,
+		"Fri, 03 Jan 2025 03:04:05 GMT": time.Minute,
+	} {
+		h := http.Header{"Retry-After": {value}}
+		if got := retryAfter(h, now, time.Minute); got != want {
+			t.Errorf("retryAfter(%q) = %v, want %v", value, got, want)
+		}
+	}
+}
+
+func TestHTTPSourceRetryAfterAndRateLimit(t *testing.T) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch calls.Add(1) {
+		case 1:
+			w.Header().Set("Retry-After", "1")
+			w.WriteHeader(http.StatusTooManyRequests)
+		case 2:
+			w.WriteHeader(http.StatusBadGateway)
+		default:
+			json.NewEncoder(w).Encode(records(0, 3))
+		}
+	}))
+	defer srv.Close()
+
+	start := time.Now()
+	wantIDs(t, collectHTTP(t, &HTTPSource{URL: srv.URL, RequestsPerSecond: 20}), 3)
+	if elapsed := time.Since(start); elapsed < time.Second {
+		t.Fatalf("Retry-After not honored: done after %v", elapsed)
+	}
+
+	// Client errors are not retried.
+	srv.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		http.Error(w, "no access", http.StatusForbidden)
+	})
+	err := FromHTTP[record]("api", &HTTPSource{URL: srv.URL}).Run(context.Background())
+	var se *StatusError
+	if !errors.As(err, &se) || se.StatusCode != http.StatusForbidden {
+		t.Fatalf("Run = %v", err)
+	}
+}
+
+func TestHTTPSourceConditionalRequests(t *testing.T) {
+	var full, notModified atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			notModified.Add(1)
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		full.Add(1)
+		w.Header().Set("ETag", `"v1"`)
+		json.NewEncoder(w).Encode(records(0, 2))
+	}))
+	defer srv.Close()
+
+	s := &HTTPSource{URL: srv.URL}
+	wantIDs(t, collectHTTP(t, s), 2)
+	if got := collectHTTP(t, s); len(got) != 0 {
+		t.Fatalf("unchanged page emitted %v", got)
+	}
+	if full.Load() != 1 || notModified.Load() != 1 {
+		t.Fatalf("full=%d notModified=%d", full.Load(), notModified.Load())
+	}
+}
+
+func TestHTTPSourceStreamsAndResumes(t *testing.T) {
+	const total = 5000
+	// The page is written in chunks; the first items must arrive before
+	// the server finishes writing.
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"items":[`))
+		for i := range total {
+			if i > 0 {
+				w.Write([]byte(","))
+			}
+			fmt.Fprintf(w, `{"id":%d}`, i)
+			if i == 10 {
+				w.(http.Flusher).Flush()
+				<-release
+			}
+		}
+		w.Write([]byte(`]}`))
+	}))
+	defer srv.Close()
+
+	store := NewFileCheckpointStore(filepath.Join(t.TempDir(), "c.json"))
+	stop := errors.New("stop")
+	seen := 0
+	err := FromCheckpoint("api", store, CheckpointOptions{}, Pages[record](&HTTPSource{URL: srv.URL})).
+		Each(context.Background(), func(r record) error {
+			if seen++; seen == 5 {
+				close(release)
+			}
+			if r.ID == 99 {
+				return stop
+			}
+			return nil
+		})
+	if !errors.Is(err, stop) {
+		t.Fatalf("Each = %v", err)
+	}
+	offset, _ := store.Load(context.Background(), "api")
+	if !strings.HasSuffix(offset, "#98") {
+		t.Fatalf("committed %q", offset)
+	}
+
+	var ids []int
+	err = FromCheckpoint("api", store, CheckpointOptions{}, Pages[record](&HTTPSource{URL: srv.URL})).
+		Each(context.Background(), func(r record) error {
+			ids = append(ids, r.ID)
+			return nil
+		})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ids) != total-99 || ids[0] != 99 {
+		t.Fatalf("resumed with %d items starting at %v", len(ids), ids[:1])
+	}
+}
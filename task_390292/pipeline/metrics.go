@@ -0,0 +1,250 @@
+package pipeline
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// latencyBuckets are the upper bounds, in seconds, of the latency
+// histogram.
+var latencyBuckets = []float64{.001, .005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+// Metrics collects per-stage counters for one or more pipelines and serves
+// them over HTTP. Counters accumulate across runs.
+type Metrics struct {
+	mu     sync.Mutex
+	stages []*stageMetrics
+	byName map[string]*stageMetrics
+}
+
+// NewMetrics returns an empty registry.
+func NewMetrics() *Metrics {
+	return &Metrics{byName: make(map[string]*stageMetrics)}
+}
+
+// WithMetrics returns a copy of p that records its stages in m.
+func (p *Pipeline[T]) WithMetrics(m *Metrics) *Pipeline[T] {
+	return &Pipeline[T]{name: p.name, start: func(ctx context.Context, g *group) <-chan msg[T] {
+		g.metrics = m
+		return p.start(ctx, g)
+	}}
+}
+
+// stageMetrics are the counters of one stage. The source of a pipeline
+// is recorded as a stage without upstream that only counts items out.
+type stageMetrics struct {
+	name     string
+	upstream string
+	workers  int
+
+	counters [numCounters]atomic.Int64
+
+	mu      sync.Mutex
+	queue   func() (depth, capacity int)
+	buckets []int64 // per latencyBuckets, plus +Inf
+	count   int64
+	sum     float64
+}
+
+// stage returns the metrics for a stage, registering it on first use. It
+// returns nil when m is nil.
+func (m *Metrics) stage(name, upstream string, workers int, queue func() (int, int)) *stageMetrics {
+	if m == nil {
+		return nil
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	s, ok := m.byName[name]
+	if !ok {
+		s = &stageMetrics{name: name, buckets: make([]int64, len(latencyBuckets)+1)}
+		m.byName[name] = s
+		m.stages = append(m.stages, s)
+	}
+	s.mu.Lock()
+	s.upstream, s.workers, s.queue = upstream, workers, queue
+	s.mu.Unlock()
+	return s
+}
+
+type counterKind int
+
+const (
+	countIn counterKind = iota
+	countOut
+	countErrors
+	countSkipped
+	countDeadLettered
+	countRetries
+	countBusy
+	numCounters
+)
+
+// add is nil-safe so stages need not check whether metrics are enabled.
+func (s *stageMetrics) add(c counterKind, n int64) {
+	if s != nil {
+		s.counters[c].Add(n)
+	}
+}
+
+func (s *stageMetrics) observe(d time.Duration) {
+	if s == nil {
+		return
+	}
+	secs := d.Seconds()
+	i := 0
+	for i < len(latencyBuckets) && secs > latencyBuckets[i] {
+		i++
+	}
+	s.mu.Lock()
+	s.buckets[i]++
+	s.count++
+	s.sum += secs
+	s.mu.Unlock()
+}
+
+// StageSnapshot is a point-in-time view of a stage's counters.
+type StageSnapshot struct {
+	Name          string  `json:"name"`
+	Upstream      string  `json:"upstream,omitempty"`
+	Workers       int     `json:"workers"`
+	Busy          int64   `json:"busy"`
+	In            int64   `json:"in"`
+	Out           int64   `json:"out"`
+	Errors        int64   `json:"errors"`
+	Skipped       int64   `json:"skipped"`
+	DeadLettered  int64   `json:"dead_lettered"`
+	Retries       int64   `json:"retries"`
+	QueueDepth    int     `json:"queue_depth"`
+	QueueCapacity int     `json:"queue_capacity"`
+	LatencyCount  int64   `json:"latency_count"`
+	LatencySum    float64 `json:"latency_sum_seconds"`
+	// LatencyBuckets maps upper bounds in seconds to cumulative counts.
+	LatencyBuckets map[string]int64 `json:"latency_buckets"`
+}
+
+// Topology is the stage graph with live counters.
+type Topology struct {
+	Stages []StageSnapshot `json:"stages"`
+	// Bottleneck is the stage with the busiest workers, ties going to the
+	// fuller input queue.
+	Bottleneck string `json:"bottleneck,omitempty"`
+}
+
+// Snapshot returns the current counters of every stage, in the order they
+// were registered.
+func (m *Metrics) Snapshot() Topology {
+	m.mu.Lock()
+	stages := append([]*stageMetrics(nil), m.stages...)
+	m.mu.Unlock()
+
+	var t Topology
+	best := -1.0
+	for _, s := range stages {
+		snap := s.snapshot()
+		t.Stages = append(t.Stages, snap)
+		if snap.Workers == 0 {
+			continue
+		}
+		load := float64(snap.Busy) / float64(snap.Workers)
+		if snap.QueueCapacity > 0 {
+			load += float64(snap.QueueDepth) / float64(snap.QueueCapacity) / 100
+		}
+		if load > best {
+			best, t.Bottleneck = load, snap.Name
+		}
+	}
+	return t
+}
+
+func (s *stageMetrics) snapshot() StageSnapshot {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	snap := StageSnapshot{
+		Name:           s.name,
+		Upstream:       s.upstream,
+		Workers:        s.workers,
+		Busy:           s.counters[countBusy].Load(),
+		In:             s.counters[countIn].Load(),
+		Out:            s.counters[countOut].Load(),
+		Errors:         s.counters[countErrors].Load(),
+		Skipped:        s.counters[countSkipped].Load(),
+		DeadLettered:   s.counters[countDeadLettered].Load(),
+		Retries:        s.counters[countRetries].Load(),
+		LatencyCount:   s.count,
+		LatencySum:     s.sum,
+		LatencyBuckets: make(map[string]int64, len(s.buckets)),
+	}
+	if s.queue != nil {
+		snap.QueueDepth, snap.QueueCapacity = s.queue()
+	}
+	var cum int64
+	for i, n := range s.buckets {
+		cum += n
+		le := "+Inf"
+		if i < len(latencyBuckets) {
+			le = strconv.FormatFloat(latencyBuckets[i], 'g', -1, 64)
+		}
+		snap.LatencyBuckets[le] = cum
+	}
+	return snap
+}
+
+// Handler serves the topology as JSON, or in the Prometheus text format
+// when the request asks for text/plain or has ?format=prometheus.
+func (m *Metrics) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t := m.Snapshot()
+		if r.URL.Query().Get("format") == "prometheus" || strings.Contains(r.Header.Get("Accept"), "text/plain") {
+			w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+			writePrometheus(w, t)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(t)
+	})
+}
+
+func writePrometheus(w http.ResponseWriter, t Topology) {
+	type metric struct {
+		name, help, kind string
+		value            func(StageSnapshot) float64
+	}
+	metrics := []metric{
+		{"pipeline_stage_items_in_total", "Items received by the stage.", "counter", func(s StageSnapshot) float64 { return float64(s.In) }},
+		{"pipeline_stage_items_out_total", "Items passed downstream by the stage.", "counter", func(s StageSnapshot) float64 { return float64(s.Out) }},
+		{"pipeline_stage_errors_total", "Failed calls of the stage function.", "counter", func(s StageSnapshot) float64 { return float64(s.Errors) }},
+		{"pipeline_stage_skipped_total", "Items dropped by the stage.", "counter", func(s StageSnapshot) float64 { return float64(s.Skipped) }},
+		{"pipeline_stage_dead_lettered_total", "Items dead-lettered by the stage.", "counter", func(s StageSnapshot) float64 { return float64(s.DeadLettered) }},
+		{"pipeline_stage_retries_total", "Retried calls of the stage function.", "counter", func(s StageSnapshot) float64 { return float64(s.Retries) }},
+		{"pipeline_stage_queue_depth", "Items waiting in the stage's input channel.", "gauge", func(s StageSnapshot) float64 { return float64(s.QueueDepth) }},
+		{"pipeline_stage_busy_workers", "Workers currently processing an item.", "gauge", func(s StageSnapshot) float64 { return float64(s.Busy) }},
+		{"pipeline_stage_workers", "Workers configured for the stage.", "gauge", func(s StageSnapshot) float64 { return float64(s.Workers) }},
+	}
+	for _, m := range metrics {
+		fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", m.name, m.help, m.name, m.kind)
+		for _, s := range t.Stages {
+			fmt.Fprintf(w, "%s{stage=%q,upstream=%q} %g\n", m.name, s.Name, s.Upstream, m.value(s))
+		}
+	}
+
+	const h = "pipeline_stage_latency_seconds"
+	fmt.Fprintf(w, "# HELP %s Time spent processing an item, retries included.\n# TYPE %s histogram\n", h, h)
+	for _, s := range t.Stages {
+		if s.Workers == 0 {
+			continue // sources are not timed
+		}
+		for _, b := range latencyBuckets {
+			le := strconv.FormatFloat(b, 'g', -1, 64)
+			fmt.Fprintf(w, "%s_bucket{stage=%q,le=%q} %d\n", h, s.Name, le, s.LatencyBuckets[le])
+		}
+		fmt.Fprintf(w, "%s_bucket{stage=%q,le=\"+Inf\"} %d\n", h, s.Name, s.LatencyBuckets["+Inf"])
+		fmt.Fprintf(w, "%s_sum{stage=%q} %g\n%s_count{stage=%q} %d\n", h, s.Name, s.LatencySum, h, s.Name, s.LatencyCount)
+	}
+}
@@ -0,0 +1,141 @@
+package pipeline
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+type spanRecorder struct {
+	mu    sync.Mutex
+	spans []*Span
+}
+
+func (r *spanRecorder) Export(s *Span) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.spans = append(r.spans, s)
+}
+
+func TestMetricsTopologyAndTracing(t *testing.T) {
+	metrics := NewMetrics()
+	tracer := &spanRecorder{}
+	srv := httptest.NewServer(metrics.Handler())
+	defer srv.Close()
+
+	items := make([]int, 40)
+	for i := range items {
+		items[i] = i
+	}
+	inSlow := make(chan struct{})
+	var once sync.Once
+	release := make(chan struct{})
+	p := FromSlice("numbers", items).
+		Then(Stage[int, int]{
+			Name:    "parse",
+			Workers: 2,
+			Fn: func(_ context.Context, n int) (int, error) {
+				if n%10 == 0 {
+					return 0, ErrSkip
+				}
+				return n, nil
+			},
+		}).
+		Then(Stage[int, int]{
+			Name: "slow",
+			Fn: func(ctx context.Context, n int) (int, error) {
+				_, span := StartSpan(ctx, "db.query")
+				defer span.Finish(nil)
+				once.Do(func() { close(inSlow) })
+				<-release
+				return n, nil
+			},
+		}).
+		WithMetrics(metrics).
+		WithTracer(tracer)
+
+	done := make(chan error)
+	go func() { done <- p.Run(context.Background()) }()
+
+	// While the slow stage holds its only worker, its input queue (parse's
+	// output buffer of two) fills up and it is the bottleneck.
+	<-inSlow
+	var live Topology
+	for deadline := time.Now().Add(time.Second); time.Now().Before(deadline); time.Sleep(5 * time.Millisecond) {
+		resp, err := http.Get(srv.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		live = Topology{}
+		json.NewDecoder(resp.Body).Decode(&live)
+		resp.Body.Close()
+		if len(live.Stages) == 3 && live.Stages[2].QueueDepth == live.Stages[2].QueueCapacity {
+			break
+		}
+	}
+	if live.Bottleneck != "slow" || live.Stages[2].Upstream != "parse" || live.Stages[2].QueueDepth != 2 {
+		t.Fatalf("live topology %+v", live)
+	}
+	close(release)
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+
+	final := metrics.Snapshot()
+	parse, slow := final.Stages[1], final.Stages[2]
+	if final.Stages[0].Out != 40 || parse.In != 40 || parse.Skipped != 4 || parse.Out != 36 || slow.Out != 36 {
+		t.Fatalf("final topology %+v", final)
+	}
+	if slow.LatencyCount != 36 || slow.LatencyBuckets["+Inf"] != 36 {
+		t.Fatalf("slow latency %+v", slow)
+	}
+
+	req, _ := http.NewRequest(http.MethodGet, srv.URL, nil)
+	req.Header.Set("Accept", "text/plain")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	for _, line := range []string{
+		`pipeline_stage_items_out_total{stage="parse",upstream="numbers"} 36`,
+		`pipeline_stage_skipped_total{stage="parse",upstream="numbers"} 4`,
+		`pipeline_stage_latency_seconds_count{stage="slow"} 36`,
+		`pipeline_stage_latency_seconds_bucket{stage="slow",le="+Inf"} 36`,
+	} {
+		if !strings.Contains(string(body), line+"\n") {
+			t.Errorf("missing %q in\n%s", line, body)
+		}
+	}
+
+	// Every item has a root span; stage spans and the span started by the
+	// stage function hang off it.
+	byID := map[string]*Span{}
+	for _, s := range tracer.spans {
+		byID[s.SpanID] = s
+	}
+	count := map[string]int{}
+	for _, s := range tracer.spans {
+		count[s.Name]++
+		if s.ParentID == "" {
+			continue
+		}
+		parent := byID[s.ParentID]
+		if parent == nil || parent.TraceID != s.TraceID {
+			t.Fatalf("span %s has bad parent", s.Name)
+		}
+		if s.Name == "db.query" && parent.Name != "slow" {
+			t.Fatalf("db.query parent is %s", parent.Name)
+		}
+	}
+	if count["numbers"] != 40 || count["parse"] != 40 || count["slow"] != 36 || count["db.query"] != 36 {
+		t.Fatalf("span counts %v", count)
+	}
+}
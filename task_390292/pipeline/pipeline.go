@@ -0,0 +1,341 @@
+// Package pipeline runs typed, concurrent processing stages connected by
+// channels. Each stage owns its output channel and closes it once all of
+// its workers are done, so callers never close channels themselves.
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// ErrSkip, returned (or wrapped) by a stage function, drops the item
+// without failing the pipeline.
+var ErrSkip = errors.New("pipeline: skip item")
+
+// Stage turns each In into an Out. With more than one worker, items may
+// leave the stage in a different order than they entered.
+type Stage[In, Out any] struct {
+	Name string
+	// Workers is the number of goroutines running Fn. Defaults to 1.
+	Workers int
+	// Buffer is the capacity of the stage's output channel. Defaults to
+	// Workers.
+	Buffer int
+	Fn     func(ctx context.Context, in In) (Out, error)
+	// Policies choose what happens when Fn fails. Without a matching
+	// policy the pipeline aborts.
+	Policies []Policy
+}
+
+// Pipeline is a source followed by zero or more stages producing T. It is
+// a description: channels and goroutines are created by Run, so a Pipeline
+// can be run more than once.
+type Pipeline[T any] struct {
+	// name is the name of the last node, the upstream of the next stage.
+	name  string
+	start func(ctx context.Context, g *group) <-chan msg[T]
+}
+
+// msg is an item in flight together with its bookkeeping.
+type msg[T any] struct {
+	item T
+	meta *meta
+}
+
+// meta tracks one item from its source to the end of the pipeline.
+type meta struct {
+	// offset is the item's position in a checkpointed source.
+	offset string
+	// ack, if set, is called once the item is done with: it left the last
+	// stage or was skipped or dead-lettered along the way.
+	ack func()
+	// span is the item's root span when the pipeline is traced.
+	span *Span
+}
+
+func (m *meta) done() {
+	if m == nil {
+		return
+	}
+	if m.ack != nil {
+		m.ack()
+	}
+	m.span.Finish(nil)
+}
+
+// fail ends the item's trace when the pipeline aborts on it.
+func (m *meta) fail(err error) {
+	if m != nil {
+		m.span.Finish(err)
+	}
+}
+
+type metaKey struct{}
+
+// itemContext returns the context a stage function sees for one item.
+func itemContext(ctx context.Context, m *meta) context.Context {
+	if m == nil {
+		return ctx
+	}
+	ctx = context.WithValue(ctx, metaKey{}, m)
+	if m.span != nil {
+		ctx = context.WithValue(ctx, spanKey{}, m.span)
+	}
+	return ctx
+}
+
+// From starts a pipeline with a source. The source calls emit for each
+// item and returns when it is exhausted; emit fails once the pipeline is
+// stopping, and the source should return that error.
+func From[T any](name string, source func(ctx context.Context, emit func(T) error) error) *Pipeline[T] {
+	return &Pipeline[T]{name: name, start: func(ctx context.Context, g *group) <-chan msg[T] {
+		out := make(chan msg[T])
+		sm := g.metrics.stage(name, "", 0, nil)
+		g.Go(func() error {
+			defer close(out)
+			err := source(ctx, func(item T) error {
+				select {
+				case out <- msg[T]{item: item, meta: g.newItem(name, nil)}:
+					sm.add(countOut, 1)
+					return nil
+				case <-ctx.Done():
+					return ctx.Err()
+				}
+			})
+			if err != nil {
+				return fmt.Errorf("pipeline: source %s: %w", name, err)
+			}
+			return nil
+		})
+		return out
+	}}
+}
+
+// FromSlice starts a pipeline that emits items in order.
+func FromSlice[T any](name string, items []T) *Pipeline[T] {
+	return From(name, func(ctx context.Context, emit func(T) error) error {
+		for _, item := range items {
+			if err := emit(item); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Then appends a stage that may change the item type. Use the Then method
+// when it does not.
+func Then[In, Out any](p *Pipeline[In], s Stage[In, Out]) *Pipeline[Out] {
+	workers := max(s.Workers, 1)
+	buffer := s.Buffer
+	if buffer <= 0 {
+		buffer = workers
+	}
+	return &Pipeline[Out]{name: s.Name, start: func(ctx context.Context, g *group) <-chan msg[Out] {
+		in := p.start(ctx, g)
+		out := make(chan msg[Out], buffer)
+		sm := g.metrics.stage(s.Name, p.name, workers, func() (int, int) { return len(in), cap(in) })
+		var wg sync.WaitGroup
+		wg.Add(workers)
+		for range workers {
+			g.Go(func() error {
+				defer wg.Done()
+				return s.work(ctx, g, sm, in, out)
+			})
+		}
+		// Only this stage's workers send on out, so it is safe to close
+		// once they have all returned.
+		go func() {
+			wg.Wait()
+			close(out)
+		}()
+		return out
+	}}
+}
+
+// Then appends a stage that keeps the item type.
+func (p *Pipeline[T]) Then(s Stage[T, T]) *Pipeline[T] {
+	return Then(p, s)
+}
+
+func (s *Stage[In, Out]) work(ctx context.Context, g *group, sm *stageMetrics, in <-chan msg[In], out chan<- msg[Out]) error {
+	for {
+		var m msg[In]
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case v, ok := <-in:
+			if !ok {
+				return nil
+			}
+			m = v
+		}
+		sm.add(countIn, 1)
+		sm.add(countBusy, 1)
+		start := time.Now()
+		itemCtx, span := StartSpan(itemContext(ctx, m.meta), s.Name)
+		res, ok, err := s.process(itemCtx, g, sm, m.item)
+		span.Finish(err)
+		sm.observe(time.Since(start))
+		sm.add(countBusy, -1)
+		if err != nil {
+			err = fmt.Errorf("pipeline: stage %s: %w", s.Name, err)
+			m.meta.fail(err)
+			return err
+		}
+		if !ok {
+			m.meta.done()
+			continue
+		}
+		select {
+		case out <- msg[Out]{item: res, meta: m.meta}:
+			sm.add(countOut, 1)
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// process calls Fn for item, applying the stage's policies to failures.
+// It reports whether res should be passed on; an error aborts the
+// pipeline.
+func (s *Stage[In, Out]) process(ctx context.Context, g *group, sm *stageMetrics, item In) (res Out, ok bool, err error) {
+	for attempt := 1; ; attempt++ {
+		res, err = s.Fn(ctx, item)
+		switch {
+		case err == nil:
+			return res, true, nil
+		case errors.Is(err, ErrSkip):
+			sm.add(countSkipped, 1)
+			return res, false, nil
+		case ctx.Err() != nil:
+			return res, false, ctx.Err()
+		}
+		sm.add(countErrors, 1)
+
+		p := s.policy(err)
+		action := p.Action
+		if action == Retry {
+			if attempt < p.attempts() {
+				if err := sleep(ctx, p.backoff(attempt)); err != nil {
+					return res, false, err
+				}
+				sm.add(countRetries, 1)
+				continue
+			}
+			action = p.Exhausted
+		}
+		switch action {
+		case Skip:
+			sm.add(countSkipped, 1)
+			return res, false, nil
+		case DeadLetter:
+			sm.add(countDeadLettered, 1)
+			return res, false, g.deadLetter(ctx, s.Name, item, attempt, err)
+		default:
+			return res, false, err
+		}
+	}
+}
+
+// Run starts every stage, discards the final stage's output and blocks
+// until the source is exhausted and all items have passed through. The
+// first error stops the pipeline and is returned.
+func (p *Pipeline[T]) Run(ctx context.Context) error {
+	return p.Each(ctx, func(T) error { return nil })
+}
+
+// Each is Run with fn called, from one goroutine, for every item the final
+// stage produces. An error from fn stops the pipeline.
+func (p *Pipeline[T]) Each(ctx context.Context, fn func(T) error) error {
+	g, ctx := newGroup(ctx)
+	out := p.start(ctx, g)
+	g.Go(func() error {
+		for m := range out {
+			if err := fn(m.item); err != nil {
+				return err
+			}
+			m.meta.done()
+		}
+		return nil
+	})
+	err := g.Wait()
+	for _, hook := range g.hooks {
+		if herr := hook(context.WithoutCancel(ctx), err); err == nil {
+			err = herr
+		}
+	}
+	return err
+}
+
+// group runs goroutines, records the first error and cancels the others.
+// It also holds the state shared by one run of a pipeline.
+type group struct {
+	wg     sync.WaitGroup
+	cancel context.CancelCauseFunc
+	once   sync.Once
+	err    error
+
+	deadLetters DeadLetterStore
+	metrics     *Metrics
+	tracer      Tracer
+
+	mu    sync.Mutex
+	hooks []func(ctx context.Context, err error) error
+}
+
+type groupKey struct{}
+
+func newGroup(ctx context.Context) (*group, context.Context) {
+	ctx, cancel := context.WithCancelCause(ctx)
+	g := &group{cancel: cancel}
+	return g, context.WithValue(ctx, groupKey{}, g)
+}
+
+// newItem adds the bookkeeping the run needs to an item emitted by
+// source; m may be nil.
+func (g *group) newItem(source string, m *meta) *meta {
+	if g.tracer == nil {
+		return m
+	}
+	if m == nil {
+		m = &meta{}
+	}
+	m.span = newTrace(g.tracer, source)
+	return m
+}
+
+// groupFrom returns the run a source or stage is part of.
+func groupFrom(ctx context.Context) *group {
+	return ctx.Value(groupKey{}).(*group)
+}
+
+// onFinish registers fn to run once every goroutine has returned. It is
+// passed the run's error, and an error it returns fails a successful run.
+func (g *group) onFinish(fn func(ctx context.Context, err error) error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.hooks = append(g.hooks, fn)
+}
+
+func (g *group) Go(fn func() error) {
+	g.wg.Add(1)
+	go func() {
+		defer g.wg.Done()
+		if err := fn(); err != nil {
+			g.once.Do(func() {
+				g.err = err
+				g.cancel(err)
+			})
+		}
+	}()
+}
+
+func (g *group) Wait() error {
+	g.wg.Wait()
+	g.cancel(nil)
+	return g.err
+}
@@ -0,0 +1,105 @@
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime"
+	"slices"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestPipelineTransformsEveryItem(t *testing.T) {
+	nums := make([]int, 100)
+	for i := range nums {
+		nums[i] = i
+	}
+	squared := FromSlice("numbers", nums).Then(Stage[int, int]{
+		Name:    "square",
+		Workers: 4,
+		Fn:      func(_ context.Context, n int) (int, error) { return n * n, nil },
+	})
+	p := Then(squared, Stage[int, string]{
+		Name: "format",
+		Fn: func(_ context.Context, n int) (string, error) {
+			if n%2 == 1 {
+				return "", fmt.Errorf("odd %d: %w", n, ErrSkip)
+			}
+			return strconv.Itoa(n), nil
+		},
+	})
+
+	// A pipeline is a description and can be run more than once.
+	for range 2 {
+		var got []string
+		if err := p.Each(context.Background(), func(s string) error {
+			got = append(got, s)
+			return nil
+		}); err != nil {
+			t.Fatal(err)
+		}
+		if len(got) != 50 || !slices.Contains(got, "9604") {
+			t.Fatalf("got %d items: %v", len(got), got)
+		}
+	}
+}
+
+func TestPipelineReturnsFirstErrorAndStops(t *testing.T) {
+	boom := errors.New("boom")
+	before := runtime.NumGoroutine()
+
+	endless := From("endless", func(ctx context.Context, emit func(int) error) error {
+		for i := 0; ; i++ {
+			if err := emit(i); err != nil {
+				return err
+			}
+		}
+	})
+	p := endless.Then(Stage[int, int]{
+		Name:    "fail-at-10",
+		Workers: 3,
+		Fn: func(_ context.Context, n int) (int, error) {
+			if n == 10 {
+				return 0, boom
+			}
+			return n, nil
+		},
+	}).Then(Stage[int, int]{
+		Name: "slow",
+		Fn: func(ctx context.Context, n int) (int, error) {
+			time.Sleep(time.Millisecond)
+			return n, nil
+		},
+	})
+
+	err := p.Run(context.Background())
+	if !errors.Is(err, boom) || err.Error() != "pipeline: stage fail-at-10: boom" {
+		t.Fatalf("Run = %v", err)
+	}
+
+	// Every stage goroutine has exited and closed its channel.
+	deadline := time.Now().Add(time.Second)
+	for runtime.NumGoroutine() > before && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if n := runtime.NumGoroutine(); n > before {
+		t.Fatalf("%d goroutines leaked", n-before)
+	}
+}
+
+func TestPipelineHonorsCancellation(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	p := From("ticker", func(ctx context.Context, emit func(int) error) error {
+		for {
+			if err := emit(1); err != nil {
+				return err
+			}
+		}
+	})
+	if err := p.Run(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Run = %v", err)
+	}
+}
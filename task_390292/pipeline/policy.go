@@ -0,0 +1,127 @@
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"time"
+)
+
+// Action is what a stage does with an item whose function failed.
+type Action int
+
+const (
+	// Abort stops the pipeline; Run returns the error.
+	Abort Action = iota
+	// Retry calls the stage function again after a backoff.
+	Retry
+	// Skip drops the item.
+	Skip
+	// DeadLetter hands the item to the pipeline's dead-letter store.
+	DeadLetter
+)
+
+func (a Action) String() string {
+	switch a {
+	case Abort:
+		return "abort"
+	case Retry:
+		return "retry"
+	case Skip:
+		return "skip"
+	case DeadLetter:
+		return "dead-letter"
+	}
+	return "unknown"
+}
+
+// Policy decides how a stage reacts to a class of errors. A stage uses the
+// first of its policies whose Match accepts the error; errors no policy
+// matches abort the pipeline.
+type Policy struct {
+	// Match selects the errors this policy applies to. Nil matches all.
+	Match  func(error) bool
+	Action Action
+	// MaxAttempts bounds the calls made under Retry, the first included.
+	// Defaults to 3.
+	MaxAttempts int
+	// Backoff returns the wait before retry n (1-based). Defaults to
+	// jittered exponential backoff starting at 100ms.
+	Backoff func(n int) time.Duration
+	// Exhausted is the action taken once Retry runs out of attempts.
+	Exhausted Action
+}
+
+// RetryOn retries errors matched by match up to attempts times, then
+// applies exhausted.
+func RetryOn(match func(error) bool, attempts int, exhausted Action) Policy {
+	return Policy{Match: match, Action: Retry, MaxAttempts: attempts, Exhausted: exhausted}
+}
+
+// SkipOn drops items failing with errors matched by match.
+func SkipOn(match func(error) bool) Policy {
+	return Policy{Match: match, Action: Skip}
+}
+
+// DeadLetterOn dead-letters items failing with errors matched by match.
+func DeadLetterOn(match func(error) bool) Policy {
+	return Policy{Match: match, Action: DeadLetter}
+}
+
+// AbortOn stops the pipeline on errors matched by match. It is useful
+// ahead of broader policies.
+func AbortOn(match func(error) bool) Policy {
+	return Policy{Match: match, Action: Abort}
+}
+
+// Is matches errors that match target under errors.Is.
+func Is(target error) func(error) bool {
+	return func(err error) bool { return errors.Is(err, target) }
+}
+
+// As matches errors that have a T in their chain under errors.As.
+func As[T error]() func(error) bool {
+	return func(err error) bool {
+		var target T
+		return errors.As(err, &target)
+	}
+}
+
+func (s *Stage[In, Out]) policy(err error) Policy {
+	for _, p := range s.Policies {
+		if p.Match == nil || p.Match(err) {
+			return p
+		}
+	}
+	return Policy{Action: Abort}
+}
+
+func (p Policy) attempts() int {
+	if p.MaxAttempts <= 0 {
+		return 3
+	}
+	return p.MaxAttempts
+}
+
+// maxBackoff is the longest wait the default backoff picks.
+const maxBackoff = 100 * time.Millisecond << 10
+
+func (p Policy) backoff(n int) time.Duration {
+	if p.Backoff != nil {
+		return p.Backoff(n)
+	}
+	d := 100 * time.Millisecond << min(n-1, 10)
+	return d/2 + time.Duration(rand.Int63n(int64(d/2)+1))
+}
+
+// sleep waits for d or until ctx is done.
+func sleep(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,159 @@
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+var (
+	errFlaky   = errors.New("flaky")
+	errInvalid = errors.New("invalid")
+	errPoison  = errors.New("poison")
+)
+
+type order struct {
+	ID    int    `json:"id"`
+	State string `json:"state"`
+}
+
+func TestPoliciesPerErrorClass(t *testing.T) {
+	store := NewMemoryStore()
+	var mu sync.Mutex
+	calls := map[int]int{}
+	fixed := false
+
+	validate := Stage[order, order]{
+		Name:    "validate",
+		Workers: 2,
+		Fn: func(_ context.Context, o order) (order, error) {
+			mu.Lock()
+			calls[o.ID]++
+			n := calls[o.ID]
+			mu.Unlock()
+			switch {
+			case o.ID == 1 && n < 3:
+				return o, fmt.Errorf("call %d: %w", n, errFlaky)
+			case o.ID == 2:
+				return o, errInvalid
+			case o.ID == 3 && !fixed:
+				return o, fmt.Errorf("order %d: %w", o.ID, errPoison)
+			}
+			o.State = "valid"
+			return o, nil
+		},
+		Policies: []Policy{
+			{Match: Is(errFlaky), Action: Retry, MaxAttempts: 3, Backoff: func(int) time.Duration { return time.Millisecond }},
+			SkipOn(Is(errInvalid)),
+			DeadLetterOn(Is(errPoison)),
+		},
+	}
+
+	var got []order
+	collect := func(o order) error { got = append(got, o); return nil }
+	orders := []order{{ID: 1}, {ID: 2}, {ID: 3}, {ID: 4}}
+	if err := FromSlice("orders", orders).Then(validate).WithDeadLetters(store).Each(context.Background(), collect); err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 || calls[1] != 3 {
+		t.Fatalf("got %v after %d calls for order 1", got, calls[1])
+	}
+
+	letters, err := store.Load(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(letters) != 1 {
+		t.Fatalf("got %d letters, want 1", len(letters))
+	}
+	l := letters[0]
+	if l.Stage != "validate" || l.Attempts != 1 || string(l.Item) != `{"id":3,"state":""}` ||
+		len(l.Causes) != 2 || l.Causes[1].Message != "poison" {
+		t.Fatalf("unexpected letter %+v", l)
+	}
+
+	// Once the cause is fixed, replaying drains the store.
+	fixed, got = true, nil
+	if err := Then(FromDeadLetters[order](store, "validate"), validate).Each(context.Background(), collect); err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 1 || got[0] != (order{ID: 3, State: "valid"}) {
+		t.Fatalf("replayed %v", got)
+	}
+	if letters, _ := store.Load(context.Background()); len(letters) != 0 {
+		t.Fatalf("%d letters left after replay", len(letters))
+	}
+}
+
+func TestJSONLStoreReplaysAfterRestart(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "dead.jsonl")
+	fixed := false
+	stage := Stage[order, order]{
+		Name: "ship",
+		Fn: func(_ context.Context, o order) (order, error) {
+			if o.ID%2 == 0 && !fixed {
+				return o, errPoison
+			}
+			o.State = "shipped"
+			return o, nil
+		},
+		Policies: []Policy{DeadLetterOn(Is(errPoison))},
+	}
+	orders := []order{{ID: 1}, {ID: 2}, {ID: 3}, {ID: 4}}
+	if err := FromSlice("orders", orders).Then(stage).WithDeadLetters(NewJSONLStore(path)).Run(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	// A letter from another stage must survive the replay.
+	other := Letter{ID: newLetterID(), Stage: "bill", Item: []byte(`{"id":9}`)}
+	if err := NewJSONLStore(path).Put(context.Background(), []Letter{other}); err != nil {
+		t.Fatal(err)
+	}
+
+	// A fresh store on the same file, as after a restart, replays them.
+	fixed = true
+	var got []order
+	collect := func(o order) error { got = append(got, o); return nil }
+	if err := Then(FromDeadLetters[order](NewJSONLStore(path), "ship"), stage).Each(context.Background(), collect); err != nil {
+		t.Fatal(err)
+	}
+	want := []order{{ID: 2, State: "shipped"}, {ID: 4, State: "shipped"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("replayed %v, want %v", got, want)
+	}
+	letters, err := NewJSONLStore(path).Load(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(letters) != 1 || letters[0].ID != other.ID {
+		t.Fatalf("letters left after replay: %+v", letters)
+	}
+}
+
+func TestPoliciesAbort(t *testing.T) {
+	stage := Stage[int, int]{
+		Name: "parse",
+		Fn: func(_ context.Context, n int) (int, error) {
+			if n == 2 {
+				return 0, errFlaky
+			}
+			return n, nil
+		},
+		Policies: []Policy{RetryOn(Is(errFlaky), 2, Abort)},
+	}
+	stage.Policies[0].Backoff = func(int) time.Duration { return 0 }
+	err := FromSlice("nums", []int{1, 2, 3}).Then(stage).Run(context.Background())
+	if !errors.Is(err, errFlaky) {
+		t.Fatalf("Run = %v", err)
+	}
+
+	// DeadLetter without a store aborts rather than losing the item.
+	stage.Policies = []Policy{DeadLetterOn(nil)}
+	if err := FromSlice("nums", []int{2}).Then(stage).Run(context.Background()); !errors.Is(err, errFlaky) {
+		t.Fatalf("Run = %v", err)
+	}
+}
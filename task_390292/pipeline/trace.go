@@ -0,0 +1,91 @@
+package pipeline
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"sync"
+	"time"
+)
+
+// Span times one item, or one stage's work on it. Each item gets a root
+// span when its source emits it; every stage it passes through adds a
+// child span, and stage functions can add their own with StartSpan.
+type Span struct {
+	TraceID  string    `json:"trace_id"`
+	SpanID   string    `json:"span_id"`
+	ParentID string    `json:"parent_id,omitempty"`
+	Name     string    `json:"name"`
+	Start    time.Time `json:"start"`
+	End      time.Time `json:"end"`
+	Error    string    `json:"error,omitempty"`
+
+	tracer Tracer
+	once   sync.Once
+}
+
+// Tracer receives spans as they finish. Export is called from stage
+// goroutines and must be safe for concurrent use.
+type Tracer interface {
+	Export(span *Span)
+}
+
+// WithTracer returns a copy of p that traces every item through t.
+func (p *Pipeline[T]) WithTracer(t Tracer) *Pipeline[T] {
+	return &Pipeline[T]{name: p.name, start: func(ctx context.Context, g *group) <-chan msg[T] {
+		g.tracer = t
+		return p.start(ctx, g)
+	}}
+}
+
+type spanKey struct{}
+
+// SpanFromContext returns the span of the item being processed, or nil
+// when the pipeline is not traced.
+func SpanFromContext(ctx context.Context) *Span {
+	s, _ := ctx.Value(spanKey{}).(*Span)
+	return s
+}
+
+// StartSpan starts a child of the span in ctx. Without one it returns ctx
+// and a nil span, on which Finish does nothing.
+func StartSpan(ctx context.Context, name string) (context.Context, *Span) {
+	parent := SpanFromContext(ctx)
+	if parent == nil {
+		return ctx, nil
+	}
+	s := &Span{
+		TraceID:  parent.TraceID,
+		SpanID:   newSpanID(8),
+		ParentID: parent.SpanID,
+		Name:     name,
+		Start:    time.Now(),
+		tracer:   parent.tracer,
+	}
+	return context.WithValue(ctx, spanKey{}, s), s
+}
+
+// newTrace starts the root span of an item.
+func newTrace(t Tracer, name string) *Span {
+	return &Span{TraceID: newSpanID(16), SpanID: newSpanID(8), Name: name, Start: time.Now(), tracer: t}
+}
+
+// Finish ends the span and exports it. Only the first call has an effect.
+func (s *Span) Finish(err error) {
+	if s == nil {
+		return
+	}
+	s.once.Do(func() {
+		s.End = time.Now()
+		if err != nil {
+			s.Error = err.Error()
+		}
+		s.tracer.Export(s)
+	})
+}
+
+func newSpanID(n int) string {
+	b := make([]byte, n)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
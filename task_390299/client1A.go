@@ -2,14 +2,15 @@ package main
 
 import (
     "context"
+    "io"
     "log"
-    "time"
     "google.golang.org/grpc"
-    pb "path/to/generated/protobuf/package"
+    "google.golang.org/grpc/credentials/insecure"
+    pb "github.com/shailendra-s-123/golang_random_5/task_390299/streampb"
 )
 
 func main() {
-    conn, err := grpc.Dial("localhost:50051", grpc.WithInsecure())
+    conn, err := grpc.NewClient("localhost:50051", grpc.WithTransportCredentials(insecure.NewCredentials()))
     if err != nil {
         log.Fatalf("did not connect: %v", err)
     }
@@ -24,9 +25,12 @@ func main() {
 
     for {
         res, err := stream.Recv()
+        if err == io.EOF {
+            return
+        }
         if err != nil {
             log.Fatalf("error while receiving: %v", err)
         }
-        log.Printf("Received data: %s", res.Data)
+        log.Printf("Received tick %d for %s", res.GetTick().GetIndex(), res.GetTick().GetRequestId())
     }
-}
\ No newline at end of file
+}
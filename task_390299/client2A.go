@@ -2,15 +2,28 @@ package main
 
 import (
     "context"
+    "flag"
     "log"
-    "time"
 
     "google.golang.org/grpc"
-    pb "path/to/your/protobuf/generated/package" // Update this path
+
+    pb "github.com/shailendra-s-123/golang_random_5/task_390299/streampb"
+    "github.com/shailendra-s-123/golang_random_5/task_390299/streamsvc"
 )
 
 func main() {
-    conn, err := grpc.Dial("localhost:50051", grpc.WithInsecure())
+    var cfg streamsvc.ClientConfig
+    flag.StringVar(&cfg.CAFile, "ca", "", "CA file for verifying the server (enables TLS)")
+    flag.StringVar(&cfg.CertFile, "cert", "", "client certificate file for mTLS")
+    flag.StringVar(&cfg.KeyFile, "key", "", "client key file for mTLS")
+    flag.StringVar(&cfg.Token, "token", "", "bearer token")
+    flag.Parse()
+
+    opts, err := cfg.DialOptions()
+    if err != nil {
+        log.Fatalf("bad client config: %v", err)
+    }
+    conn, err := grpc.NewClient("localhost:50051", opts...)
     if err != nil {
         log.Fatalf("did not connect: %v", err)
     }
@@ -20,16 +33,17 @@ func main() {
 
     req := &pb.RequestMessage{RequestId: "request-123"}
 
-    stream, err := client.StreamData(context.Background(), req)
+    // Follow reconnects when the stream breaks and resumes after the last
+    // event received, so no event is lost or handled twice.
+    err = streamsvc.Follow(context.Background(), client, req, streamsvc.FollowOptions{
+        OnReconnect: func(err error, resumeFrom uint64) {
+            log.Printf("stream broke (%v), resuming after event %d", err, resumeFrom)
+        },
+    }, func(res *pb.ResponseMessage) error {
+        log.Printf("Received JSON data #%d: %s", res.Seq, res.Data)
+        return nil
+    })
     if err != nil {
-        log.Fatalf("error while calling StreamData: %v", err)
-    }
-
-    for {
-        res, err := stream.Recv()
-        if err != nil {
-            log.Fatalf("error while receiving: %v", err)
-        }
-        log.Printf("Received JSON data: %s", res.Data)
+        log.Fatalf("error while streaming: %v", err)
     }
-}
\ No newline at end of file
+}
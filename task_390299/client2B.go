@@ -2,24 +2,26 @@ package main
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
+	"io"
 	"log"
 	"time"
 
 	"google.golang.org/grpc"
-	pb "path/to/generated/protobuf/package" // Adjust import path
+	"google.golang.org/grpc/credentials/insecure"
+
+	pb "github.com/shailendra-s-123/golang_random_5/task_390299/streampb"
 )
 
 func main() {
-	conn, err := grpc.Dial("localhost:50051", grpc.WithInsecure())
+	conn, err := grpc.NewClient("localhost:50051", grpc.WithTransportCredentials(insecure.NewCredentials()))
 	if err != nil {
 		log.Fatalf("did not connect: %v", err)
 	}
 	defer conn.Close()
 
 	c := pb.NewStreamServiceClient(conn)
-	req := &pb.Request{ClientId: "client123"}
+	req := &pb.RequestMessage{RequestId: "client123"}
 
 	stream, err := c.StreamData(context.Background(), req)
 	if err != nil {
@@ -35,11 +37,11 @@ func main() {
 			log.Fatalf("error while receiving: %v", err)
 		}
 
-		var data map[string]interface{}
-		if err := json.Unmarshal([]byte(res.Data), &data); err != nil {
-			log.Error(err)
+		sample := res.GetSample()
+		if sample == nil {
+			log.Printf("skipping %s event", res.GetKind())
 			continue
 		}
-		fmt.Println("Received Data:", data)
+		fmt.Println("Received Data:", sample.GetClientId(), sample.GetTime().AsTime().Format(time.RFC3339), sample.GetValue())
 	}
-}
\ No newline at end of file
+}
@@ -2,51 +2,52 @@ package main
 
 import (
     "context"
-    "encoding/json"
+    "flag"
     "log"
     "net"
+    "os"
+    "os/signal"
+    "syscall"
     "time"
 
-    "google.golang.org/grpc"
-    pb "path/to/your/protobuf/generated/package" // Update this path
+    "github.com/shailendra-s-123/golang_random_5/task_390299/streamsvc"
 )
 
-// Server struct implements the gRPC interface
-type server struct {
-    pb.UnimplementedStreamServiceServer
-}
-
-// StreamData method sends continuous data to the client
-func (s *server) StreamData(req *pb.RequestMessage, stream pb.StreamService_StreamDataServer) error {
-    for i := 0; i < 5; i++ { // Simulate 5 messages
-        jsonData, err := json.Marshal(map[string]interface{}{
-            "request_id": req.GetRequestId(),
-            "event":      i,
-        })
-        if err != nil {
-            return err
-        }
-
-        res := &pb.ResponseMessage{Data: string(jsonData)}
-        if err := stream.Send(res); err != nil {
-            return err
-        }
-        time.Sleep(1 * time.Second) // Simulate some processing delay
+func main() {
+    var cfg streamsvc.ServerConfig
+    token := flag.String("token", "", "bearer token clients must send; empty disables token auth")
+    flag.StringVar(&cfg.CertFile, "cert", "", "TLS certificate file")
+    flag.StringVar(&cfg.KeyFile, "key", "", "TLS key file")
+    flag.StringVar(&cfg.ClientCAFile, "client-ca", "", "CA file for verifying client certificates (enables mTLS)")
+    flag.DurationVar(&cfg.MaxTimeout, "max-timeout", 5*time.Minute, "longest deadline allowed for a call")
+    flag.Parse()
+    if *token != "" {
+        cfg.Tokens = map[string]string{*token: "client"}
     }
-    return nil
-}
 
-func main() {
     lis, err := net.Listen("tcp", ":50051")
     if err != nil {
         log.Fatalf("failed to listen: %v", err)
     }
 
-    grpcServer := grpc.NewServer()
-    pb.RegisterStreamServiceServer(grpcServer, &server{})
+    // Five Tick events per request, one second apart. The last 256 events
+    // of each request are kept so clients can resume after a disconnect.
+    grpcServer, err := cfg.Build(&streamsvc.Server{
+        Source:     streamsvc.DemoSource(5, time.Second),
+        ReplaySize: 256,
+    })
+    if err != nil {
+        log.Fatalf("failed to build server: %v", err)
+    }
+
+    // SIGTERM drains the server: in-flight streams finish, new calls are
+    // refused.
+    ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM, os.Interrupt)
+    defer stop()
     log.Println("Server is running on port :50051")
     
-    if err := grpcServer.Serve(lis); err != nil {
+    if err := grpcServer.Serve(ctx, lis); err != nil {
         log.Fatalf("failed to serve: %v", err)
     }
-}
\ No newline at end of file
+    log.Println("Server stopped")
+}
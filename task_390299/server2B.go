@@ -2,46 +2,30 @@ package main
 
 import (
 	"context"
-	"encoding/json"
 	"log"
 	"net"
+	"os"
+	"os/signal"
+	"syscall"
 	"time"
 
-	"google.golang.org/grpc"
-	pb "path/to/generated/protobuf/package" // Adjust import path
+	pb "github.com/shailendra-s-123/golang_random_5/task_390299/streampb"
+	"github.com/shailendra-s-123/golang_random_5/task_390299/streamsvc"
 )
 
-type server struct {
-	pb.UnimplementedStreamServiceServer
-}
-
-func (s *server) StreamData(ctx context.Context, req *pb.Request) (*pb.ResponseStream, error) {
-	stream, err := pb.NewStreamServiceServer(ctx, s)
-	if err != nil {
-		return nil, err
-	}
-
-	go func() {
-		for i := 0; ; i++ {
-			data := map[string]interface{}{
-				"client_id": req.ClientId,
-				"timestamp": time.Now().Format(time.RFC3339),
-				"value":     i,
-			}
-			jsonData, err := json.Marshal(data)
-			if err != nil {
-				log.Error(err)
-				return
-			}
-			if err := stream.Send(&pb.Response{Data: string(jsonData)}); err != nil {
-				log.Error(err)
-				return
-			}
-			time.Sleep(2 * time.Second)
+// samples streams a Sample for the requesting client every two seconds
+// until the client goes away.
+func samples(ctx context.Context, req *pb.RequestMessage, emit func(*pb.ResponseMessage) error) error {
+	for i := int64(0); ; i++ {
+		if err := emit(streamsvc.NewSample(req.GetRequestId(), time.Now(), i)); err != nil {
+			return err
 		}
-	}()
-
-	return stream, nil
+		select {
+		case <-time.After(2 * time.Second):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
 }
 
 func main() {
@@ -49,10 +33,16 @@ func main() {
 	if err != nil {
 		log.Fatalf("failed to listen: %v", err)
 	}
-	s := grpc.NewServer()
-	pb.RegisterStreamServiceServer(s, &server{})
+	// Sample streams never end on their own, so drain for at most ten
+	// seconds on SIGTERM.
+	s, err := streamsvc.ServerConfig{DrainTimeout: 10 * time.Second}.Build(&streamsvc.Server{Source: samples})
+	if err != nil {
+		log.Fatalf("failed to build server: %v", err)
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM, os.Interrupt)
+	defer stop()
 	log.Println("Server is running on :50051")
-	if err := s.Serve(lis); err != nil {
+	if err := s.Serve(ctx, lis); err != nil {
 		log.Fatalf("failed to serve: %v", err)
 	}
-}
\ No newline at end of file
+}
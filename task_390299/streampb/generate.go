@@ -0,0 +1,5 @@
+// Package streampb holds the StreamService protocol: stream.proto and the
+// code generated from it.
+package streampb
+
+//go:generate protoc -I .. --go_out=.. --go_opt=paths=source_relative --go-grpc_out=.. --go-grpc_opt=paths=source_relative streampb/stream.proto
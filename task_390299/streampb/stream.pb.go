@@ -0,0 +1,1131 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.12
+// 	protoc        v5.29.3
+// source: streampb/stream.proto
+
+package streampb
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	anypb "google.golang.org/protobuf/types/known/anypb"
+	timestamppb "google.golang.org/protobuf/types/known/timestamppb"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type RequestMessage struct {
+	state     protoimpl.MessageState `protogen:"open.v1"`
+	RequestId string                 `protobuf:"bytes,1,opt,name=request_id,json=requestId,proto3" json:"request_id,omitempty"`
+	// ResumeFrom is the sequence number of the last event the client has
+	// seen; the server starts after it. Zero starts from the beginning.
+	ResumeFrom    uint64 `protobuf:"varint,2,opt,name=resume_from,json=resumeFrom,proto3" json:"resume_from,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RequestMessage) Reset() {
+	*x = RequestMessage{}
+	mi := &file_streampb_stream_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RequestMessage) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RequestMessage) ProtoMessage() {}
+
+func (x *RequestMessage) ProtoReflect() protoreflect.Message {
+	mi := &file_streampb_stream_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RequestMessage.ProtoReflect.Descriptor instead.
+func (*RequestMessage) Descriptor() ([]byte, []int) {
+	return file_streampb_stream_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *RequestMessage) GetRequestId() string {
+	if x != nil {
+		return x.RequestId
+	}
+	return ""
+}
+
+func (x *RequestMessage) GetResumeFrom() uint64 {
+	if x != nil {
+		return x.ResumeFrom
+	}
+	return 0
+}
+
+type ResponseMessage struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Data is the event as a JSON object, for clients that predate the typed
+	// events. Servers may leave it empty.
+	//
+	// Deprecated: Marked as deprecated in streampb/stream.proto.
+	Data string `protobuf:"bytes,1,opt,name=data,proto3" json:"data,omitempty"`
+	// Kind classifies the event; filters match on it. It names the event
+	// field that is set: "tick", "sample", or for custom events the full
+	// name of the message in the Any.
+	Kind string `protobuf:"bytes,2,opt,name=kind,proto3" json:"kind,omitempty"`
+	// Seq numbers the events of a request from 1, without gaps.
+	Seq uint64 `protobuf:"varint,3,opt,name=seq,proto3" json:"seq,omitempty"`
+	// Types that are valid to be assigned to Event:
+	//
+	//	*ResponseMessage_Tick
+	//	*ResponseMessage_Sample
+	//	*ResponseMessage_Custom
+	Event         isResponseMessage_Event `protobuf_oneof:"event"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ResponseMessage) Reset() {
+	*x = ResponseMessage{}
+	mi := &file_streampb_stream_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ResponseMessage) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ResponseMessage) ProtoMessage() {}
+
+func (x *ResponseMessage) ProtoReflect() protoreflect.Message {
+	mi := &file_streampb_stream_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ResponseMessage.ProtoReflect.Descriptor instead.
+func (*ResponseMessage) Descriptor() ([]byte, []int) {
+	return file_streampb_stream_proto_rawDescGZIP(), []int{1}
+}
+
+// Deprecated: Marked as deprecated in streampb/stream.proto.
+func (x *ResponseMessage) GetData() string {
+	if x != nil {
+		return x.Data
+	}
+	return ""
+}
+
+func (x *ResponseMessage) GetKind() string {
+	if x != nil {
+		return x.Kind
+	}
+	return ""
+}
+
+func (x *ResponseMessage) GetSeq() uint64 {
+	if x != nil {
+		return x.Seq
+	}
+	return 0
+}
+
+func (x *ResponseMessage) GetEvent() isResponseMessage_Event {
+	if x != nil {
+		return x.Event
+	}
+	return nil
+}
+
+func (x *ResponseMessage) GetTick() *Tick {
+	if x != nil {
+		if x, ok := x.Event.(*ResponseMessage_Tick); ok {
+			return x.Tick
+		}
+	}
+	return nil
+}
+
+func (x *ResponseMessage) GetSample() *Sample {
+	if x != nil {
+		if x, ok := x.Event.(*ResponseMessage_Sample); ok {
+			return x.Sample
+		}
+	}
+	return nil
+}
+
+func (x *ResponseMessage) GetCustom() *anypb.Any {
+	if x != nil {
+		if x, ok := x.Event.(*ResponseMessage_Custom); ok {
+			return x.Custom
+		}
+	}
+	return nil
+}
+
+type isResponseMessage_Event interface {
+	isResponseMessage_Event()
+}
+
+type ResponseMessage_Tick struct {
+	Tick *Tick `protobuf:"bytes,4,opt,name=tick,proto3,oneof"`
+}
+
+type ResponseMessage_Sample struct {
+	Sample *Sample `protobuf:"bytes,5,opt,name=sample,proto3,oneof"`
+}
+
+type ResponseMessage_Custom struct {
+	// Custom carries events the schema does not know yet.
+	Custom *anypb.Any `protobuf:"bytes,15,opt,name=custom,proto3,oneof"`
+}
+
+func (*ResponseMessage_Tick) isResponseMessage_Event() {}
+
+func (*ResponseMessage_Sample) isResponseMessage_Event() {}
+
+func (*ResponseMessage_Custom) isResponseMessage_Event() {}
+
+// Tick is the index-th event of a request.
+type Tick struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	RequestId     string                 `protobuf:"bytes,1,opt,name=request_id,json=requestId,proto3" json:"request_id,omitempty"`
+	Index         int64                  `protobuf:"varint,2,opt,name=index,proto3" json:"index,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Tick) Reset() {
+	*x = Tick{}
+	mi := &file_streampb_stream_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Tick) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Tick) ProtoMessage() {}
+
+func (x *Tick) ProtoReflect() protoreflect.Message {
+	mi := &file_streampb_stream_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Tick.ProtoReflect.Descriptor instead.
+func (*Tick) Descriptor() ([]byte, []int) {
+	return file_streampb_stream_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *Tick) GetRequestId() string {
+	if x != nil {
+		return x.RequestId
+	}
+	return ""
+}
+
+func (x *Tick) GetIndex() int64 {
+	if x != nil {
+		return x.Index
+	}
+	return 0
+}
+
+// Sample is a measurement taken for a client.
+type Sample struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	ClientId      string                 `protobuf:"bytes,1,opt,name=client_id,json=clientId,proto3" json:"client_id,omitempty"`
+	Time          *timestamppb.Timestamp `protobuf:"bytes,2,opt,name=time,proto3" json:"time,omitempty"`
+	Value         int64                  `protobuf:"varint,3,opt,name=value,proto3" json:"value,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Sample) Reset() {
+	*x = Sample{}
+	mi := &file_streampb_stream_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Sample) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Sample) ProtoMessage() {}
+
+func (x *Sample) ProtoReflect() protoreflect.Message {
+	mi := &file_streampb_stream_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Sample.ProtoReflect.Descriptor instead.
+func (*Sample) Descriptor() ([]byte, []int) {
+	return file_streampb_stream_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *Sample) GetClientId() string {
+	if x != nil {
+		return x.ClientId
+	}
+	return ""
+}
+
+func (x *Sample) GetTime() *timestamppb.Timestamp {
+	if x != nil {
+		return x.Time
+	}
+	return nil
+}
+
+func (x *Sample) GetValue() int64 {
+	if x != nil {
+		return x.Value
+	}
+	return 0
+}
+
+type ClientMessage struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Types that are valid to be assigned to Msg:
+	//
+	//	*ClientMessage_Start
+	//	*ClientMessage_Ack
+	//	*ClientMessage_Credit
+	//	*ClientMessage_Filter
+	Msg           isClientMessage_Msg `protobuf_oneof:"msg"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ClientMessage) Reset() {
+	*x = ClientMessage{}
+	mi := &file_streampb_stream_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ClientMessage) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ClientMessage) ProtoMessage() {}
+
+func (x *ClientMessage) ProtoReflect() protoreflect.Message {
+	mi := &file_streampb_stream_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ClientMessage.ProtoReflect.Descriptor instead.
+func (*ClientMessage) Descriptor() ([]byte, []int) {
+	return file_streampb_stream_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *ClientMessage) GetMsg() isClientMessage_Msg {
+	if x != nil {
+		return x.Msg
+	}
+	return nil
+}
+
+func (x *ClientMessage) GetStart() *Start {
+	if x != nil {
+		if x, ok := x.Msg.(*ClientMessage_Start); ok {
+			return x.Start
+		}
+	}
+	return nil
+}
+
+func (x *ClientMessage) GetAck() *Ack {
+	if x != nil {
+		if x, ok := x.Msg.(*ClientMessage_Ack); ok {
+			return x.Ack
+		}
+	}
+	return nil
+}
+
+func (x *ClientMessage) GetCredit() *Credit {
+	if x != nil {
+		if x, ok := x.Msg.(*ClientMessage_Credit); ok {
+			return x.Credit
+		}
+	}
+	return nil
+}
+
+func (x *ClientMessage) GetFilter() *Filter {
+	if x != nil {
+		if x, ok := x.Msg.(*ClientMessage_Filter); ok {
+			return x.Filter
+		}
+	}
+	return nil
+}
+
+type isClientMessage_Msg interface {
+	isClientMessage_Msg()
+}
+
+type ClientMessage_Start struct {
+	Start *Start `protobuf:"bytes,1,opt,name=start,proto3,oneof"`
+}
+
+type ClientMessage_Ack struct {
+	Ack *Ack `protobuf:"bytes,2,opt,name=ack,proto3,oneof"`
+}
+
+type ClientMessage_Credit struct {
+	Credit *Credit `protobuf:"bytes,3,opt,name=credit,proto3,oneof"`
+}
+
+type ClientMessage_Filter struct {
+	Filter *Filter `protobuf:"bytes,4,opt,name=filter,proto3,oneof"`
+}
+
+func (*ClientMessage_Start) isClientMessage_Msg() {}
+
+func (*ClientMessage_Ack) isClientMessage_Msg() {}
+
+func (*ClientMessage_Credit) isClientMessage_Msg() {}
+
+func (*ClientMessage_Filter) isClientMessage_Msg() {}
+
+type Start struct {
+	state   protoimpl.MessageState `protogen:"open.v1"`
+	Request *RequestMessage        `protobuf:"bytes,1,opt,name=request,proto3" json:"request,omitempty"`
+	// Credit is the number of messages the server may send before the
+	// client grants more.
+	Credit        uint32  `protobuf:"varint,2,opt,name=credit,proto3" json:"credit,omitempty"`
+	Filter        *Filter `protobuf:"bytes,3,opt,name=filter,proto3" json:"filter,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Start) Reset() {
+	*x = Start{}
+	mi := &file_streampb_stream_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Start) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Start) ProtoMessage() {}
+
+func (x *Start) ProtoReflect() protoreflect.Message {
+	mi := &file_streampb_stream_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Start.ProtoReflect.Descriptor instead.
+func (*Start) Descriptor() ([]byte, []int) {
+	return file_streampb_stream_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *Start) GetRequest() *RequestMessage {
+	if x != nil {
+		return x.Request
+	}
+	return nil
+}
+
+func (x *Start) GetCredit() uint32 {
+	if x != nil {
+		return x.Credit
+	}
+	return 0
+}
+
+func (x *Start) GetFilter() *Filter {
+	if x != nil {
+		return x.Filter
+	}
+	return nil
+}
+
+// Ack reports how many messages the client has processed so far.
+type Ack struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Received      uint64                 `protobuf:"varint,1,opt,name=received,proto3" json:"received,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Ack) Reset() {
+	*x = Ack{}
+	mi := &file_streampb_stream_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Ack) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Ack) ProtoMessage() {}
+
+func (x *Ack) ProtoReflect() protoreflect.Message {
+	mi := &file_streampb_stream_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Ack.ProtoReflect.Descriptor instead.
+func (*Ack) Descriptor() ([]byte, []int) {
+	return file_streampb_stream_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *Ack) GetReceived() uint64 {
+	if x != nil {
+		return x.Received
+	}
+	return 0
+}
+
+// Credit lets the server send grant more messages.
+type Credit struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Grant         uint32                 `protobuf:"varint,1,opt,name=grant,proto3" json:"grant,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Credit) Reset() {
+	*x = Credit{}
+	mi := &file_streampb_stream_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Credit) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Credit) ProtoMessage() {}
+
+func (x *Credit) ProtoReflect() protoreflect.Message {
+	mi := &file_streampb_stream_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Credit.ProtoReflect.Descriptor instead.
+func (*Credit) Descriptor() ([]byte, []int) {
+	return file_streampb_stream_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *Credit) GetGrant() uint32 {
+	if x != nil {
+		return x.Grant
+	}
+	return 0
+}
+
+// Filter replaces the stream's filter. An empty filter passes everything.
+type Filter struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Kinds         []string               `protobuf:"bytes,1,rep,name=kinds,proto3" json:"kinds,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Filter) Reset() {
+	*x = Filter{}
+	mi := &file_streampb_stream_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Filter) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Filter) ProtoMessage() {}
+
+func (x *Filter) ProtoReflect() protoreflect.Message {
+	mi := &file_streampb_stream_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Filter.ProtoReflect.Descriptor instead.
+func (*Filter) Descriptor() ([]byte, []int) {
+	return file_streampb_stream_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *Filter) GetKinds() []string {
+	if x != nil {
+		return x.Kinds
+	}
+	return nil
+}
+
+type SubscriptionControl struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Types that are valid to be assigned to Msg:
+	//
+	//	*SubscriptionControl_Subscribe
+	//	*SubscriptionControl_Unsubscribe
+	Msg           isSubscriptionControl_Msg `protobuf_oneof:"msg"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SubscriptionControl) Reset() {
+	*x = SubscriptionControl{}
+	mi := &file_streampb_stream_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SubscriptionControl) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SubscriptionControl) ProtoMessage() {}
+
+func (x *SubscriptionControl) ProtoReflect() protoreflect.Message {
+	mi := &file_streampb_stream_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SubscriptionControl.ProtoReflect.Descriptor instead.
+func (*SubscriptionControl) Descriptor() ([]byte, []int) {
+	return file_streampb_stream_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *SubscriptionControl) GetMsg() isSubscriptionControl_Msg {
+	if x != nil {
+		return x.Msg
+	}
+	return nil
+}
+
+func (x *SubscriptionControl) GetSubscribe() *TopicSubscribe {
+	if x != nil {
+		if x, ok := x.Msg.(*SubscriptionControl_Subscribe); ok {
+			return x.Subscribe
+		}
+	}
+	return nil
+}
+
+func (x *SubscriptionControl) GetUnsubscribe() *TopicUnsubscribe {
+	if x != nil {
+		if x, ok := x.Msg.(*SubscriptionControl_Unsubscribe); ok {
+			return x.Unsubscribe
+		}
+	}
+	return nil
+}
+
+type isSubscriptionControl_Msg interface {
+	isSubscriptionControl_Msg()
+}
+
+type SubscriptionControl_Subscribe struct {
+	Subscribe *TopicSubscribe `protobuf:"bytes,1,opt,name=subscribe,proto3,oneof"`
+}
+
+type SubscriptionControl_Unsubscribe struct {
+	Unsubscribe *TopicUnsubscribe `protobuf:"bytes,2,opt,name=unsubscribe,proto3,oneof"`
+}
+
+func (*SubscriptionControl_Subscribe) isSubscriptionControl_Msg() {}
+
+func (*SubscriptionControl_Unsubscribe) isSubscriptionControl_Msg() {}
+
+// TopicSubscribe adds a topic to the stream. Subscribing to a topic the
+// stream already carries does nothing.
+type TopicSubscribe struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Topic string                 `protobuf:"bytes,1,opt,name=topic,proto3" json:"topic,omitempty"`
+	// ResumeFrom is the sequence number of the last event seen, as in
+	// RequestMessage.
+	ResumeFrom    uint64 `protobuf:"varint,2,opt,name=resume_from,json=resumeFrom,proto3" json:"resume_from,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *TopicSubscribe) Reset() {
+	*x = TopicSubscribe{}
+	mi := &file_streampb_stream_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *TopicSubscribe) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*TopicSubscribe) ProtoMessage() {}
+
+func (x *TopicSubscribe) ProtoReflect() protoreflect.Message {
+	mi := &file_streampb_stream_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use TopicSubscribe.ProtoReflect.Descriptor instead.
+func (*TopicSubscribe) Descriptor() ([]byte, []int) {
+	return file_streampb_stream_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *TopicSubscribe) GetTopic() string {
+	if x != nil {
+		return x.Topic
+	}
+	return ""
+}
+
+func (x *TopicSubscribe) GetResumeFrom() uint64 {
+	if x != nil {
+		return x.ResumeFrom
+	}
+	return 0
+}
+
+// TopicUnsubscribe removes a topic; the server confirms with a TopicEnd.
+type TopicUnsubscribe struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Topic         string                 `protobuf:"bytes,1,opt,name=topic,proto3" json:"topic,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *TopicUnsubscribe) Reset() {
+	*x = TopicUnsubscribe{}
+	mi := &file_streampb_stream_proto_msgTypes[11]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *TopicUnsubscribe) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*TopicUnsubscribe) ProtoMessage() {}
+
+func (x *TopicUnsubscribe) ProtoReflect() protoreflect.Message {
+	mi := &file_streampb_stream_proto_msgTypes[11]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use TopicUnsubscribe.ProtoReflect.Descriptor instead.
+func (*TopicUnsubscribe) Descriptor() ([]byte, []int) {
+	return file_streampb_stream_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *TopicUnsubscribe) GetTopic() string {
+	if x != nil {
+		return x.Topic
+	}
+	return ""
+}
+
+type TopicEvent struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Topic string                 `protobuf:"bytes,1,opt,name=topic,proto3" json:"topic,omitempty"`
+	// Types that are valid to be assigned to Msg:
+	//
+	//	*TopicEvent_Event
+	//	*TopicEvent_End
+	Msg           isTopicEvent_Msg `protobuf_oneof:"msg"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *TopicEvent) Reset() {
+	*x = TopicEvent{}
+	mi := &file_streampb_stream_proto_msgTypes[12]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *TopicEvent) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*TopicEvent) ProtoMessage() {}
+
+func (x *TopicEvent) ProtoReflect() protoreflect.Message {
+	mi := &file_streampb_stream_proto_msgTypes[12]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use TopicEvent.ProtoReflect.Descriptor instead.
+func (*TopicEvent) Descriptor() ([]byte, []int) {
+	return file_streampb_stream_proto_rawDescGZIP(), []int{12}
+}
+
+func (x *TopicEvent) GetTopic() string {
+	if x != nil {
+		return x.Topic
+	}
+	return ""
+}
+
+func (x *TopicEvent) GetMsg() isTopicEvent_Msg {
+	if x != nil {
+		return x.Msg
+	}
+	return nil
+}
+
+func (x *TopicEvent) GetEvent() *ResponseMessage {
+	if x != nil {
+		if x, ok := x.Msg.(*TopicEvent_Event); ok {
+			return x.Event
+		}
+	}
+	return nil
+}
+
+func (x *TopicEvent) GetEnd() *TopicEnd {
+	if x != nil {
+		if x, ok := x.Msg.(*TopicEvent_End); ok {
+			return x.End
+		}
+	}
+	return nil
+}
+
+type isTopicEvent_Msg interface {
+	isTopicEvent_Msg()
+}
+
+type TopicEvent_Event struct {
+	Event *ResponseMessage `protobuf:"bytes,2,opt,name=event,proto3,oneof"`
+}
+
+type TopicEvent_End struct {
+	End *TopicEnd `protobuf:"bytes,3,opt,name=end,proto3,oneof"`
+}
+
+func (*TopicEvent_Event) isTopicEvent_Msg() {}
+
+func (*TopicEvent_End) isTopicEvent_Msg() {}
+
+// TopicEnd is the last message of a topic. Code is a gRPC status code: OK
+// when the topic ran out of events or was unsubscribed.
+type TopicEnd struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Code          int32                  `protobuf:"varint,1,opt,name=code,proto3" json:"code,omitempty"`
+	Message       string                 `protobuf:"bytes,2,opt,name=message,proto3" json:"message,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *TopicEnd) Reset() {
+	*x = TopicEnd{}
+	mi := &file_streampb_stream_proto_msgTypes[13]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *TopicEnd) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*TopicEnd) ProtoMessage() {}
+
+func (x *TopicEnd) ProtoReflect() protoreflect.Message {
+	mi := &file_streampb_stream_proto_msgTypes[13]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use TopicEnd.ProtoReflect.Descriptor instead.
+func (*TopicEnd) Descriptor() ([]byte, []int) {
+	return file_streampb_stream_proto_rawDescGZIP(), []int{13}
+}
+
+func (x *TopicEnd) GetCode() int32 {
+	if x != nil {
+		return x.Code
+	}
+	return 0
+}
+
+func (x *TopicEnd) GetMessage() string {
+	if x != nil {
+		return x.Message
+	}
+	return ""
+}
+
+var File_streampb_stream_proto protoreflect.FileDescriptor
+
+const file_streampb_stream_proto_rawDesc = "" +
+	"\n" +
+	"\x15streampb/stream.proto\x12\x06stream\x1a\x19google/protobuf/any.proto\x1a\x1fgoogle/protobuf/timestamp.proto\"P\n" +
+	"\x0eRequestMessage\x12\x1d\n" +
+	"\n" +
+	"request_id\x18\x01 \x01(\tR\trequestId\x12\x1f\n" +
+	"\vresume_from\x18\x02 \x01(\x04R\n" +
+	"resumeFrom\"\xd6\x01\n" +
+	"\x0fResponseMessage\x12\x16\n" +
+	"\x04data\x18\x01 \x01(\tB\x02\x18\x01R\x04data\x12\x12\n" +
+	"\x04kind\x18\x02 \x01(\tR\x04kind\x12\x10\n" +
+	"\x03seq\x18\x03 \x01(\x04R\x03seq\x12\"\n" +
+	"\x04tick\x18\x04 \x01(\v2\f.stream.TickH\x00R\x04tick\x12(\n" +
+	"\x06sample\x18\x05 \x01(\v2\x0e.stream.SampleH\x00R\x06sample\x12.\n" +
+	"\x06custom\x18\x0f \x01(\v2\x14.google.protobuf.AnyH\x00R\x06customB\a\n" +
+	"\x05event\";\n" +
+	"\x04Tick\x12\x1d\n" +
+	"\n" +
+	"request_id\x18\x01 \x01(\tR\trequestId\x12\x14\n" +
+	"\x05index\x18\x02 \x01(\x03R\x05index\"k\n" +
+	"\x06Sample\x12\x1b\n" +
+	"\tclient_id\x18\x01 \x01(\tR\bclientId\x12.\n" +
+	"\x04time\x18\x02 \x01(\v2\x1a.google.protobuf.TimestampR\x04time\x12\x14\n" +
+	"\x05value\x18\x03 \x01(\x03R\x05value\"\xb2\x01\n" +
+	"\rClientMessage\x12%\n" +
+	"\x05start\x18\x01 \x01(\v2\r.stream.StartH\x00R\x05start\x12\x1f\n" +
+	"\x03ack\x18\x02 \x01(\v2\v.stream.AckH\x00R\x03ack\x12(\n" +
+	"\x06credit\x18\x03 \x01(\v2\x0e.stream.CreditH\x00R\x06credit\x12(\n" +
+	"\x06filter\x18\x04 \x01(\v2\x0e.stream.FilterH\x00R\x06filterB\x05\n" +
+	"\x03msg\"y\n" +
+	"\x05Start\x120\n" +
+	"\arequest\x18\x01 \x01(\v2\x16.stream.RequestMessageR\arequest\x12\x16\n" +
+	"\x06credit\x18\x02 \x01(\rR\x06credit\x12&\n" +
+	"\x06filter\x18\x03 \x01(\v2\x0e.stream.FilterR\x06filter\"!\n" +
+	"\x03Ack\x12\x1a\n" +
+	"\breceived\x18\x01 \x01(\x04R\breceived\"\x1e\n" +
+	"\x06Credit\x12\x14\n" +
+	"\x05grant\x18\x01 \x01(\rR\x05grant\"\x1e\n" +
+	"\x06Filter\x12\x14\n" +
+	"\x05kinds\x18\x01 \x03(\tR\x05kinds\"\x92\x01\n" +
+	"\x13SubscriptionControl\x126\n" +
+	"\tsubscribe\x18\x01 \x01(\v2\x16.stream.TopicSubscribeH\x00R\tsubscribe\x12<\n" +
+	"\vunsubscribe\x18\x02 \x01(\v2\x18.stream.TopicUnsubscribeH\x00R\vunsubscribeB\x05\n" +
+	"\x03msg\"G\n" +
+	"\x0eTopicSubscribe\x12\x14\n" +
+	"\x05topic\x18\x01 \x01(\tR\x05topic\x12\x1f\n" +
+	"\vresume_from\x18\x02 \x01(\x04R\n" +
+	"resumeFrom\"(\n" +
+	"\x10TopicUnsubscribe\x12\x14\n" +
+	"\x05topic\x18\x01 \x01(\tR\x05topic\"\x80\x01\n" +
+	"\n" +
+	"TopicEvent\x12\x14\n" +
+	"\x05topic\x18\x01 \x01(\tR\x05topic\x12/\n" +
+	"\x05event\x18\x02 \x01(\v2\x17.stream.ResponseMessageH\x00R\x05event\x12$\n" +
+	"\x03end\x18\x03 \x01(\v2\x10.stream.TopicEndH\x00R\x03endB\x05\n" +
+	"\x03msg\"8\n" +
+	"\bTopicEnd\x12\x12\n" +
+	"\x04code\x18\x01 \x01(\x05R\x04code\x12\x18\n" +
+	"\amessage\x18\x02 \x01(\tR\amessage2\xd2\x01\n" +
+	"\rStreamService\x12?\n" +
+	"\n" +
+	"StreamData\x12\x16.stream.RequestMessage\x1a\x17.stream.ResponseMessage0\x01\x12>\n" +
+	"\bExchange\x12\x15.stream.ClientMessage\x1a\x17.stream.ResponseMessage(\x010\x01\x12@\n" +
+	"\tSubscribe\x12\x1b.stream.SubscriptionControl\x1a\x12.stream.TopicEvent(\x010\x01BBZ@github.com/shailendra-s-123/golang_random_5/task_390299/streampbb\x06proto3"
+
+var (
+	file_streampb_stream_proto_rawDescOnce sync.Once
+	file_streampb_stream_proto_rawDescData []byte
+)
+
+func file_streampb_stream_proto_rawDescGZIP() []byte {
+	file_streampb_stream_proto_rawDescOnce.Do(func() {
+		file_streampb_stream_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_streampb_stream_proto_rawDesc), len(file_streampb_stream_proto_rawDesc)))
+	})
+	return file_streampb_stream_proto_rawDescData
+}
+
+var file_streampb_stream_proto_msgTypes = make([]protoimpl.MessageInfo, 14)
+var file_streampb_stream_proto_goTypes = []any{
+	(*RequestMessage)(nil),        // 0: stream.RequestMessage
+	(*ResponseMessage)(nil),       // 1: stream.ResponseMessage
+	(*Tick)(nil),                  // 2: stream.Tick
+	(*Sample)(nil),                // 3: stream.Sample
+	(*ClientMessage)(nil),         // 4: stream.ClientMessage
+	(*Start)(nil),                 // 5: stream.Start
+	(*Ack)(nil),                   // 6: stream.Ack
+	(*Credit)(nil),                // 7: stream.Credit
+	(*Filter)(nil),                // 8: stream.Filter
+	(*SubscriptionControl)(nil),   // 9: stream.SubscriptionControl
+	(*TopicSubscribe)(nil),        // 10: stream.TopicSubscribe
+	(*TopicUnsubscribe)(nil),      // 11: stream.TopicUnsubscribe
+	(*TopicEvent)(nil),            // 12: stream.TopicEvent
+	(*TopicEnd)(nil),              // 13: stream.TopicEnd
+	(*anypb.Any)(nil),             // 14: google.protobuf.Any
+	(*timestamppb.Timestamp)(nil), // 15: google.protobuf.Timestamp
+}
+var file_streampb_stream_proto_depIdxs = []int32{
+	2,  // 0: stream.ResponseMessage.tick:type_name -> stream.Tick
+	3,  // 1: stream.ResponseMessage.sample:type_name -> stream.Sample
+	14, // 2: stream.ResponseMessage.custom:type_name -> google.protobuf.Any
+	15, // 3: stream.Sample.time:type_name -> google.protobuf.Timestamp
+	5,  // 4: stream.ClientMessage.start:type_name -> stream.Start
+	6,  // 5: stream.ClientMessage.ack:type_name -> stream.Ack
+	7,  // 6: stream.ClientMessage.credit:type_name -> stream.Credit
+	8,  // 7: stream.ClientMessage.filter:type_name -> stream.Filter
+	0,  // 8: stream.Start.request:type_name -> stream.RequestMessage
+	8,  // 9: stream.Start.filter:type_name -> stream.Filter
+	10, // 10: stream.SubscriptionControl.subscribe:type_name -> stream.TopicSubscribe
+	11, // 11: stream.SubscriptionControl.unsubscribe:type_name -> stream.TopicUnsubscribe
+	1,  // 12: stream.TopicEvent.event:type_name -> stream.ResponseMessage
+	13, // 13: stream.TopicEvent.end:type_name -> stream.TopicEnd
+	0,  // 14: stream.StreamService.StreamData:input_type -> stream.RequestMessage
+	4,  // 15: stream.StreamService.Exchange:input_type -> stream.ClientMessage
+	9,  // 16: stream.StreamService.Subscribe:input_type -> stream.SubscriptionControl
+	1,  // 17: stream.StreamService.StreamData:output_type -> stream.ResponseMessage
+	1,  // 18: stream.StreamService.Exchange:output_type -> stream.ResponseMessage
+	12, // 19: stream.StreamService.Subscribe:output_type -> stream.TopicEvent
+	17, // [17:20] is the sub-list for method output_type
+	14, // [14:17] is the sub-list for method input_type
+	14, // [14:14] is the sub-list for extension type_name
+	14, // [14:14] is the sub-list for extension extendee
+	0,  // [0:14] is the sub-list for field type_name
+}
+
+func init() { file_streampb_stream_proto_init() }
+func file_streampb_stream_proto_init() {
+	if File_streampb_stream_proto != nil {
+		return
+	}
+	file_streampb_stream_proto_msgTypes[1].OneofWrappers = []any{
+		(*ResponseMessage_Tick)(nil),
+		(*ResponseMessage_Sample)(nil),
+		(*ResponseMessage_Custom)(nil),
+	}
+	file_streampb_stream_proto_msgTypes[4].OneofWrappers = []any{
+		(*ClientMessage_Start)(nil),
+		(*ClientMessage_Ack)(nil),
+		(*ClientMessage_Credit)(nil),
+		(*ClientMessage_Filter)(nil),
+	}
+	file_streampb_stream_proto_msgTypes[9].OneofWrappers = []any{
+		(*SubscriptionControl_Subscribe)(nil),
+		(*SubscriptionControl_Unsubscribe)(nil),
+	}
+	file_streampb_stream_proto_msgTypes[12].OneofWrappers = []any{
+		(*TopicEvent_Event)(nil),
+		(*TopicEvent_End)(nil),
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_streampb_stream_proto_rawDesc), len(file_streampb_stream_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   14,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_streampb_stream_proto_goTypes,
+		DependencyIndexes: file_streampb_stream_proto_depIdxs,
+		MessageInfos:      file_streampb_stream_proto_msgTypes,
+	}.Build()
+	File_streampb_stream_proto = out.File
+	file_streampb_stream_proto_goTypes = nil
+	file_streampb_stream_proto_depIdxs = nil
+}
@@ -0,0 +1,206 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.6.2
+// - protoc             v5.29.3
+// source: streampb/stream.proto
+
+package streampb
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	StreamService_StreamData_FullMethodName = "/stream.StreamService/StreamData"
+	StreamService_Exchange_FullMethodName   = "/stream.StreamService/Exchange"
+	StreamService_Subscribe_FullMethodName  = "/stream.StreamService/Subscribe"
+)
+
+// StreamServiceClient is the client API for StreamService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type StreamServiceClient interface {
+	// StreamData sends the events of one request.
+	StreamData(ctx context.Context, in *RequestMessage, opts ...grpc.CallOption) (grpc.ServerStreamingClient[ResponseMessage], error)
+	// Exchange streams the events of the request named by the first client
+	// message, a Start. Afterwards the client steers the stream with acks,
+	// credit and filter changes. The server sends only while the client has
+	// credit left.
+	Exchange(ctx context.Context, opts ...grpc.CallOption) (grpc.BidiStreamingClient[ClientMessage, ResponseMessage], error)
+	// Subscribe carries many topics over one stream. A topic is the events
+	// of the request whose ID is the topic name. The client subscribes and
+	// unsubscribes at any time; the server interleaves the topics' events
+	// fairly and ends each topic with a TopicEnd.
+	Subscribe(ctx context.Context, opts ...grpc.CallOption) (grpc.BidiStreamingClient[SubscriptionControl, TopicEvent], error)
+}
+
+type streamServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewStreamServiceClient(cc grpc.ClientConnInterface) StreamServiceClient {
+	return &streamServiceClient{cc}
+}
+
+func (c *streamServiceClient) StreamData(ctx context.Context, in *RequestMessage, opts ...grpc.CallOption) (grpc.ServerStreamingClient[ResponseMessage], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &StreamService_ServiceDesc.Streams[0], StreamService_StreamData_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[RequestMessage, ResponseMessage]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type StreamService_StreamDataClient = grpc.ServerStreamingClient[ResponseMessage]
+
+func (c *streamServiceClient) Exchange(ctx context.Context, opts ...grpc.CallOption) (grpc.BidiStreamingClient[ClientMessage, ResponseMessage], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &StreamService_ServiceDesc.Streams[1], StreamService_Exchange_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[ClientMessage, ResponseMessage]{ClientStream: stream}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type StreamService_ExchangeClient = grpc.BidiStreamingClient[ClientMessage, ResponseMessage]
+
+func (c *streamServiceClient) Subscribe(ctx context.Context, opts ...grpc.CallOption) (grpc.BidiStreamingClient[SubscriptionControl, TopicEvent], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &StreamService_ServiceDesc.Streams[2], StreamService_Subscribe_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[SubscriptionControl, TopicEvent]{ClientStream: stream}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type StreamService_SubscribeClient = grpc.BidiStreamingClient[SubscriptionControl, TopicEvent]
+
+// StreamServiceServer is the server API for StreamService service.
+// All implementations must embed UnimplementedStreamServiceServer
+// for forward compatibility.
+type StreamServiceServer interface {
+	// StreamData sends the events of one request.
+	StreamData(*RequestMessage, grpc.ServerStreamingServer[ResponseMessage]) error
+	// Exchange streams the events of the request named by the first client
+	// message, a Start. Afterwards the client steers the stream with acks,
+	// credit and filter changes. The server sends only while the client has
+	// credit left.
+	Exchange(grpc.BidiStreamingServer[ClientMessage, ResponseMessage]) error
+	// Subscribe carries many topics over one stream. A topic is the events
+	// of the request whose ID is the topic name. The client subscribes and
+	// unsubscribes at any time; the server interleaves the topics' events
+	// fairly and ends each topic with a TopicEnd.
+	Subscribe(grpc.BidiStreamingServer[SubscriptionControl, TopicEvent]) error
+	mustEmbedUnimplementedStreamServiceServer()
+}
+
+// UnimplementedStreamServiceServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedStreamServiceServer struct{}
+
+func (UnimplementedStreamServiceServer) StreamData(*RequestMessage, grpc.ServerStreamingServer[ResponseMessage]) error {
+	return status.Error(codes.Unimplemented, "method StreamData not implemented")
+}
+func (UnimplementedStreamServiceServer) Exchange(grpc.BidiStreamingServer[ClientMessage, ResponseMessage]) error {
+	return status.Error(codes.Unimplemented, "method Exchange not implemented")
+}
+func (UnimplementedStreamServiceServer) Subscribe(grpc.BidiStreamingServer[SubscriptionControl, TopicEvent]) error {
+	return status.Error(codes.Unimplemented, "method Subscribe not implemented")
+}
+func (UnimplementedStreamServiceServer) mustEmbedUnimplementedStreamServiceServer() {}
+func (UnimplementedStreamServiceServer) testEmbeddedByValue()                       {}
+
+// UnsafeStreamServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to StreamServiceServer will
+// result in compilation errors.
+type UnsafeStreamServiceServer interface {
+	mustEmbedUnimplementedStreamServiceServer()
+}
+
+func RegisterStreamServiceServer(s grpc.ServiceRegistrar, srv StreamServiceServer) {
+	// If the following call panics, it indicates UnimplementedStreamServiceServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&StreamService_ServiceDesc, srv)
+}
+
+func _StreamService_StreamData_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(RequestMessage)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(StreamServiceServer).StreamData(m, &grpc.GenericServerStream[RequestMessage, ResponseMessage]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type StreamService_StreamDataServer = grpc.ServerStreamingServer[ResponseMessage]
+
+func _StreamService_Exchange_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(StreamServiceServer).Exchange(&grpc.GenericServerStream[ClientMessage, ResponseMessage]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type StreamService_ExchangeServer = grpc.BidiStreamingServer[ClientMessage, ResponseMessage]
+
+func _StreamService_Subscribe_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(StreamServiceServer).Subscribe(&grpc.GenericServerStream[SubscriptionControl, TopicEvent]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type StreamService_SubscribeServer = grpc.BidiStreamingServer[SubscriptionControl, TopicEvent]
+
+// StreamService_ServiceDesc is the grpc.ServiceDesc for StreamService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var StreamService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "stream.StreamService",
+	HandlerType: (*StreamServiceServer)(nil),
+	Methods:     []grpc.MethodDesc{},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "StreamData",
+			Handler:       _StreamService_StreamData_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "Exchange",
+			Handler:       _StreamService_Exchange_Handler,
+			ServerStreams: true,
+			ClientStreams: true,
+		},
+		{
+			StreamName:    "Subscribe",
+			Handler:       _StreamService_Subscribe_Handler,
+			ServerStreams: true,
+			ClientStreams: true,
+		},
+	},
+	Metadata: "streampb/stream.proto",
+}
@@ -0,0 +1,114 @@
+package streamsvc
+
+import (
+	"context"
+	"io"
+	"math/rand"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	pb "github.com/shailendra-s-123/golang_random_5/task_390299/streampb"
+)
+
+// FollowOptions controls how Follow reconnects.
+type FollowOptions struct {
+	// MinBackoff and MaxBackoff bound the delay before a reconnect. The
+	// delay doubles with every failed attempt and is jittered over
+	// [0, delay). Default to 100ms and 10s.
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+	// MaxAttempts is the number of consecutive reconnects without
+	// receiving an event after which Follow gives up. Zero retries forever.
+	MaxAttempts int
+	// OnReconnect, if set, is called before each reconnect with the error
+	// that ended the stream and the sequence number it resumes after.
+	OnReconnect func(err error, resumeFrom uint64)
+}
+
+// Follow streams the events of req to handle, reconnecting with jittered
+// backoff when the stream fails and resuming after the last event handled.
+// Events are handled once each, in order. It returns nil when the server
+// ends the stream, or the error that made it give up.
+func Follow(ctx context.Context, client pb.StreamServiceClient, req *pb.RequestMessage, opts FollowOptions, handle func(*pb.ResponseMessage) error) error {
+	if opts.MinBackoff <= 0 {
+		opts.MinBackoff = 100 * time.Millisecond
+	}
+	if opts.MaxBackoff <= 0 {
+		opts.MaxBackoff = 10 * time.Second
+	}
+	last := req.GetResumeFrom()
+	backoff := opts.MinBackoff
+	attempts := 0
+	for {
+		progressed, err := follow(ctx, client, req.GetRequestId(), &last, handle)
+		if err == nil {
+			return nil
+		}
+		if h, ok := err.(handlerError); ok {
+			return h.error
+		}
+		if progressed {
+			attempts, backoff = 0, opts.MinBackoff
+		}
+		attempts++
+		if !retryable(ctx, err) || opts.MaxAttempts > 0 && attempts > opts.MaxAttempts {
+			return err
+		}
+		if opts.OnReconnect != nil {
+			opts.OnReconnect(err, last)
+		}
+		select {
+		case <-time.After(time.Duration(rand.Int63n(int64(backoff)))):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		backoff = min(2*backoff, opts.MaxBackoff)
+	}
+}
+
+// follow reads one stream, advancing *last past every event handled. It
+// reports whether any event was handled.
+func follow(ctx context.Context, client pb.StreamServiceClient, requestID string, last *uint64, handle func(*pb.ResponseMessage) error) (bool, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	stream, err := client.StreamData(ctx, &pb.RequestMessage{RequestId: requestID, ResumeFrom: *last})
+	if err != nil {
+		return false, err
+	}
+	progressed := false
+	for {
+		m, err := stream.Recv()
+		if err == io.EOF {
+			return progressed, nil
+		}
+		if err != nil {
+			return progressed, err
+		}
+		if m.GetSeq() <= *last {
+			continue // already handled before the reconnect
+		}
+		if err := handle(m); err != nil {
+			return progressed, handlerError{err}
+		}
+		*last = m.GetSeq()
+		progressed = true
+	}
+}
+
+// handlerError marks errors from the handler, which are not retried.
+type handlerError struct{ error }
+
+// retryable reports whether a stream error is worth a reconnect.
+func retryable(ctx context.Context, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+	switch status.Code(err) {
+	case codes.InvalidArgument, codes.OutOfRange, codes.NotFound, codes.PermissionDenied,
+		codes.Unauthenticated, codes.Unimplemented, codes.FailedPrecondition:
+		return false
+	}
+	return true
+}
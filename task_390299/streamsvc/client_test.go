@@ -0,0 +1,145 @@
+package streamsvc
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sync"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	pb "github.com/shailendra-s-123/golang_random_5/task_390299/streampb"
+)
+
+// flaky breaks the first few streams after three events each, and makes
+// the server replay one event too many on every resume.
+type flaky struct {
+	mu     sync.Mutex
+	breaks int
+}
+
+func (f *flaky) intercept(srv interface{}, ss grpc.ServerStream, _ *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	f.mu.Lock()
+	breaking := f.breaks > 0
+	f.breaks--
+	f.mu.Unlock()
+	return handler(srv, &flakyStream{ServerStream: ss, breaking: breaking})
+}
+
+type flakyStream struct {
+	grpc.ServerStream
+	breaking bool
+	sent     int
+}
+
+func (s *flakyStream) RecvMsg(m interface{}) error {
+	err := s.ServerStream.RecvMsg(m)
+	if req, ok := m.(*pb.RequestMessage); ok && req.ResumeFrom > 0 {
+		req.ResumeFrom--
+	}
+	return err
+}
+
+func (s *flakyStream) SendMsg(m interface{}) error {
+	if s.breaking && s.sent == 3 {
+		return status.Error(codes.Unavailable, "connection reset")
+	}
+	s.sent++
+	return s.ServerStream.SendMsg(m)
+}
+
+func TestFollowResumes(t *testing.T) {
+	f := &flaky{breaks: 2}
+	client := dial(t, &Server{Source: (&counting{n: 8}).source}, grpc.StreamInterceptor(f.intercept))
+
+	var got []string
+	var resumed []uint64
+	err := Follow(context.Background(), client, &pb.RequestMessage{RequestId: "r1"}, FollowOptions{
+		MinBackoff:  time.Millisecond,
+		OnReconnect: func(_ error, from uint64) { resumed = append(resumed, from) },
+	}, func(m *pb.ResponseMessage) error {
+		got = append(got, fmt.Sprintf("%d:%s", m.GetSeq(), m.GetData()))
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The second stream replays event 3 and the third events 5 and 6;
+	// Follow hands each event over once.
+	if fmt.Sprint(resumed) != "[3 5]" {
+		t.Fatalf("resumed after %v", resumed)
+	}
+	for i, g := range got {
+		if want := fmt.Sprintf(`%d:{"event":%d}`, i+1, i); g != want {
+			t.Fatalf("event %d = %s, want %s", i, g, want)
+		}
+	}
+	if len(got) != 8 {
+		t.Fatalf("got %d events", len(got))
+	}
+}
+
+func TestFollowStops(t *testing.T) {
+	f := &flaky{breaks: 100}
+	client := dial(t, &Server{Source: (&counting{n: 0}).source}, grpc.StreamInterceptor(f.intercept))
+	// Streams of an empty source end cleanly however flaky the server.
+	if err := Follow(context.Background(), client, &pb.RequestMessage{RequestId: "r1"}, FollowOptions{}, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	client = dial(t, &Server{Source: (&counting{n: 8}).source}, grpc.StreamInterceptor(f.intercept))
+	handled := 0
+	err := Follow(context.Background(), client, &pb.RequestMessage{RequestId: "r1"}, FollowOptions{
+		MinBackoff:  time.Millisecond,
+		MaxAttempts: 1,
+	}, func(*pb.ResponseMessage) error {
+		handled++
+		if handled == 6 {
+			return fmt.Errorf("stop")
+		}
+		return nil
+	})
+	if err == nil || err.Error() != "stop" {
+		t.Fatalf("got %v, want the handler's error", err)
+	}
+}
+
+func TestReplayBuffer(t *testing.T) {
+	client := dial(t, &Server{Source: (&counting{n: 5}).source, ReplaySize: 2})
+	read := func(resumeFrom uint64) ([]uint64, error) {
+		stream, err := client.StreamData(context.Background(), &pb.RequestMessage{RequestId: "r1", ResumeFrom: resumeFrom})
+		if err != nil {
+			return nil, err
+		}
+		var seqs []uint64
+		for {
+			m, err := stream.Recv()
+			if err != nil {
+				if err == io.EOF {
+					return seqs, nil
+				}
+				return seqs, err
+			}
+			seqs = append(seqs, m.GetSeq())
+		}
+	}
+	if seqs, err := read(0); err != nil || fmt.Sprint(seqs) != "[1 2 3 4 5]" {
+		t.Fatalf("first read %v, %v", seqs, err)
+	}
+	// The source is not rerun: later streams are served from the buffer.
+	if seqs, err := read(3); err != nil || fmt.Sprint(seqs) != "[4 5]" {
+		t.Fatalf("resume from 3: %v, %v", seqs, err)
+	}
+	if _, err := read(1); status.Code(err) != codes.OutOfRange {
+		t.Fatalf("resume from 1: %v, want OutOfRange", err)
+	}
+	// A client without a resume point joins at the oldest buffered event
+	// rather than failing because event 1 is gone.
+	if seqs, err := read(0); err != nil || fmt.Sprint(seqs) != "[4 5]" {
+		t.Fatalf("fresh read after wrap: %v, %v", seqs, err)
+	}
+}
@@ -0,0 +1,87 @@
+package streamsvc
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/anypb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	pb "github.com/shailendra-s-123/golang_random_5/task_390299/streampb"
+)
+
+// NewTick returns the index-th event of a request.
+func NewTick(requestID string, index int64) *pb.ResponseMessage {
+	return &pb.ResponseMessage{
+		Kind:  "tick",
+		Event: &pb.ResponseMessage_Tick{Tick: &pb.Tick{RequestId: requestID, Index: index}},
+	}
+}
+
+// NewSample returns a measurement event for a client.
+func NewSample(clientID string, t time.Time, value int64) *pb.ResponseMessage {
+	return &pb.ResponseMessage{
+		Kind:  "sample",
+		Event: &pb.ResponseMessage_Sample{Sample: &pb.Sample{ClientId: clientID, Time: timestamppb.New(t), Value: value}},
+	}
+}
+
+// NewCustom wraps an event the schema has no field for. Its kind is the
+// message's full name.
+func NewCustom(event proto.Message) (*pb.ResponseMessage, error) {
+	a, err := anypb.New(event)
+	if err != nil {
+		return nil, err
+	}
+	return &pb.ResponseMessage{
+		Kind:  string(event.ProtoReflect().Descriptor().FullName()),
+		Event: &pb.ResponseMessage_Custom{Custom: a},
+	}, nil
+}
+
+// LegacyJSON renders an event the way the JSON-only schema sent it in
+// Data: ticks as {"event", "request_id"}, samples as {"client_id",
+// "timestamp", "value"} and custom events in their protobuf JSON mapping.
+func LegacyJSON(m *pb.ResponseMessage) (string, error) {
+	var v interface{}
+	switch e := m.Event.(type) {
+	case *pb.ResponseMessage_Tick:
+		v = map[string]interface{}{
+			"request_id": e.Tick.GetRequestId(),
+			"event":      e.Tick.GetIndex(),
+		}
+	case *pb.ResponseMessage_Sample:
+		v = map[string]interface{}{
+			"client_id": e.Sample.GetClientId(),
+			"timestamp": e.Sample.GetTime().AsTime().Format(time.RFC3339),
+			"value":     e.Sample.GetValue(),
+		}
+	case *pb.ResponseMessage_Custom:
+		b, err := protojson.Marshal(e.Custom)
+		return string(b), err
+	default:
+		return "", fmt.Errorf("streamsvc: event of kind %q has no payload", m.GetKind())
+	}
+	b, err := json.Marshal(v)
+	return string(b), err
+}
+
+// withLegacyJSON fills in Data for events the source leaves it empty on.
+func withLegacyJSON(src Source) Source {
+	return func(ctx context.Context, req *pb.RequestMessage, emit func(*pb.ResponseMessage) error) error {
+		return src(ctx, req, func(m *pb.ResponseMessage) error {
+			if m.Data == "" && m.Event != nil {
+				data, err := LegacyJSON(m)
+				if err != nil {
+					return err
+				}
+				m.Data = data
+			}
+			return emit(m)
+		})
+	}
+}
@@ -0,0 +1,73 @@
+package streamsvc
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+
+	pb "github.com/shailendra-s-123/golang_random_5/task_390299/streampb"
+)
+
+func TestTypedEvents(t *testing.T) {
+	at := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	custom, err := NewCustom(wrapperspb.String("hello"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	src := func(ctx context.Context, req *pb.RequestMessage, emit func(*pb.ResponseMessage) error) error {
+		for _, m := range []*pb.ResponseMessage{NewSample("c1", at, 7), custom} {
+			if err := emit(proto.Clone(m).(*pb.ResponseMessage)); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	for _, legacy := range []bool{true, false} {
+		client := dial(t, &Server{Source: src, DisableLegacyJSON: !legacy})
+		stream, err := client.StreamData(context.Background(), &pb.RequestMessage{RequestId: "r1"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		sample, err := stream.Recv()
+		if err != nil {
+			t.Fatal(err)
+		}
+		s := sample.GetSample()
+		if sample.GetKind() != "sample" || s.GetClientId() != "c1" || !s.GetTime().AsTime().Equal(at) || s.GetValue() != 7 {
+			t.Fatalf("sample %v", sample)
+		}
+		cm, err := stream.Recv()
+		if err != nil {
+			t.Fatal(err)
+		}
+		var str wrapperspb.StringValue
+		if err := cm.GetCustom().UnmarshalTo(&str); err != nil || str.GetValue() != "hello" || cm.GetKind() != "google.protobuf.StringValue" {
+			t.Fatalf("custom %v: %v", cm, err)
+		}
+
+		if !legacy {
+			if sample.GetData() != "" || cm.GetData() != "" {
+				t.Fatalf("legacy JSON sent while disabled: %q, %q", sample.GetData(), cm.GetData())
+			}
+			continue
+		}
+		for _, c := range []struct {
+			data string
+			want map[string]interface{}
+		}{
+			{sample.GetData(), map[string]interface{}{"client_id": "c1", "timestamp": "2024-05-01T12:00:00Z", "value": 7.0}},
+			{cm.GetData(), map[string]interface{}{"@type": "type.googleapis.com/google.protobuf.StringValue", "value": "hello"}},
+		} {
+			var got map[string]interface{}
+			if err := json.Unmarshal([]byte(c.data), &got); err != nil || !reflect.DeepEqual(got, c.want) {
+				t.Fatalf("legacy data %s, want %v", c.data, c.want)
+			}
+		}
+	}
+}
@@ -0,0 +1,108 @@
+package streamsvc
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sync"
+
+	pb "github.com/shailendra-s-123/golang_random_5/task_390299/streampb"
+)
+
+// errDrained is returned by acquire when the client has closed its side
+// of the stream without credit left: nothing more may be sent, and the
+// exchange ends without error.
+var errDrained = errors.New("streamsvc: client closed with no credit left")
+
+// flow is the client-controlled state of one Exchange: remaining credit
+// and the kinds the client wants.
+type flow struct {
+	mu     sync.Mutex
+	credit uint64
+	kinds  map[string]bool // nil passes everything
+	closed bool            // the client half-closed or the stream failed
+	wake   chan struct{}   // closed and replaced whenever credit or closed change
+}
+
+func newFlow(credit uint32, filter *pb.Filter) *flow {
+	f := &flow{credit: uint64(credit), wake: make(chan struct{})}
+	f.setFilter(filter)
+	return f
+}
+
+func (f *flow) setFilter(filter *pb.Filter) {
+	var kinds map[string]bool
+	if len(filter.GetKinds()) > 0 {
+		kinds = make(map[string]bool, len(filter.GetKinds()))
+		for _, k := range filter.GetKinds() {
+			kinds[k] = true
+		}
+	}
+	f.mu.Lock()
+	f.kinds = kinds
+	f.mu.Unlock()
+}
+
+// wants reports whether the client's current filter passes kind.
+func (f *flow) wants(kind string) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.kinds == nil || f.kinds[kind]
+}
+
+// acquire takes one unit of credit, waiting for the client to grant more
+// when none is left.
+func (f *flow) acquire(ctx context.Context) error {
+	for {
+		f.mu.Lock()
+		if f.credit > 0 {
+			f.credit--
+			f.mu.Unlock()
+			return nil
+		}
+		if f.closed {
+			f.mu.Unlock()
+			return errDrained
+		}
+		wake := f.wake
+		f.mu.Unlock()
+
+		select {
+		case <-wake:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// update changes the state under the lock and wakes any waiting acquire.
+func (f *flow) update(fn func()) {
+	f.mu.Lock()
+	fn()
+	close(f.wake)
+	f.wake = make(chan struct{})
+	f.mu.Unlock()
+}
+
+// control applies the client's messages until it half-closes or the stream
+// fails. A half-close is not an error.
+func (f *flow) control(stream pb.StreamService_ExchangeServer, onAck func(received uint64)) error {
+	defer f.update(func() { f.closed = true })
+	for {
+		m, err := stream.Recv()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		switch msg := m.Msg.(type) {
+		case *pb.ClientMessage_Ack:
+			onAck(msg.Ack.GetReceived())
+		case *pb.ClientMessage_Credit:
+			f.update(func() { f.credit += uint64(msg.Credit.GetGrant()) })
+		case *pb.ClientMessage_Filter:
+			f.setFilter(msg.Filter)
+		}
+	}
+}
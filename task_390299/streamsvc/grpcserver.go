@@ -0,0 +1,154 @@
+package streamsvc
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/keepalive"
+	"google.golang.org/grpc/reflection"
+
+	pb "github.com/shailendra-s-123/golang_random_5/task_390299/streampb"
+)
+
+// ServerConfig describes a production gRPC server for StreamService. The
+// zero value serves plaintext without authentication.
+type ServerConfig struct {
+	// CertFile and KeyFile enable TLS.
+	CertFile, KeyFile string
+	// ClientCAFile additionally requires clients to present a certificate
+	// signed by one of its CAs.
+	ClientCAFile string
+	// Tokens maps accepted bearer tokens to the principal they stand for.
+	// They need TLS, so that they never cross the network in the clear.
+	// When empty, calls are not checked for tokens and the principal is
+	// the client certificate's common name, if any. Health checks never
+	// need a token.
+	Tokens map[string]string
+
+	// DefaultTimeout is the deadline of calls that arrive without one, and
+	// MaxTimeout caps the deadline of all calls. Zero leaves them alone.
+	DefaultTimeout time.Duration
+	MaxTimeout     time.Duration
+
+	// Keepalive and KeepalivePolicy default to pinging idle connections
+	// every two minutes and letting clients ping every 30 seconds.
+	Keepalive       keepalive.ServerParameters
+	KeepalivePolicy keepalive.EnforcementPolicy
+
+	// DrainTimeout bounds how long Serve waits for in-flight calls on
+	// shutdown before cutting them off. Defaults to 30 seconds.
+	DrainTimeout time.Duration
+}
+
+// GRPCServer is a grpc.Server with the health service wired to its
+// lifecycle.
+type GRPCServer struct {
+	*grpc.Server
+	health *health.Server
+	drain  time.Duration
+}
+
+// Build returns a server for svc with the health and reflection services
+// registered. It fails if Tokens is set without TLS.
+func (c ServerConfig) Build(svc pb.StreamServiceServer, opts ...grpc.ServerOption) (*GRPCServer, error) {
+	if len(c.Tokens) > 0 && c.CertFile == "" {
+		return nil, errors.New("streamsvc: bearer tokens need TLS: set CertFile and KeyFile")
+	}
+	if c.CertFile != "" {
+		cfg, err := serverTLS(c.CertFile, c.KeyFile, c.ClientCAFile)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, grpc.Creds(credentials.NewTLS(cfg)))
+	}
+	ka := c.Keepalive
+	if ka == (keepalive.ServerParameters{}) {
+		ka = keepalive.ServerParameters{Time: 2 * time.Minute, Timeout: 20 * time.Second}
+	}
+	policy := c.KeepalivePolicy
+	if policy == (keepalive.EnforcementPolicy{}) {
+		policy = keepalive.EnforcementPolicy{MinTime: 30 * time.Second, PermitWithoutStream: true}
+	}
+	auth := &authenticator{tokens: c.Tokens, exempt: func(method string) bool {
+		return strings.HasPrefix(method, "/"+healthpb.Health_ServiceDesc.ServiceName+"/")
+	}}
+	opts = append(opts,
+		grpc.KeepaliveParams(ka),
+		grpc.KeepaliveEnforcementPolicy(policy),
+		grpc.ChainUnaryInterceptor(c.unaryDeadline, auth.unary),
+		grpc.ChainStreamInterceptor(c.streamDeadline, auth.stream),
+	)
+
+	s := &GRPCServer{Server: grpc.NewServer(opts...), health: health.NewServer(), drain: c.DrainTimeout}
+	if s.drain <= 0 {
+		s.drain = 30 * time.Second
+	}
+	pb.RegisterStreamServiceServer(s.Server, svc)
+	healthpb.RegisterHealthServer(s.Server, s.health)
+	reflection.Register(s.Server)
+	s.health.SetServingStatus(pb.StreamService_ServiceDesc.ServiceName, healthpb.HealthCheckResponse_SERVING)
+	return s, nil
+}
+
+// Serve serves lis until ctx is done, then reports NOT_SERVING and stops
+// gracefully, letting in-flight calls such as StreamData finish. Calls
+// still running after DrainTimeout are cancelled.
+func (s *GRPCServer) Serve(ctx context.Context, lis net.Listener) error {
+	errc := make(chan error, 1)
+	go func() { errc <- s.Server.Serve(lis) }()
+	select {
+	case err := <-errc:
+		return err
+	case <-ctx.Done():
+	}
+
+	s.health.Shutdown()
+	stopped := make(chan struct{})
+	go func() {
+		s.GracefulStop()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-time.After(s.drain):
+		s.Stop()
+		<-stopped
+	}
+	return <-errc
+}
+
+// deadline applies DefaultTimeout and MaxTimeout to ctx.
+func (c ServerConfig) deadline(ctx context.Context) (context.Context, context.CancelFunc) {
+	var timeout time.Duration
+	if d, ok := ctx.Deadline(); !ok {
+		timeout = c.DefaultTimeout
+		if c.MaxTimeout > 0 && (timeout == 0 || timeout > c.MaxTimeout) {
+			timeout = c.MaxTimeout
+		}
+	} else if c.MaxTimeout > 0 && time.Until(d) > c.MaxTimeout {
+		timeout = c.MaxTimeout
+	}
+	if timeout == 0 {
+		return ctx, func() {}
+	}
+	return context.WithTimeout(ctx, timeout)
+}
+
+func (c ServerConfig) unaryDeadline(ctx context.Context, req interface{}, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	ctx, cancel := c.deadline(ctx)
+	defer cancel()
+	return handler(ctx, req)
+}
+
+func (c ServerConfig) streamDeadline(srv interface{}, ss grpc.ServerStream, _ *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	ctx, cancel := c.deadline(ss.Context())
+	defer cancel()
+	return handler(srv, contextStream{ss, ctx})
+}
@@ -0,0 +1,283 @@
+package streamsvc
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	pb "github.com/shailendra-s-123/golang_random_5/task_390299/streampb"
+)
+
+// pki writes a CA, a server certificate for 127.0.0.1 and a client
+// certificate for "alice" to dir, returning the CA's path.
+func pki(t *testing.T, dir string) string {
+	t.Helper()
+	caKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	caTmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}
+	caDER, err := x509.CreateCertificate(rand.Reader, caTmpl, caTmpl, &caKey.PublicKey, caKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ca, _ := x509.ParseCertificate(caDER)
+	write := func(name, typ string, der []byte) {
+		if err := os.WriteFile(filepath.Join(dir, name), pem.EncodeToMemory(&pem.Block{Type: typ, Bytes: der}), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("ca.pem", "CERTIFICATE", caDER)
+
+	for i, leaf := range []struct {
+		name, cn string
+		usage    x509.ExtKeyUsage
+	}{
+		{"server", "localhost", x509.ExtKeyUsageServerAuth},
+		{"client", "alice", x509.ExtKeyUsageClientAuth},
+	} {
+		key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		tmpl := &x509.Certificate{
+			SerialNumber: big.NewInt(int64(i + 2)),
+			Subject:      pkix.Name{CommonName: leaf.cn},
+			DNSNames:     []string{leaf.cn},
+			IPAddresses:  []net.IP{net.IPv4(127, 0, 0, 1)},
+			NotBefore:    time.Now().Add(-time.Hour),
+			NotAfter:     time.Now().Add(time.Hour),
+			KeyUsage:     x509.KeyUsageDigitalSignature,
+			ExtKeyUsage:  []x509.ExtKeyUsage{leaf.usage},
+		}
+		der, err := x509.CreateCertificate(rand.Reader, tmpl, ca, &key.PublicKey, caKey)
+		if err != nil {
+			t.Fatal(err)
+		}
+		keyDER, _ := x509.MarshalECPrivateKey(key)
+		write(leaf.name+".pem", "CERTIFICATE", der)
+		write(leaf.name+"-key.pem", "EC PRIVATE KEY", keyDER)
+	}
+	return filepath.Join(dir, "ca.pem")
+}
+
+// serve runs a server built from cfg on a local port until the test ends
+// or stop is called.
+func serve(t *testing.T, cfg ServerConfig, svc *Server) (addr string, stop func() error) {
+	t.Helper()
+	s, err := cfg.Build(svc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- s.Serve(ctx, lis) }()
+	var once sync.Once
+	var serveErr error
+	stop = func() error {
+		once.Do(func() {
+			cancel()
+			serveErr = <-done
+		})
+		return serveErr
+	}
+	t.Cleanup(func() { stop() })
+	return lis.Addr().String(), stop
+}
+
+func connect(t *testing.T, addr string, cfg ClientConfig) *grpc.ClientConn {
+	t.Helper()
+	opts, err := cfg.DialOptions()
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn, err := grpc.NewClient(addr, opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+func TestServerConfigTLSAndAuth(t *testing.T) {
+	dir := t.TempDir()
+	ca := pki(t, dir)
+	svc := &Server{Source: func(ctx context.Context, req *pb.RequestMessage, emit func(*pb.ResponseMessage) error) error {
+		return emit(NewTick(req.GetRequestId(), 0))
+	}}
+	addr, _ := serve(t, ServerConfig{
+		CertFile:     filepath.Join(dir, "server.pem"),
+		KeyFile:      filepath.Join(dir, "server-key.pem"),
+		ClientCAFile: ca,
+		Tokens:       map[string]string{"s3cret": "ops"},
+	}, svc)
+	client := ClientConfig{
+		CAFile:   ca,
+		CertFile: filepath.Join(dir, "client.pem"),
+		KeyFile:  filepath.Join(dir, "client-key.pem"),
+	}
+
+	// Health checks need no token.
+	conn := connect(t, addr, client)
+	resp, err := healthpb.NewHealthClient(conn).Check(context.Background(), &healthpb.HealthCheckRequest{Service: "stream.StreamService"})
+	if err != nil || resp.GetStatus() != healthpb.HealthCheckResponse_SERVING {
+		t.Fatalf("health: %v, %v", resp, err)
+	}
+
+	call := func(conn *grpc.ClientConn) error {
+		stream, err := pb.NewStreamServiceClient(conn).StreamData(context.Background(), &pb.RequestMessage{RequestId: "r1"})
+		if err != nil {
+			return err
+		}
+		for {
+			if _, err := stream.Recv(); err == io.EOF {
+				return nil
+			} else if err != nil {
+				return err
+			}
+		}
+	}
+	if err := call(conn); status.Code(err) != codes.Unauthenticated {
+		t.Fatalf("without token: %v", err)
+	}
+	client.Token = "wrong"
+	if err := call(connect(t, addr, client)); status.Code(err) != codes.Unauthenticated {
+		t.Fatalf("wrong token: %v", err)
+	}
+	client.Token = "s3cret"
+	if err := call(connect(t, addr, client)); err != nil {
+		t.Fatalf("valid token: %v", err)
+	}
+
+	// Without a client certificate the handshake fails.
+	client.CertFile, client.KeyFile = "", ""
+	if err := call(connect(t, addr, client)); status.Code(err) != codes.Unavailable {
+		t.Fatalf("without client certificate: %v", err)
+	}
+}
+
+func TestServerConfigDeadlines(t *testing.T) {
+	dir := t.TempDir()
+	ca := pki(t, dir)
+	addr, _ := serve(t, ServerConfig{
+		CertFile:     filepath.Join(dir, "server.pem"),
+		KeyFile:      filepath.Join(dir, "server-key.pem"),
+		ClientCAFile: ca,
+		MaxTimeout:   50 * time.Millisecond,
+	}, &Server{Source: func(ctx context.Context, req *pb.RequestMessage, emit func(*pb.ResponseMessage) error) error {
+		return emit(NewTick(req.GetRequestId(), 0))
+	}})
+	conn := connect(t, addr, ClientConfig{
+		CAFile:   ca,
+		CertFile: filepath.Join(dir, "client.pem"),
+		KeyFile:  filepath.Join(dir, "client-key.pem"),
+	})
+
+	// Without credit the server never sends, and the call is cut off at
+	// MaxTimeout although the client set no deadline.
+	start := time.Now()
+	stream, err := pb.NewStreamServiceClient(conn).Exchange(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	stream.Send(&pb.ClientMessage{Msg: &pb.ClientMessage_Start{Start: &pb.Start{Request: &pb.RequestMessage{RequestId: "r1"}}}})
+	if _, err := stream.Recv(); status.Code(err) != codes.DeadlineExceeded {
+		t.Fatalf("got %v, want DeadlineExceeded", err)
+	}
+	if d := time.Since(start); d > time.Second {
+		t.Fatalf("deadline enforced after %v", d)
+	}
+}
+
+func TestServerConfigGracefulStop(t *testing.T) {
+	addr, stop := serve(t, ServerConfig{}, &Server{Source: DemoSource(3, 50*time.Millisecond)})
+	conn := connect(t, addr, ClientConfig{})
+	client := pb.NewStreamServiceClient(conn)
+
+	stream, err := client.StreamData(context.Background(), &pb.RequestMessage{RequestId: "r1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := stream.Recv(); err != nil {
+		t.Fatal(err)
+	}
+	stopped := make(chan error, 1)
+	go func() { stopped <- stop() }()
+
+	// The call in flight runs to completion while the server drains.
+	for i := 1; i < 3; i++ {
+		m, err := stream.Recv()
+		if err != nil || m.GetTick().GetIndex() != int64(i) {
+			t.Fatalf("event %d during drain: %v, %v", i, m, err)
+		}
+	}
+	if _, err := stream.Recv(); err != io.EOF {
+		t.Fatalf("end of stream: %v", err)
+	}
+	if err := <-stopped; err != nil {
+		t.Fatal(err)
+	}
+	if s, err := client.StreamData(context.Background(), &pb.RequestMessage{RequestId: "r2"}); err == nil {
+		if _, err := s.Recv(); err == nil {
+			t.Fatal("server still accepting calls")
+		}
+	}
+}
+
+func TestPrincipal(t *testing.T) {
+	a := &authenticator{tokens: map[string]string{"s3cret": "ops"}, exempt: func(string) bool { return false }}
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs("authorization", "Bearer s3cret"))
+	ctx, err := a.authenticate(ctx, "/stream.StreamService/StreamData")
+	if err != nil || Principal(ctx) != "ops" {
+		t.Fatalf("principal %q, %v", Principal(ctx), err)
+	}
+}
+
+func TestServerConfigRegistersServices(t *testing.T) {
+	s, err := ServerConfig{}.Build(&Server{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	info := s.GetServiceInfo()
+	for _, name := range []string{"stream.StreamService", "grpc.health.v1.Health", "grpc.reflection.v1.ServerReflection"} {
+		if _, ok := info[name]; !ok {
+			t.Errorf("%s not registered", name)
+		}
+	}
+}
+
+func TestTokensNeedTLS(t *testing.T) {
+	if _, err := (ServerConfig{Tokens: map[string]string{"s3cret": "ops"}}).Build(&Server{}); err == nil {
+		t.Fatal("Build accepted tokens without TLS")
+	}
+	if _, err := (ClientConfig{Token: "s3cret"}).DialOptions(); err == nil {
+		t.Fatal("DialOptions accepted a token without TLS")
+	}
+	if !(bearer{token: "s3cret"}).RequireTransportSecurity() {
+		t.Fatal("bearer allows plaintext")
+	}
+}
@@ -0,0 +1,263 @@
+package streamsvc
+
+import (
+	"context"
+	"io"
+	"sync"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	pb "github.com/shailendra-s-123/golang_random_5/task_390299/streampb"
+)
+
+const (
+	defaultMaxTopics  = 64
+	defaultTopicQueue = 8
+)
+
+// topic is one subscription of a Subscribe stream. Its pump moves events
+// from the request's session into pending, which the scheduler drains.
+type topic struct {
+	name   string
+	cancel context.CancelFunc
+	// pending holds at most TopicQueue events; a pump with a full queue
+	// waits, which pauses the topic's source.
+	pending []*pb.TopicEvent
+	ready   bool // in mux.ready
+	ended   bool // the TopicEnd is queued; nothing follows it
+}
+
+// mux schedules the topics of one Subscribe stream. Topics with pending
+// events take turns, one event each, so a busy topic cannot starve the
+// others and a slow one holds up nobody.
+type mux struct {
+	srv   *Server
+	ctx   context.Context
+	queue int
+
+	mu       sync.Mutex
+	topics   map[string]*topic
+	ready    []*topic // topics with pending events, in turn order
+	closed   bool     // the client half-closed
+	changed  chan struct{}
+	pumps    sync.WaitGroup
+	maxTopic int
+}
+
+// broadcast wakes the sender and pumps. Callers hold m.mu.
+func (m *mux) broadcast() {
+	close(m.changed)
+	m.changed = make(chan struct{})
+}
+
+// wait releases m.mu until the mux changes or its context is done.
+func (m *mux) wait() error {
+	changed := m.changed
+	m.mu.Unlock()
+	defer m.mu.Lock()
+	select {
+	case <-changed:
+		return nil
+	case <-m.ctx.Done():
+		return m.ctx.Err()
+	}
+}
+
+// push queues ev on t, waiting for room unless force is set. It reports
+// false once the topic has ended.
+func (m *mux) push(t *topic, ev *pb.TopicEvent, force bool) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for !force && len(t.pending) >= m.queue && !t.ended {
+		if m.wait() != nil {
+			return false
+		}
+	}
+	if t.ended {
+		return false
+	}
+	m.enqueue(t, ev)
+	return true
+}
+
+// enqueue adds ev to t's pending events. A TopicEnd ends the topic, which
+// leaves m.topics so the name can be subscribed again. Callers hold m.mu.
+func (m *mux) enqueue(t *topic, ev *pb.TopicEvent) {
+	t.pending = append(t.pending, ev)
+	if ev.GetEnd() != nil {
+		t.ended = true
+		if m.topics[t.name] == t {
+			delete(m.topics, t.name)
+		}
+	}
+	if !t.ready {
+		t.ready = true
+		m.ready = append(m.ready, t)
+	}
+	m.broadcast()
+}
+
+func end(name string, err error) *pb.TopicEvent {
+	st := status.Convert(err)
+	if err == nil {
+		st = status.New(codes.OK, "")
+	}
+	return &pb.TopicEvent{Topic: name, Msg: &pb.TopicEvent_End{End: &pb.TopicEnd{Code: int32(st.Code()), Message: st.Message()}}}
+}
+
+func (m *mux) subscribe(sub *pb.TopicSubscribe) {
+	name := sub.GetTopic()
+	m.mu.Lock()
+	if _, ok := m.topics[name]; ok {
+		m.mu.Unlock()
+		return
+	}
+	t := &topic{name: name}
+	if len(m.topics) >= m.maxTopic {
+		m.enqueue(t, end(name, status.Errorf(codes.ResourceExhausted, "at most %d topics per stream", m.maxTopic)))
+		m.mu.Unlock()
+		return
+	}
+	ctx, cancel := context.WithCancel(m.ctx)
+	t.cancel = cancel
+	m.topics[name] = t
+	m.mu.Unlock()
+
+	m.pumps.Add(1)
+	go func() {
+		defer m.pumps.Done()
+		defer cancel()
+		sess := m.srv.attach(&pb.RequestMessage{RequestId: name})
+		defer m.srv.detach(sess)
+		seq, fresh := sub.GetResumeFrom(), sub.GetResumeFrom() == 0
+		for {
+			ev, err := sess.get(ctx, seq+1, fresh)
+			if err != nil {
+				if err == io.EOF {
+					err = nil
+				}
+				if ctx.Err() == nil {
+					m.push(t, end(name, err), true)
+				}
+				return
+			}
+			if !m.push(t, &pb.TopicEvent{Topic: name, Msg: &pb.TopicEvent_Event{Event: ev}}, false) {
+				return
+			}
+			seq, fresh = ev.GetSeq(), false
+		}
+	}()
+}
+
+func (m *mux) unsubscribe(name string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	t, ok := m.topics[name]
+	if !ok {
+		return
+	}
+	t.cancel()
+	// Events not sent yet are dropped; the TopicEnd takes their turn.
+	t.pending = t.pending[:0]
+	m.enqueue(t, end(name, nil))
+}
+
+// next returns the next event to send, taking turns between topics. It
+// returns io.EOF once the client has half-closed and every topic ended.
+func (m *mux) next() (*pb.TopicEvent, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for len(m.ready) == 0 {
+		if m.closed && len(m.topics) == 0 {
+			return nil, io.EOF
+		}
+		if err := m.wait(); err != nil {
+			return nil, err
+		}
+	}
+	t := m.ready[0]
+	m.ready = m.ready[1:]
+	ev := t.pending[0]
+	t.pending = t.pending[1:]
+	if len(t.pending) > 0 {
+		m.ready = append(m.ready, t) // back of the line
+	} else {
+		t.ready = false
+	}
+	m.broadcast()
+	return ev, nil
+}
+
+// Subscribe multiplexes the topics the client subscribes to over one
+// stream.
+func (s *Server) Subscribe(stream pb.StreamService_SubscribeServer) error {
+	ctx, cancel := context.WithCancel(stream.Context())
+	m := &mux{
+		srv:      s,
+		ctx:      ctx,
+		queue:    s.TopicQueue,
+		topics:   make(map[string]*topic),
+		changed:  make(chan struct{}),
+		maxTopic: s.MaxTopics,
+	}
+	if m.queue <= 0 {
+		m.queue = defaultTopicQueue
+	}
+	if m.maxTopic <= 0 {
+		m.maxTopic = defaultMaxTopics
+	}
+	defer m.pumps.Wait()
+	defer cancel()
+
+	recvErr := make(chan error, 1)
+	go func() {
+		recvErr <- m.control(stream)
+		cancel()
+	}()
+	for {
+		ev, err := m.next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			// Cancelled: by the client, or by a failed control loop.
+			select {
+			case rerr := <-recvErr:
+				if rerr != nil {
+					return rerr
+				}
+			default:
+			}
+			return err
+		}
+		if err := stream.Send(ev); err != nil {
+			return err
+		}
+	}
+}
+
+// control applies the client's messages until it half-closes.
+func (m *mux) control(stream pb.StreamService_SubscribeServer) error {
+	for {
+		c, err := stream.Recv()
+		if err == io.EOF {
+			m.mu.Lock()
+			m.closed = true
+			m.broadcast()
+			m.mu.Unlock()
+			// Keep the context alive for the topics still running.
+			<-m.ctx.Done()
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		switch msg := c.Msg.(type) {
+		case *pb.SubscriptionControl_Subscribe:
+			m.subscribe(msg.Subscribe)
+		case *pb.SubscriptionControl_Unsubscribe:
+			m.unsubscribe(msg.Unsubscribe.GetTopic())
+		}
+	}
+}
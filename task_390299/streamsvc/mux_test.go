@@ -0,0 +1,161 @@
+package streamsvc
+
+import (
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/codes"
+
+	pb "github.com/shailendra-s-123/golang_random_5/task_390299/streampb"
+)
+
+// topics is a source whose events depend on the topic: "a" and "b" emit
+// 200 events as fast as they are taken once gate closes, "slow" emits
+// three 150ms apart and "endless" never stops.
+func topics(gate chan struct{}) Source {
+	return func(ctx context.Context, req *pb.RequestMessage, emit func(*pb.ResponseMessage) error) error {
+		name := req.GetRequestId()
+		n, pause := 200, time.Duration(0)
+		switch name {
+		case "a", "b":
+			<-gate
+		case "slow":
+			n, pause = 3, 150*time.Millisecond
+		case "endless":
+			n, pause = 1<<30, time.Millisecond
+		}
+		for i := 0; i < n; i++ {
+			if i > 0 && pause > 0 {
+				select {
+				case <-time.After(pause):
+				case <-ctx.Done():
+					return ctx.Err()
+				}
+			}
+			if err := emit(NewTick(name, int64(i))); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+func subscribe(topic string, resumeFrom uint64) *pb.SubscriptionControl {
+	return &pb.SubscriptionControl{Msg: &pb.SubscriptionControl_Subscribe{Subscribe: &pb.TopicSubscribe{Topic: topic, ResumeFrom: resumeFrom}}}
+}
+
+func TestSubscribeFairShare(t *testing.T) {
+	gate := make(chan struct{})
+	client := dial(t, &Server{Source: topics(gate)})
+	stream, err := client.Subscribe(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, topic := range []string{"slow", "a", "b"} {
+		stream.Send(subscribe(topic, 0))
+	}
+	time.Sleep(20 * time.Millisecond)
+	close(gate)
+
+	var order []string
+	next := map[string]int64{}
+	ends := map[string]int{}
+	for len(ends) < 3 {
+		ev, err := stream.Recv()
+		if err != nil {
+			t.Fatal(err)
+		}
+		topic := ev.GetTopic()
+		if end := ev.GetEnd(); end != nil {
+			if codes.Code(end.GetCode()) != codes.OK {
+				t.Fatalf("%s ended with %v", topic, end)
+			}
+			ends[topic] = len(order)
+			continue
+		}
+		if i := ev.GetEvent().GetTick().GetIndex(); i != next[topic] {
+			t.Fatalf("%s: event %d, want %d", topic, i, next[topic])
+		}
+		next[topic]++
+		order = append(order, topic)
+	}
+	if next["a"] != 200 || next["b"] != 200 || next["slow"] != 3 {
+		t.Fatalf("events per topic %v", next)
+	}
+
+	// a and b take turns rather than one draining before the other.
+	count := map[string]int{}
+	for _, topic := range order {
+		if count["a"]+count["b"] == 40 {
+			break
+		}
+		count[topic]++
+	}
+	if count["a"] < 10 || count["b"] < 10 {
+		t.Fatalf("first 40 events split %v", count)
+	}
+	// The slow topic holds up neither.
+	if ends["slow"] < ends["a"] || ends["slow"] < ends["b"] {
+		t.Fatalf("topics ended at %v", ends)
+	}
+
+	stream.CloseSend()
+	if _, err := stream.Recv(); err != io.EOF {
+		t.Fatalf("end of stream: %v", err)
+	}
+}
+
+func TestUnsubscribeAndResume(t *testing.T) {
+	client := dial(t, &Server{Source: topics(nil), MaxTopics: 1})
+	stream, err := client.Subscribe(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	stream.Send(subscribe("endless", 0))
+	stream.Send(subscribe("slow", 0))
+
+	// The second topic is over the limit.
+	var last uint64
+	for gotEnd := false; !gotEnd || last < 3; {
+		ev, err := stream.Recv()
+		if err != nil {
+			t.Fatal(err)
+		}
+		switch {
+		case ev.GetTopic() == "slow":
+			if codes.Code(ev.GetEnd().GetCode()) != codes.ResourceExhausted {
+				t.Fatalf("slow: %v", ev)
+			}
+			gotEnd = true
+		case ev.GetEvent() != nil:
+			last = ev.GetEvent().GetSeq()
+		}
+	}
+
+	stream.Send(&pb.SubscriptionControl{Msg: &pb.SubscriptionControl_Unsubscribe{Unsubscribe: &pb.TopicUnsubscribe{Topic: "endless"}}})
+	for {
+		ev, err := stream.Recv()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ev.GetEnd() != nil {
+			if codes.Code(ev.GetEnd().GetCode()) != codes.OK {
+				t.Fatalf("unsubscribe ended with %v", ev)
+			}
+			break
+		}
+		last = ev.GetEvent().GetSeq()
+	}
+
+	// Subscribing again resumes from the replay buffer.
+	stream.Send(subscribe("endless", last))
+	ev, err := stream.Recv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := ev.GetEvent().GetSeq(); got != last+1 {
+		t.Fatalf("resumed at %d, want %d", got, last+1)
+	}
+}
@@ -0,0 +1,162 @@
+// Package streamsvc implements StreamService: the server-streaming
+// StreamData RPC and the flow-controlled, bidirectional Exchange RPC.
+package streamsvc
+
+import (
+	"context"
+	"io"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	pb "github.com/shailendra-s-123/golang_random_5/task_390299/streampb"
+)
+
+// Source produces the events of a request, calling emit for each. emit
+// blocks until a client is ready for the event and fails once the request
+// is abandoned; the source should return that error. emit numbers the
+// events by setting their Seq.
+type Source func(ctx context.Context, req *pb.RequestMessage, emit func(*pb.ResponseMessage) error) error
+
+// DemoSource emits n ticks one interval apart, like the original example
+// servers.
+func DemoSource(n int, interval time.Duration) Source {
+	return func(ctx context.Context, req *pb.RequestMessage, emit func(*pb.ResponseMessage) error) error {
+		for i := 0; i < n; i++ {
+			if i > 0 {
+				select {
+				case <-time.After(interval):
+				case <-ctx.Done():
+					return ctx.Err()
+				}
+			}
+			if err := emit(NewTick(req.GetRequestId(), int64(i))); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+// Server implements pb.StreamServiceServer.
+type Server struct {
+	pb.UnimplementedStreamServiceServer
+
+	// Source produces events. Defaults to DemoSource(5, time.Second).
+	Source Source
+	// OnAck, if set, is called with every Ack an Exchange client sends.
+	OnAck func(requestID string, received uint64)
+	// ReplaySize is the number of recent events kept per request for
+	// clients that resume. Defaults to 256.
+	ReplaySize int
+	// SessionTTL is how long a request's source and replay buffer outlive
+	// its last stream. Defaults to a minute.
+	SessionTTL time.Duration
+	// MaxTopics caps the topics of one Subscribe stream. Defaults to 64.
+	MaxTopics int
+	// TopicQueue is the number of events a topic may have waiting for its
+	// turn on a Subscribe stream before its source is paused. Defaults
+	// to 8.
+	TopicQueue int
+	// DisableLegacyJSON stops filling in ResponseMessage.Data for clients
+	// that only read the JSON. Set it once none are left.
+	DisableLegacyJSON bool
+
+	mu       sync.Mutex
+	sessions map[string]*session
+}
+
+func (s *Server) source() Source {
+	if s.Source == nil {
+		return DemoSource(5, time.Second)
+	}
+	return s.Source
+}
+
+// StreamData sends the events of the request after req.ResumeFrom.
+func (s *Server) StreamData(req *pb.RequestMessage, stream pb.StreamService_StreamDataServer) error {
+	sess := s.attach(req)
+	defer s.detach(sess)
+	seq, fresh := req.GetResumeFrom(), req.GetResumeFrom() == 0
+	for {
+		m, err := sess.get(stream.Context(), seq+1, fresh)
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if err := stream.Send(m); err != nil {
+			return err
+		}
+		seq, fresh = m.GetSeq(), false
+	}
+}
+
+// Exchange streams the events of the request in the client's Start
+// message, sending only while the client has credit.
+func (s *Server) Exchange(stream pb.StreamService_ExchangeServer) error {
+	first, err := stream.Recv()
+	if err != nil {
+		return err
+	}
+	start := first.GetStart()
+	if start == nil || start.GetRequest() == nil {
+		return status.Error(codes.InvalidArgument, "first message must be a Start with a request")
+	}
+	ctx, cancel := context.WithCancel(stream.Context())
+	defer cancel()
+
+	f := newFlow(start.GetCredit(), start.GetFilter())
+	requestID := start.GetRequest().GetRequestId()
+	recvErr := make(chan error, 1)
+	go func() {
+		err := f.control(stream, func(received uint64) {
+			if s.OnAck != nil {
+				s.OnAck(requestID, received)
+			}
+		})
+		if err != nil {
+			cancel()
+		}
+		recvErr <- err
+	}()
+
+	sess := s.attach(start.GetRequest())
+	defer s.detach(sess)
+	seq := start.GetRequest().GetResumeFrom()
+	fresh := seq == 0
+	for {
+		// Take the credit first so a paused stream does not pull events
+		// from the source; filtered events are skipped under it.
+		if err = f.acquire(ctx); err != nil {
+			break
+		}
+		var m *pb.ResponseMessage
+		for {
+			if m, err = sess.get(ctx, seq+1, fresh); err != nil {
+				break
+			}
+			seq, fresh = m.GetSeq(), false
+			if f.wants(m.GetKind()) {
+				break
+			}
+		}
+		if err != nil {
+			break
+		}
+		if err = stream.Send(m); err != nil {
+			break
+		}
+	}
+	if err == io.EOF {
+		return nil
+	}
+	if err == errDrained || (ctx.Err() != nil && stream.Context().Err() == nil) {
+		// The control loop has ended; its error, if any, is the cause.
+		return <-recvErr
+	}
+	return err
+}
@@ -0,0 +1,159 @@
+package streamsvc
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+
+	pb "github.com/shailendra-s-123/golang_random_5/task_390299/streampb"
+)
+
+func dial(t *testing.T, srv *Server, opts ...grpc.ServerOption) pb.StreamServiceClient {
+	t.Helper()
+	lis := bufconn.Listen(1 << 20)
+	s := grpc.NewServer(opts...)
+	pb.RegisterStreamServiceServer(s, srv)
+	go s.Serve(lis)
+	t.Cleanup(s.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return pb.NewStreamServiceClient(conn)
+}
+
+// counting emits n events without pause, alternating kinds "even" and
+// "odd", and records how many it has handed to emit.
+type counting struct {
+	n       int
+	mu      sync.Mutex
+	emitted int
+}
+
+func (c *counting) source(_ context.Context, _ *pb.RequestMessage, emit func(*pb.ResponseMessage) error) error {
+	for i := 0; i < c.n; i++ {
+		kind := "even"
+		if i%2 == 1 {
+			kind = "odd"
+		}
+		if err := emit(&pb.ResponseMessage{Data: fmt.Sprintf(`{"event":%d}`, i), Kind: kind}); err != nil {
+			return err
+		}
+		c.mu.Lock()
+		c.emitted++
+		c.mu.Unlock()
+	}
+	return nil
+}
+
+func (c *counting) count() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.emitted
+}
+
+func TestStreamData(t *testing.T) {
+	client := dial(t, &Server{Source: DemoSource(3, time.Millisecond)})
+	stream, err := client.StreamData(context.Background(), &pb.RequestMessage{RequestId: "r1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 3; i++ {
+		m, err := stream.Recv()
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := fmt.Sprintf(`{"event":%d,"request_id":"r1"}`, i)
+		if m.GetData() != want || m.GetKind() != "tick" || m.GetTick().GetIndex() != int64(i) || m.GetTick().GetRequestId() != "r1" {
+			t.Fatalf("message %d = %v", i, m)
+		}
+	}
+	if _, err := stream.Recv(); err == nil {
+		t.Fatal("stream did not end")
+	}
+}
+
+func TestExchangeCreditAndFilter(t *testing.T) {
+	src := &counting{n: 10}
+	acks := make(chan uint64, 10)
+	client := dial(t, &Server{
+		Source: src.source,
+		OnAck:  func(_ string, received uint64) { acks <- received },
+	})
+	stream, err := client.Exchange(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	stream.Send(&pb.ClientMessage{Msg: &pb.ClientMessage_Start{Start: &pb.Start{
+		Request: &pb.RequestMessage{RequestId: "r1"},
+		Credit:  3,
+	}}})
+
+	recv := func() *pb.ResponseMessage {
+		t.Helper()
+		m, err := stream.Recv()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return m
+	}
+	for i := 0; i < 3; i++ {
+		if m := recv(); m.GetData() != fmt.Sprintf(`{"event":%d}`, i) {
+			t.Fatalf("message %d = %v", i, m)
+		}
+	}
+
+	// Out of credit, the server holds the fourth event back.
+	time.Sleep(50 * time.Millisecond)
+	if n := src.count(); n != 3 {
+		t.Fatalf("source emitted %d events without credit", n)
+	}
+
+	stream.Send(&pb.ClientMessage{Msg: &pb.ClientMessage_Ack{Ack: &pb.Ack{Received: 3}}})
+	if got := <-acks; got != 3 {
+		t.Fatalf("ack %d", got)
+	}
+
+	// Only odd events from here on; the filter applies before credit is
+	// spent, so two credits buy events 3 and 5.
+	stream.Send(&pb.ClientMessage{Msg: &pb.ClientMessage_Filter{Filter: &pb.Filter{Kinds: []string{"odd"}}}})
+	time.Sleep(20 * time.Millisecond)
+	stream.Send(&pb.ClientMessage{Msg: &pb.ClientMessage_Credit{Credit: &pb.Credit{Grant: 2}}})
+	for _, want := range []int{3, 5} {
+		if m := recv(); m.GetData() != fmt.Sprintf(`{"event":%d}`, want) || m.GetKind() != "odd" {
+			t.Fatalf("got %v, want event %d", m, want)
+		}
+	}
+
+	// Closing the send side with no credit left ends the exchange cleanly.
+	stream.CloseSend()
+	if _, err := stream.Recv(); err != io.EOF {
+		t.Fatalf("end of exchange: %v", err)
+	}
+}
+
+func TestExchangeRequiresStart(t *testing.T) {
+	client := dial(t, &Server{Source: (&counting{n: 1}).source})
+	stream, err := client.Exchange(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	stream.Send(&pb.ClientMessage{Msg: &pb.ClientMessage_Credit{Credit: &pb.Credit{Grant: 1}}})
+	if _, err := stream.Recv(); status.Code(err) != codes.InvalidArgument {
+		t.Fatalf("got %v, want InvalidArgument", err)
+	}
+}
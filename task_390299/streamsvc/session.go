@@ -0,0 +1,173 @@
+package streamsvc
+
+import (
+	"context"
+	"io"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	pb "github.com/shailendra-s-123/golang_random_5/task_390299/streampb"
+)
+
+const (
+	defaultReplaySize = 256
+	defaultSessionTTL = time.Minute
+)
+
+// session runs the source of one request and keeps its latest events, so
+// a client that reconnects can resume where it left off. The source only
+// produces an event once some stream asks for it, so it is paced by the
+// fastest reader and a paused stream pauses the source.
+type session struct {
+	cancel context.CancelFunc
+
+	mu       sync.Mutex
+	ring     []*pb.ResponseMessage // event seq is at ring[seq%len(ring)]
+	produced uint64                // seq of the latest event
+	wanted   uint64                // highest seq a stream has asked for
+	done     bool
+	err      error
+	streams  int       // streams reading the session
+	idle     time.Time // when streams last dropped to zero
+	changed  chan struct{}
+}
+
+func newSession(size int) *session {
+	return &session{ring: make([]*pb.ResponseMessage, size), changed: make(chan struct{})}
+}
+
+// broadcast wakes everything waiting on the session. Callers hold s.mu.
+func (s *session) broadcast() {
+	close(s.changed)
+	s.changed = make(chan struct{})
+}
+
+// wait releases s.mu until the session changes or ctx is done, and
+// reacquires it.
+func (s *session) wait(ctx context.Context) error {
+	changed := s.changed
+	s.mu.Unlock()
+	defer s.mu.Lock()
+	select {
+	case <-changed:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// run feeds the session from src until the source returns.
+func (s *session) run(ctx context.Context, src Source, req *pb.RequestMessage) {
+	err := src(ctx, req, func(m *pb.ResponseMessage) error {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		for s.wanted <= s.produced {
+			if err := s.wait(ctx); err != nil {
+				return err
+			}
+		}
+		s.produced++
+		m.Seq = s.produced
+		s.ring[s.produced%uint64(len(s.ring))] = m
+		s.broadcast()
+		return nil
+	})
+	s.mu.Lock()
+	s.done, s.err = true, err
+	s.broadcast()
+	s.mu.Unlock()
+}
+
+// get returns the event numbered seq, asking the source for it if needed.
+// It returns io.EOF after the last event, the source's error if it
+// failed, and OutOfRange if seq has already left the replay buffer. With
+// fresh set, for the first read of a stream that has no resume point, it
+// returns the oldest buffered event instead, so callers must carry on
+// from the Seq of what they get.
+func (s *session) get(ctx context.Context, seq uint64, fresh bool) (*pb.ResponseMessage, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if seq > s.wanted {
+		s.wanted = seq
+		s.broadcast()
+	}
+	for s.produced < seq && !s.done {
+		if err := s.wait(ctx); err != nil {
+			return nil, err
+		}
+	}
+	if size := uint64(len(s.ring)); fresh && s.produced >= size && seq <= s.produced-size {
+		seq = s.produced - size + 1
+	}
+	switch {
+	case s.produced >= seq && s.produced-seq < uint64(len(s.ring)):
+		return s.ring[seq%uint64(len(s.ring))], nil
+	case s.produced >= seq:
+		return nil, status.Errorf(codes.OutOfRange, "event %d is no longer buffered; the oldest is %d", seq, s.produced-uint64(len(s.ring))+1)
+	case s.err != nil:
+		return nil, s.err
+	default:
+		return nil, io.EOF
+	}
+}
+
+// attach returns the session of req, starting its source if there is none
+// yet. Callers must detach when done with it.
+func (srv *Server) attach(req *pb.RequestMessage) *session {
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	srv.evict(time.Now())
+	if srv.sessions == nil {
+		srv.sessions = make(map[string]*session)
+	}
+	sess, ok := srv.sessions[req.GetRequestId()]
+	if !ok {
+		size := srv.ReplaySize
+		if size <= 0 {
+			size = defaultReplaySize
+		}
+		sess = newSession(size)
+		ctx, cancel := context.WithCancel(context.Background())
+		sess.cancel = cancel
+		srv.sessions[req.GetRequestId()] = sess
+		src := srv.source()
+		if !srv.DisableLegacyJSON {
+			src = withLegacyJSON(src)
+		}
+		go sess.run(ctx, src, &pb.RequestMessage{RequestId: req.GetRequestId()})
+	}
+	sess.mu.Lock()
+	sess.streams++
+	sess.mu.Unlock()
+	return sess
+}
+
+func (srv *Server) detach(sess *session) {
+	sess.mu.Lock()
+	defer sess.mu.Unlock()
+	sess.streams--
+	if sess.streams == 0 {
+		sess.idle = time.Now()
+	}
+}
+
+// evict drops sessions nobody has read for SessionTTL, stopping their
+// sources. Callers hold srv.mu.
+func (srv *Server) evict(now time.Time) {
+	ttl := srv.SessionTTL
+	if ttl <= 0 {
+		ttl = defaultSessionTTL
+	}
+	for id, sess := range srv.sessions {
+		sess.mu.Lock()
+		expired := sess.streams == 0 && now.Sub(sess.idle) > ttl
+		sess.mu.Unlock()
+		if expired {
+			sess.cancel()
+			delete(srv.sessions, id)
+		}
+	}
+}
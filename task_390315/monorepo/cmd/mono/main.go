@@ -0,0 +1,243 @@
+// Command mono manages the modules of the monorepo.
+//
+// Usage:
+//
+//	mono sync [-root dir] [-release]
+//	mono check [-root dir]
+//	mono affected [-root dir] [-base rev] [-run build,test] [-p n] [file...]
+//	mono release [-root dir] [-dry-run]
+//
+// sync writes go.work so that it uses every module in the tree and points
+// cross-module requirements at the local modules with replace
+// directives. With -release it instead pins them to the pseudo-version
+// (or release tag) of the required module's last commit and drops the
+// local replaces. Both refuse to run while a module imports another
+// module's internal packages, which check reports on its own.
+//
+// affected lists the modules that contain one of the changed files, or
+// depend on one that does, dependencies first. The files are the
+// arguments, the files that differ from -base in git, or else the lines
+// of standard input. With -run it runs go build ./... and/or go test ./...
+// in each of those modules in that order, up to -p modules at a time,
+// skipping the dependents of a module that failed.
+//
+// release tags a new version of every module with commits since its last
+// release tag, as their conventional commit messages call for: feat makes
+// a minor release, fix and perf a patch release, and a breaking change a
+// major one. Modules requiring a released module get at least a patch
+// release, with their go.mod updated to the new version in a release
+// commit. With -dry-run it only prints the plan.
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"runtime"
+	"strings"
+
+	"github.com/yourusername/monorepo/internal/mono"
+)
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+	}
+	var err error
+	switch cmd, args := os.Args[1], os.Args[2:]; cmd {
+	case "sync":
+		err = runSync(args)
+	case "check":
+		err = runCheck(args)
+	case "affected":
+		err = runAffected(args)
+	case "release":
+		err = runRelease(args)
+	default:
+		usage()
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "mono:", err)
+		os.Exit(1)
+	}
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: mono sync [-root dir] [-release]\n       mono check [-root dir]\n       mono affected [-root dir] [-base rev] [-run build,test] [-p n] [file...]\n       mono release [-root dir] [-dry-run]")
+	os.Exit(2)
+}
+
+func discover(fs *flag.FlagSet, args []string) (*mono.Repo, error) {
+	root := fs.String("root", ".", "monorepo root")
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+	return mono.Discover(*root)
+}
+
+func runCheck(args []string) error {
+	repo, err := discover(flag.NewFlagSet("check", flag.ExitOnError), args)
+	if err != nil {
+		return err
+	}
+	return check(repo)
+}
+
+func check(repo *mono.Repo) error {
+	vs, err := repo.CheckInternal()
+	if err != nil {
+		return err
+	}
+	for _, v := range vs {
+		fmt.Fprintln(os.Stderr, v)
+	}
+	if len(vs) > 0 {
+		return fmt.Errorf("%d imports of other modules' internal packages", len(vs))
+	}
+	return nil
+}
+
+func runSync(args []string) error {
+	fs := flag.NewFlagSet("sync", flag.ExitOnError)
+	release := fs.Bool("release", false, "pin cross-module requirements instead of replacing them with local paths")
+	repo, err := discover(fs, args)
+	if err != nil {
+		return err
+	}
+	if err := check(repo); err != nil {
+		return err
+	}
+
+	var changed []*mono.Module
+	if *release {
+		changed, err = repo.Pin(repo.PseudoVersion)
+	} else {
+		changed, err = repo.Develop()
+	}
+	for _, m := range changed {
+		fmt.Printf("updated %s/go.mod\n", m.Dir)
+	}
+	if err != nil {
+		return err
+	}
+	if ok, err := repo.SyncWork(); err != nil {
+		return err
+	} else if ok {
+		fmt.Println("updated go.work")
+	}
+	return nil
+}
+
+func runAffected(args []string) error {
+	fs := flag.NewFlagSet("affected", flag.ExitOnError)
+	base := fs.String("base", "", "take the changed files from git diff against this revision")
+	run := fs.String("run", "", "comma-separated go commands to run in each affected module: build, test")
+	parallel := fs.Int("p", runtime.GOMAXPROCS(0), "number of modules to run at a time")
+	repo, err := discover(fs, args)
+	if err != nil {
+		return err
+	}
+	var cmds [][]string
+	if *run != "" {
+		for _, name := range strings.Split(*run, ",") {
+			switch name {
+			case "build", "test":
+				cmds = append(cmds, []string{"go", name, "./..."})
+			default:
+				return fmt.Errorf("-run: unknown command %q", name)
+			}
+		}
+	}
+
+	files := fs.Args()
+	switch {
+	case *base != "":
+		if len(files) > 0 {
+			return errors.New("-base and file arguments are exclusive")
+		}
+		if files, err = repo.ChangedFiles(*base); err != nil {
+			return err
+		}
+	case len(files) == 0:
+		sc := bufio.NewScanner(os.Stdin)
+		for sc.Scan() {
+			if f := strings.TrimSpace(sc.Text()); f != "" {
+				files = append(files, f)
+			}
+		}
+		if err := sc.Err(); err != nil {
+			return err
+		}
+	}
+	mods, err := repo.Affected(files)
+	if err != nil {
+		return err
+	}
+	if len(cmds) == 0 {
+		for _, m := range mods {
+			fmt.Println(m.Dir)
+		}
+		return nil
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	failed := 0
+	for _, res := range repo.Run(ctx, mods, *parallel, cmds...) {
+		switch {
+		case res.Skipped:
+			fmt.Printf("skip\t%s\t(%v)\n", res.Module.Dir, res.Err)
+		case res.Err != nil:
+			failed++
+			fmt.Printf("FAIL\t%s\t%v\n", res.Module.Dir, res.Err)
+			os.Stdout.Write(res.Output)
+		default:
+			fmt.Printf("ok\t%s\t%.1fs\n", res.Module.Dir, res.Elapsed.Seconds())
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d modules failed", failed, len(mods))
+	}
+	return nil
+}
+
+func runRelease(args []string) error {
+	fs := flag.NewFlagSet("release", flag.ExitOnError)
+	dryRun := fs.Bool("dry-run", false, "print the release plan without changing anything")
+	repo, err := discover(fs, args)
+	if err != nil {
+		return err
+	}
+	plan, err := repo.PlanRelease()
+	if err != nil {
+		return err
+	}
+	if len(plan) == 0 {
+		fmt.Println("nothing to release")
+		return nil
+	}
+	for _, rel := range plan {
+		from := rel.From
+		if from == "" {
+			from = "(none)"
+		}
+		fmt.Printf("%s\t%s -> %s\t%s\n", rel.Module.Dir, from, rel.To, rel.Bump)
+		for _, reason := range rel.Reasons {
+			fmt.Printf("\t%s\n", reason)
+		}
+	}
+	if *dryRun {
+		return nil
+	}
+	if err := repo.Release(plan); err != nil {
+		return err
+	}
+	for _, rel := range plan {
+		fmt.Println("tagged", rel.Tag())
+	}
+	return nil
+}
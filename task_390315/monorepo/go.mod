@@ -1,3 +1,5 @@
 module github.com/yourusername/monorepo
 
 go 1.23.4
+
+require golang.org/x/mod v0.24.0
@@ -0,0 +1,92 @@
+package mono
+
+import (
+	"bytes"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/mod/module"
+	"golang.org/x/mod/semver"
+)
+
+// git runs git in the repo root and returns its trimmed output.
+func (r *Repo) git(args ...string) (string, error) {
+	return r.gitInput("", args...)
+}
+
+// gitInput is git with stdin as the command's standard input.
+func (r *Repo) gitInput(stdin string, args ...string) (string, error) {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = r.Root
+	cmd.Stdin = strings.NewReader(stdin)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("git %s: %v: %s", strings.Join(args, " "), err, strings.TrimSpace(stderr.String()))
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// TagPrefix is the prefix of m's release tags: "libs/lib1/" for a module
+// in libs/lib1 and "" for the root module.
+func (m *Module) TagPrefix() string {
+	if m.Dir == "." {
+		return ""
+	}
+	return m.Dir + "/"
+}
+
+// LatestTag returns the highest release version tagged for m, or "" if
+// it has none. Only tags reachable from rev count.
+func (r *Repo) LatestTag(m *Module, rev string) (string, error) {
+	out, err := r.git("tag", "--merged", rev, "--list", m.TagPrefix()+"v*")
+	if err != nil {
+		return "", err
+	}
+	best := ""
+	for _, tag := range strings.Fields(out) {
+		v := strings.TrimPrefix(tag, m.TagPrefix())
+		if semver.IsValid(v) && semver.Prerelease(v) == "" && strings.Count(v, ".") == 2 && semver.Compare(v, best) > 0 {
+			best = v
+		}
+	}
+	return best, nil
+}
+
+// PseudoVersion returns the version that pins m at the last commit that
+// touched its directory: its release tag if that commit is tagged, and a
+// pseudo-version based on the latest earlier tag otherwise.
+func (r *Repo) PseudoVersion(m *Module) (string, error) {
+	out, err := r.git("log", "-1", "--format=%H %ct", "--", m.Dir)
+	if err != nil {
+		return "", err
+	}
+	fields := strings.Fields(out)
+	if len(fields) != 2 {
+		return "", fmt.Errorf("no commits touch %s", m.Dir)
+	}
+	rev := fields[0]
+	secs, err := strconv.ParseInt(fields[1], 10, 64)
+	if err != nil {
+		return "", err
+	}
+	older, err := r.LatestTag(m, rev)
+	if err != nil {
+		return "", err
+	}
+	if older != "" {
+		tagged, err := r.git("rev-list", "-n", "1", m.TagPrefix()+older)
+		if err != nil {
+			return "", err
+		}
+		if tagged == rev {
+			return older, nil
+		}
+	}
+	_, major, _ := module.SplitPathVersion(m.Path)
+	return module.PseudoVersion(module.PathMajorPrefix(major), older, time.Unix(secs, 0), rev[:12]), nil
+}
@@ -0,0 +1,133 @@
+package mono
+
+import (
+	"path"
+	"sort"
+	"strings"
+)
+
+// CycleError reports modules that require each other in a loop.
+type CycleError struct {
+	// Chain is the loop of module paths, first and last being the same.
+	Chain []string
+}
+
+func (e *CycleError) Error() string {
+	return "require cycle: " + strings.Join(e.Chain, " requires ")
+}
+
+// Sort orders mods so that every module comes after the repo modules it
+// requires among them. Ties keep the order of mods. It fails with a
+// *CycleError if the requirements loop.
+func (r *Repo) Sort(mods []*Module) ([]*Module, error) {
+	in := make(map[*Module]bool, len(mods))
+	for _, m := range mods {
+		in[m] = true
+	}
+	const (
+		unvisited = iota
+		visiting
+		done
+	)
+	state := make(map[*Module]int, len(mods))
+	var stack []*Module
+	var out []*Module
+	var visit func(*Module) error
+	visit = func(m *Module) error {
+		switch state[m] {
+		case done:
+			return nil
+		case visiting:
+			var chain []string
+			for i := len(stack) - 1; i >= 0; i-- {
+				chain = append([]string{stack[i].Path}, chain...)
+				if stack[i] == m {
+					break
+				}
+			}
+			return &CycleError{Chain: append(chain, m.Path)}
+		}
+		state[m] = visiting
+		stack = append(stack, m)
+		for _, dep := range r.Requires(m) {
+			if !in[dep] {
+				continue
+			}
+			if err := visit(dep); err != nil {
+				return err
+			}
+		}
+		stack = stack[:len(stack)-1]
+		state[m] = done
+		out = append(out, m)
+		return nil
+	}
+	for _, m := range mods {
+		if err := visit(m); err != nil {
+			return nil, err
+		}
+	}
+	return out, nil
+}
+
+// Dependents returns the repo modules that require m directly.
+func (r *Repo) Dependents(m *Module) []*Module {
+	var out []*Module
+	for _, other := range r.Modules {
+		for _, dep := range r.Requires(other) {
+			if dep == m {
+				out = append(out, other)
+				break
+			}
+		}
+	}
+	return out
+}
+
+// Affected returns the modules that contain one of the changed files,
+// slash-separated and relative to the root, and every module that depends
+// on those, directly or not. The result is sorted dependencies first.
+func (r *Repo) Affected(files []string) ([]*Module, error) {
+	seen := make(map[*Module]bool)
+	var queue []*Module
+	for _, f := range files {
+		m := r.ModuleAt(path.Dir(path.Clean(f)))
+		if m != nil && !seen[m] {
+			seen[m] = true
+			queue = append(queue, m)
+		}
+	}
+	for len(queue) > 0 {
+		m := queue[0]
+		queue = queue[1:]
+		for _, dep := range r.Dependents(m) {
+			if !seen[dep] {
+				seen[dep] = true
+				queue = append(queue, dep)
+			}
+		}
+	}
+	// Start from r.Modules so the order does not depend on the files'.
+	var affected []*Module
+	for _, m := range r.Modules {
+		if seen[m] {
+			affected = append(affected, m)
+		}
+	}
+	return r.Sort(affected)
+}
+
+// ChangedFiles returns the files under the root that differ between base
+// and the working tree, as git diff reports them, relative to the root.
+func (r *Repo) ChangedFiles(base string) ([]string, error) {
+	out, err := r.git("diff", "--name-only", "--relative", base, "--")
+	if err != nil {
+		return nil, err
+	}
+	if out == "" {
+		return nil, nil
+	}
+	files := strings.Split(out, "\n")
+	sort.Strings(files)
+	return files, nil
+}
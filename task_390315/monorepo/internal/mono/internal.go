@@ -0,0 +1,107 @@
+package mono
+
+import (
+	"fmt"
+	"go/parser"
+	"go/token"
+	"io/fs"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Violation is an import of another module's internal package.
+type Violation struct {
+	// Pos is the import's position, or the go.mod requirement's, relative
+	// to the root.
+	Pos    string
+	Module string // the importing module
+	Import string
+	Owner  string // the module the internal package belongs to
+}
+
+func (v Violation) String() string {
+	return fmt.Sprintf("%s: %s imports %s, which is internal to %s", v.Pos, v.Module, v.Import, v.Owner)
+}
+
+// internalRoot returns the path up to the last internal element of
+// pkgPath, or "" if it has none.
+func internalRoot(pkgPath string) string {
+	switch {
+	case strings.HasSuffix(pkgPath, "/internal"):
+		return pkgPath
+	case strings.Contains(pkgPath, "/internal/"):
+		return pkgPath[:strings.LastIndex(pkgPath, "/internal/")+len("/internal")]
+	}
+	return ""
+}
+
+// CheckInternal reports imports of internal packages across module
+// boundaries. The go command allows them when the import path happens to
+// sit below the internal directory's parent, as nested modules' paths do,
+// but a module must not depend on another one's internals. Requirements
+// of modules whose own path is internal count too.
+func (r *Repo) CheckInternal() ([]Violation, error) {
+	var vs []Violation
+	for _, m := range r.Modules {
+		for _, req := range m.File.Require {
+			if internalRoot(req.Mod.Path) == "" {
+				continue
+			}
+			pos := path.Join(m.Dir, "go.mod")
+			if req.Syntax != nil {
+				pos = fmt.Sprintf("%s:%d", pos, req.Syntax.Start.Line)
+			}
+			vs = append(vs, Violation{Pos: pos, Module: m.Path, Import: req.Mod.Path, Owner: req.Mod.Path})
+		}
+	}
+
+	fset := token.NewFileSet()
+	err := filepath.WalkDir(r.Root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		name := d.Name()
+		if d.IsDir() {
+			if p != r.Root && (strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_") || name == "vendor" || name == "testdata") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !strings.HasSuffix(name, ".go") {
+			return nil
+		}
+		rel, err := filepath.Rel(r.Root, p)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		m := r.ModuleAt(path.Dir(rel))
+		if m == nil {
+			return nil
+		}
+		f, err := parser.ParseFile(fset, p, nil, parser.ImportsOnly)
+		if err != nil {
+			return nil // not Go source we can check; the build will say so
+		}
+		for _, imp := range f.Imports {
+			pkg, _ := strconv.Unquote(imp.Path.Value)
+			if internalRoot(pkg) == "" {
+				continue
+			}
+			owner := r.ModuleOf(pkg)
+			if owner == nil || owner == m {
+				continue
+			}
+			vs = append(vs, Violation{
+				Pos:    fmt.Sprintf("%s:%d", rel, fset.Position(imp.Pos()).Line),
+				Module: m.Path,
+				Import: pkg,
+				Owner:  owner.Path,
+			})
+		}
+		return nil
+	})
+	return vs, err
+}
@@ -0,0 +1,181 @@
+// Package mono discovers the Go modules of a monorepo and keeps their
+// go.work, go.mod and release state consistent.
+package mono
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"golang.org/x/mod/modfile"
+	"golang.org/x/mod/semver"
+)
+
+// Module is a go.mod found in the tree.
+type Module struct {
+	// Path is the module path.
+	Path string
+	// Dir is the module's directory relative to the root, slash-separated;
+	// "." for the root module.
+	Dir string
+	// File is the parsed go.mod.
+	File *modfile.File
+	// Placeholders are the requirements whose version in go.mod was not
+	// a valid version, such as "v0.0.0-<version>", by module path. File
+	// has ZeroVersion in their place.
+	Placeholders map[string]string
+}
+
+// ZeroVersion is the version the go command uses for requirements that
+// are satisfied by a replace directive.
+const ZeroVersion = "v0.0.0-00010101000000-000000000000"
+
+// Repo is the set of modules under a root directory.
+type Repo struct {
+	Root    string
+	Modules []*Module // sorted by Dir
+	byPath  map[string]*Module
+}
+
+// Discover finds every go.mod under root. Hidden directories, vendor and
+// testdata are skipped.
+func Discover(root string) (*Repo, error) {
+	r := &Repo{Root: root, byPath: make(map[string]*Module)}
+	err := filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			name := d.Name()
+			if p != root && (strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_") || name == "vendor" || name == "testdata") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if d.Name() != "go.mod" {
+			return nil
+		}
+		data, err := os.ReadFile(p)
+		if err != nil {
+			return err
+		}
+		placeholders := make(map[string]string)
+		f, err := modfile.Parse(p, data, func(modPath, vers string) (string, error) {
+			if semver.IsValid(vers) {
+				return vers, nil
+			}
+			placeholders[modPath] = vers
+			return ZeroVersion, nil
+		})
+		if err != nil {
+			return err
+		}
+		if f.Module == nil {
+			return fmt.Errorf("%s: no module directive", p)
+		}
+		rel, err := filepath.Rel(root, filepath.Dir(p))
+		if err != nil {
+			return err
+		}
+		m := &Module{Path: f.Module.Mod.Path, Dir: filepath.ToSlash(rel), File: f, Placeholders: placeholders}
+		if other, ok := r.byPath[m.Path]; ok {
+			return fmt.Errorf("module %s is declared in both %s and %s", m.Path, other.Dir, m.Dir)
+		}
+		r.byPath[m.Path] = m
+		r.Modules = append(r.Modules, m)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(r.Modules, func(i, j int) bool { return r.Modules[i].Dir < r.Modules[j].Dir })
+	return r, nil
+}
+
+// Module returns the module with the given path, or nil.
+func (r *Repo) Module(modPath string) *Module {
+	return r.byPath[modPath]
+}
+
+// ModuleOf returns the module a package import path belongs to: the one
+// with the longest path that is a prefix of it. It returns nil for
+// packages outside the repo.
+func (r *Repo) ModuleOf(pkgPath string) *Module {
+	for p := pkgPath; ; p = path.Dir(p) {
+		if m := r.byPath[p]; m != nil {
+			return m
+		}
+		if !strings.Contains(p, "/") {
+			return nil
+		}
+	}
+}
+
+// ModuleAt returns the innermost module containing dir, a slash-separated
+// path relative to the root.
+func (r *Repo) ModuleAt(dir string) *Module {
+	var best *Module
+	depth := -1
+	for _, m := range r.Modules {
+		d := 0
+		if m.Dir != "." {
+			if dir != m.Dir && !strings.HasPrefix(dir, m.Dir+"/") {
+				continue
+			}
+			d = strings.Count(m.Dir, "/") + 1
+		}
+		if d > depth {
+			best, depth = m, d
+		}
+	}
+	return best
+}
+
+// Requires returns the modules of the repo that m requires directly.
+func (r *Repo) Requires(m *Module) []*Module {
+	var deps []*Module
+	for _, req := range m.File.Require {
+		if dep := r.byPath[req.Mod.Path]; dep != nil && dep != m {
+			deps = append(deps, dep)
+		}
+	}
+	return deps
+}
+
+// relDir returns the path from module from to module to, in the form
+// replace directives need: always starting with ./ or ../.
+func relDir(from, to *Module) string {
+	rel, _ := filepath.Rel(filepath.FromSlash(from.Dir), filepath.FromSlash(to.Dir))
+	rel = filepath.ToSlash(rel)
+	if !strings.HasPrefix(rel, "../") && rel != ".." {
+		rel = "./" + rel
+	}
+	return rel
+}
+
+// update applies edit to m's go.mod and writes it back if that changed
+// it. It reports whether it did; formatting alone does not count.
+func (r *Repo) update(m *Module, edit func(*modfile.File) error) (bool, error) {
+	m.File.Cleanup()
+	before, err := m.File.Format()
+	if err != nil {
+		return false, err
+	}
+	if err := edit(m.File); err != nil {
+		return false, err
+	}
+	m.File.Cleanup()
+	after, err := m.File.Format()
+	if err != nil {
+		return false, err
+	}
+	if string(before) == string(after) && len(m.Placeholders) == 0 {
+		return false, nil
+	}
+	m.Placeholders = nil
+	return true, os.WriteFile(filepath.Join(r.Root, filepath.FromSlash(m.Dir), "go.mod"), after, 0o644)
+}
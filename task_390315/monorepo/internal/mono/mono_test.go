@@ -0,0 +1,464 @@
+package mono
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeTree creates files under a temporary root.
+func writeTree(t *testing.T, files map[string]string) string {
+	t.Helper()
+	root := t.TempDir()
+	for name, body := range files {
+		p := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(body), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return root
+}
+
+func read(t *testing.T, root, name string) string {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join(root, filepath.FromSlash(name)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+// sample is a monorepo shaped like ours: service1 requires lib2 with the
+// placeholder version of t1a.go, lib2 requires lib1, and the CLI requires
+// a module at an internal path as t1b.go does.
+var sample = map[string]string{
+	"go.mod":                    "module github.com/yourusername/monorepo\n\ngo 1.23.4\n",
+	"libs/lib1/go.mod":          "module github.com/yourusername/monorepo/libs/lib1\n\ngo 1.21\n",
+	"libs/lib1/internal/x/x.go": "package x\n",
+	"libs/lib1/lib1.go":         "package lib1\n\nimport _ \"github.com/yourusername/monorepo/libs/lib1/internal/x\"\n",
+	"libs/lib2/go.mod":          "module github.com/yourusername/monorepo/libs/lib2\n\ngo 1.21\n\nrequire github.com/yourusername/monorepo/libs/lib1 v1.0.0\n",
+	"services/service1/go.mod":  "module github.com/yourusername/monorepo/services/service1\n\ngo 1.19\n\nrequire (\n    github.com/yourusername/monorepo/libs/lib2 v0.0.0-<version>\n)\n",
+	"services/service1/main.go": "package main\n\nimport (\n\t\"fmt\"\n\n\t_ \"github.com/yourusername/monorepo/libs/lib1/internal/x\"\n)\n\nfunc main() { fmt.Println() }\n",
+	"cli/go.mod":                "module example.com/v1/cli\n\ngo 1.18\n\nrequire (\n    example.com/v1/internal/utils v0.0.1\n)\n",
+	"testdata/go.mod":           "module ignored\n",
+}
+
+func TestDiscover(t *testing.T) {
+	repo, err := Discover(writeTree(t, sample))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var dirs []string
+	for _, m := range repo.Modules {
+		dirs = append(dirs, m.Dir)
+	}
+	if got := strings.Join(dirs, " "); got != ". cli libs/lib1 libs/lib2 services/service1" {
+		t.Fatalf("modules %s", got)
+	}
+	svc := repo.Module("github.com/yourusername/monorepo/services/service1")
+	if svc.Placeholders["github.com/yourusername/monorepo/libs/lib2"] != "v0.0.0-<version>" {
+		t.Fatalf("placeholders %v", svc.Placeholders)
+	}
+	if m := repo.ModuleOf("github.com/yourusername/monorepo/libs/lib1/internal/x"); m == nil || m.Dir != "libs/lib1" {
+		t.Fatalf("ModuleOf: %v", m)
+	}
+	if m := repo.ModuleAt("services/service1/cmd"); m != svc {
+		t.Fatalf("ModuleAt: %v", m)
+	}
+	if m := repo.ModuleAt("docs"); m.Dir != "." {
+		t.Fatalf("ModuleAt(docs): %v", m)
+	}
+}
+
+func TestDevelopAndSyncWork(t *testing.T) {
+	root := writeTree(t, sample)
+	os.WriteFile(filepath.Join(root, "go.work"), []byte("go 1.21\n\ntoolchain go1.23.4\n\nuse (\n\t.\n\t./gone\n\tlibs/lib1\n)\n"), 0o644)
+	repo, err := Discover(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	changed, err := repo.Develop()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(changed) != 2 {
+		t.Fatalf("changed %d modules", len(changed))
+	}
+	// service1 needs lib1 replaced too, as lib2 requires it.
+	want := `module github.com/yourusername/monorepo/services/service1
+
+go 1.19
+
+require github.com/yourusername/monorepo/libs/lib2 v0.0.0-00010101000000-000000000000
+
+replace github.com/yourusername/monorepo/libs/lib1 => ../../libs/lib1
+
+replace github.com/yourusername/monorepo/libs/lib2 => ../../libs/lib2
+`
+	if got := read(t, root, "services/service1/go.mod"); got != want {
+		t.Fatalf("service1 go.mod:\n%s", got)
+	}
+	if changed, _ := repo.Develop(); len(changed) != 0 {
+		t.Fatalf("second Develop changed %d modules", len(changed))
+	}
+
+	if ok, err := repo.SyncWork(); !ok || err != nil {
+		t.Fatalf("SyncWork: %v, %v", ok, err)
+	}
+	want = `go 1.23.4
+
+toolchain go1.23.4
+
+use (
+	.
+	./cli
+	./libs/lib1
+	./libs/lib2
+	./services/service1
+)
+`
+	if got := read(t, root, "go.work"); got != want {
+		t.Fatalf("go.work:\n%s", got)
+	}
+	if ok, _ := repo.SyncWork(); ok {
+		t.Fatal("second SyncWork changed go.work")
+	}
+}
+
+func TestPin(t *testing.T) {
+	root := writeTree(t, sample)
+	repo, _ := Discover(root)
+	if _, err := repo.Develop(); err != nil {
+		t.Fatal(err)
+	}
+	changed, err := repo.Pin(func(m *Module) (string, error) {
+		return map[string]string{"libs/lib1": "v1.2.0", "libs/lib2": "v0.3.1"}[m.Dir], nil
+	})
+	if err != nil || len(changed) != 2 {
+		t.Fatalf("Pin changed %d modules: %v", len(changed), err)
+	}
+	want := `module github.com/yourusername/monorepo/services/service1
+
+go 1.19
+
+require github.com/yourusername/monorepo/libs/lib2 v0.3.1
+`
+	if got := read(t, root, "services/service1/go.mod"); got != want {
+		t.Fatalf("service1 go.mod:\n%s", got)
+	}
+	if got := read(t, root, "libs/lib2/go.mod"); !strings.Contains(got, "libs/lib1 v1.2.0\n") || strings.Contains(got, "replace") {
+		t.Fatalf("lib2 go.mod:\n%s", got)
+	}
+}
+
+func TestPlaceholderOutsideRepo(t *testing.T) {
+	repo, _ := Discover(writeTree(t, map[string]string{
+		"go.mod": "module a\n\ngo 1.21\n\nrequire example.com/b v0.0.0-<version>\n",
+	}))
+	if _, err := repo.Develop(); err == nil || !strings.Contains(err.Error(), "example.com/b") {
+		t.Fatalf("got %v", err)
+	}
+}
+
+func TestCheckInternal(t *testing.T) {
+	repo, _ := Discover(writeTree(t, sample))
+	vs, err := repo.CheckInternal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, v := range vs {
+		got = append(got, v.String())
+	}
+	want := []string{
+		"cli/go.mod:6: example.com/v1/cli imports example.com/v1/internal/utils, which is internal to example.com/v1/internal/utils",
+		"services/service1/main.go:6: github.com/yourusername/monorepo/services/service1 imports github.com/yourusername/monorepo/libs/lib1/internal/x, which is internal to github.com/yourusername/monorepo/libs/lib1",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("violations:\n%s", strings.Join(got, "\n"))
+	}
+}
+
+// gitRepo turns root into a git repository with everything committed.
+func gitRepo(t *testing.T, root string) func(args ...string) string {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	run := func(args ...string) string {
+		t.Helper()
+		cmd := exec.Command("git", args...)
+		cmd.Dir = root
+		cmd.Env = append(os.Environ(), "GIT_CONFIG_GLOBAL=/dev/null", "GIT_CONFIG_NOSYSTEM=1")
+		out, err := cmd.CombinedOutput()
+		if err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, out)
+		}
+		return strings.TrimSpace(string(out))
+	}
+	run("init", "-q", "-b", "main")
+	// Set in the repository, so that the git commands Repo runs see it.
+	run("config", "user.name", "t")
+	run("config", "user.email", "t@example.com")
+	run("add", "-A")
+	run("commit", "-q", "-m", "initial")
+	return run
+}
+
+func TestPseudoVersion(t *testing.T) {
+	root := writeTree(t, sample)
+	git := gitRepo(t, root)
+	repo, _ := Discover(root)
+	lib1 := repo.Module("github.com/yourusername/monorepo/libs/lib1")
+
+	v, err := repo.PseudoVersion(lib1)
+	if err != nil || !strings.HasPrefix(v, "v0.0.0-") || !strings.HasSuffix(v, git("rev-parse", "--short=12", "HEAD")) {
+		t.Fatalf("untagged: %s, %v", v, err)
+	}
+	git("tag", "libs/lib1/v1.2.0")
+	if v, err := repo.PseudoVersion(lib1); v != "v1.2.0" || err != nil {
+		t.Fatalf("tagged: %s, %v", v, err)
+	}
+	os.WriteFile(filepath.Join(root, "libs/lib1/lib1.go"), []byte("package lib1\n"), 0o644)
+	git("commit", "-q", "-am", "change lib1")
+	if v, err := repo.PseudoVersion(lib1); !strings.HasPrefix(v, "v1.2.1-0.") || err != nil {
+		t.Fatalf("after tag: %s, %v", v, err)
+	}
+}
+
+func dirs(mods []*Module) string {
+	var out []string
+	for _, m := range mods {
+		out = append(out, m.Dir)
+	}
+	return strings.Join(out, " ")
+}
+
+func TestAffected(t *testing.T) {
+	repo, _ := Discover(writeTree(t, sample))
+	for _, tc := range []struct{ files, want string }{
+		{"libs/lib1/lib1.go", "libs/lib1 libs/lib2 services/service1"},
+		{"services/service1/main.go libs/lib2/go.mod", "libs/lib2 services/service1"},
+		{"docs/README.md cli/go.mod", ". cli"},
+		{"", ""},
+	} {
+		mods, err := repo.Affected(strings.Fields(tc.files))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := dirs(mods); got != tc.want {
+			t.Errorf("Affected(%s) = %q, want %q", tc.files, got, tc.want)
+		}
+	}
+}
+
+func TestCycle(t *testing.T) {
+	repo, _ := Discover(writeTree(t, map[string]string{
+		"a/go.mod": "module example.com/a\n\ngo 1.21\n\nrequire example.com/b v0.0.0\n",
+		"b/go.mod": "module example.com/b\n\ngo 1.21\n\nrequire example.com/c v0.0.0\n",
+		"c/go.mod": "module example.com/c\n\ngo 1.21\n\nrequire example.com/a v0.0.0\n",
+		"d/go.mod": "module example.com/d\n\ngo 1.21\n\nrequire example.com/a v0.0.0\n",
+	}))
+	_, err := repo.Affected([]string{"d/d.go", "a/a.go"})
+	var cycle *CycleError
+	if !errors.As(err, &cycle) {
+		t.Fatalf("got %v", err)
+	}
+	if want := "require cycle: example.com/a requires example.com/b requires example.com/c requires example.com/a"; err.Error() != want {
+		t.Fatalf("got %q", err)
+	}
+}
+
+func TestRun(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh not installed")
+	}
+	root := writeTree(t, sample)
+	repo, _ := Discover(root)
+	log := filepath.Join(root, "log")
+	mods, _ := repo.Affected([]string{"libs/lib1/lib1.go", "cli/go.mod"})
+
+	// Every module logs its directory after a pause, so a dependent that
+	// did not wait would log first.
+	step := []string{"sh", "-c", `sleep 0.1; basename "$PWD" >>"$0"`, log}
+	results := repo.Run(context.Background(), mods, 4, step)
+	for _, res := range results {
+		if res.Err != nil {
+			t.Fatalf("%s: %v\n%s", res.Module.Dir, res.Err, res.Output)
+		}
+	}
+	order := strings.Fields(read(t, root, "log"))
+	pos := make(map[string]int)
+	for i, name := range order {
+		pos[name] = i
+	}
+	if len(order) != 4 || pos["lib1"] > pos["lib2"] || pos["lib2"] > pos["service1"] {
+		t.Fatalf("ran in order %v", order)
+	}
+
+	os.WriteFile(filepath.Join(root, "libs/lib2/fail"), nil, 0o644)
+	results = repo.Run(context.Background(), mods, 2, []string{"sh", "-c", "echo checked; test ! -e fail"})
+	var got []string
+	for _, res := range results {
+		switch {
+		case res.Skipped:
+			got = append(got, "skip "+res.Module.Dir)
+		case res.Err != nil:
+			if string(res.Output) != "checked\n" {
+				t.Errorf("output %q", res.Output)
+			}
+			got = append(got, "fail "+res.Module.Dir)
+		default:
+			got = append(got, "ok "+res.Module.Dir)
+		}
+	}
+	if want := "ok cli, ok libs/lib1, fail libs/lib2, skip services/service1"; strings.Join(got, ", ") != want {
+		t.Fatalf("got %s", strings.Join(got, ", "))
+	}
+}
+
+func TestChangedFiles(t *testing.T) {
+	root := writeTree(t, sample)
+	git := gitRepo(t, root)
+	repo, _ := Discover(root)
+	os.WriteFile(filepath.Join(root, "libs/lib2/go.mod"), []byte("module github.com/yourusername/monorepo/libs/lib2\n"), 0o644)
+	git("rm", "-q", "libs/lib1/internal/x/x.go")
+	files, err := repo.ChangedFiles("HEAD")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(files, " "); got != "libs/lib1/internal/x/x.go libs/lib2/go.mod" {
+		t.Fatalf("changed %s", got)
+	}
+}
+
+func TestCommitBump(t *testing.T) {
+	for msg, want := range map[string]Bump{
+		"feat(lib1): add Greet":               Minor,
+		"fix: nil map":                        Patch,
+		"perf: fewer allocations":             Patch,
+		"refactor!: drop Old":                 Major,
+		"feat: x\n\nBREAKING CHANGE: gone":    Major,
+		"docs: readme":                        NoBump,
+		"Merge branch 'main'":                 NoBump,
+		"feature: not a conventional type":    NoBump,
+		"chore(release): libs/lib1/v1.2.0 \n": NoBump,
+	} {
+		if got := commitBump(msg); got != want {
+			t.Errorf("commitBump(%q) = %v, want %v", msg, got, want)
+		}
+	}
+}
+
+// plan formats a release plan as "dir from->to" entries.
+func plan(t *testing.T, repo *Repo) string {
+	t.Helper()
+	rels, err := repo.PlanRelease()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out []string
+	for _, rel := range rels {
+		out = append(out, rel.Module.Dir+" "+rel.From+"->"+rel.To)
+	}
+	return strings.Join(out, ", ")
+}
+
+func TestRelease(t *testing.T) {
+	root := writeTree(t, sample)
+	git := gitRepo(t, root)
+	git("tag", "libs/lib1/v1.1.0")
+	git("tag", "libs/lib2/v0.3.0")
+	os.WriteFile(filepath.Join(root, "libs/lib1/lib1.go"), []byte("package lib1\n\nfunc Greet() {}\n"), 0o644)
+	git("commit", "-q", "-am", "feat(lib1): add Greet")
+	os.WriteFile(filepath.Join(root, "cli/README"), []byte("cli\n"), 0o644)
+	git("add", "cli")
+	git("commit", "-q", "-m", "docs: cli readme")
+
+	repo, _ := Discover(root)
+	if got, want := plan(t, repo), "libs/lib1 v1.1.0->v1.2.0, libs/lib2 v0.3.0->v0.3.1, services/service1 ->v0.0.1"; got != want {
+		t.Fatalf("plan %s, want %s", got, want)
+	}
+	rels, _ := repo.PlanRelease()
+	if err := repo.Release(rels); err != nil {
+		t.Fatal(err)
+	}
+	if got := git("log", "-1", "--format=%s"); got != "chore(release): libs/lib1/v1.2.0 libs/lib2/v0.3.1 services/service1/v0.0.1" {
+		t.Fatalf("release commit %q", got)
+	}
+	if got := git("tag", "--points-at", "HEAD"); got != "libs/lib1/v1.2.0\nlibs/lib2/v0.3.1\nservices/service1/v0.0.1" {
+		t.Fatalf("tags at HEAD:\n%s", got)
+	}
+	if got := read(t, root, "libs/lib2/go.mod"); !strings.Contains(got, "libs/lib1 v1.2.0\n") {
+		t.Fatalf("lib2 go.mod:\n%s", got)
+	}
+	if got := read(t, root, "services/service1/go.mod"); !strings.Contains(got, "libs/lib2 v0.3.1\n") {
+		t.Fatalf("service1 go.mod:\n%s", got)
+	}
+	if git("status", "--porcelain") != "" {
+		t.Fatal("release left changes behind")
+	}
+
+	repo, _ = Discover(root)
+	if got := plan(t, repo); got != "" {
+		t.Fatalf("plan after release: %s", got)
+	}
+	os.WriteFile(filepath.Join(root, "libs/lib2/lib2.go"), []byte("package lib2\n"), 0o644)
+	git("add", "libs")
+	git("commit", "-q", "-m", "feat!: new lib2 API")
+	if got := plan(t, repo); got != "libs/lib2 v0.3.1->v0.4.0, services/service1 v0.0.1->v0.0.2" {
+		t.Fatalf("breaking v0 plan: %s", got)
+	}
+	os.WriteFile(filepath.Join(root, "libs/lib1/lib1.go"), []byte("package lib1\n"), 0o644)
+	git("commit", "-q", "-am", "fix!: remove Greet")
+	if _, err := repo.PlanRelease(); err == nil || !strings.Contains(err.Error(), "github.com/yourusername/monorepo/libs/lib1/v2") {
+		t.Fatalf("breaking v1 plan: %v", err)
+	}
+}
+
+func TestReleaseRollback(t *testing.T) {
+	root := writeTree(t, sample)
+	git := gitRepo(t, root)
+	git("tag", "libs/lib2/v0.3.0")
+	os.WriteFile(filepath.Join(root, "libs/lib2/lib2.go"), []byte("package lib2\n"), 0o644)
+	git("add", "libs")
+	git("commit", "-q", "-m", "fix: lib2")
+	// A tag the plan cannot see, as it is not reachable from HEAD, makes
+	// the tag transaction fail after the release commit.
+	git("checkout", "-q", "-b", "other")
+	git("commit", "-q", "--allow-empty", "-m", "other")
+	git("tag", "services/service1/v0.0.1")
+	git("checkout", "-q", "main")
+	head := git("rev-parse", "HEAD")
+	mod := read(t, root, "services/service1/go.mod")
+
+	repo, _ := Discover(root)
+	rels, _ := repo.PlanRelease()
+	if err := repo.Release(rels); err == nil {
+		t.Fatal("release succeeded")
+	}
+	if got := git("rev-parse", "HEAD"); got != head {
+		t.Fatal("HEAD moved")
+	}
+	if got := read(t, root, "services/service1/go.mod"); got != mod {
+		t.Fatalf("go.mod changed:\n%s", got)
+	}
+	if got := git("status", "--porcelain"); got != "" {
+		t.Fatalf("status:\n%s", got)
+	}
+	if got := git("tag", "--list", "libs/lib2/*"); got != "libs/lib2/v0.3.0" {
+		t.Fatalf("tags %s", got)
+	}
+
+	os.WriteFile(filepath.Join(root, "libs/lib2/lib2.go"), []byte("package lib2 // dirty\n"), 0o644)
+	if err := repo.Release(rels); err == nil || !strings.Contains(err.Error(), "uncommitted") {
+		t.Fatalf("dirty tree: %v", err)
+	}
+}
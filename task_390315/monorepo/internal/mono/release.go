@@ -0,0 +1,279 @@
+package mono
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"golang.org/x/mod/modfile"
+	"golang.org/x/mod/module"
+	"golang.org/x/mod/semver"
+)
+
+// Bump is how much a release raises a module's version.
+type Bump int
+
+const (
+	NoBump Bump = iota
+	Patch
+	Minor
+	Major
+)
+
+func (b Bump) String() string {
+	return [...]string{"none", "patch", "minor", "major"}[b]
+}
+
+// Release is the planned release of one module.
+type Release struct {
+	Module *Module
+	// From is the module's latest release, or "" if it has none.
+	From string
+	To   string
+	Bump Bump
+	// Reasons are the subjects of the commits that call for the release,
+	// and the new versions of the repo modules it requires.
+	Reasons []string
+}
+
+// Tag is the git tag of the release.
+func (rel *Release) Tag() string {
+	return rel.Module.TagPrefix() + rel.To
+}
+
+// header matches the first line of a conventional commit message.
+var header = regexp.MustCompile(`^(\w+)(?:\([^)]*\))?(!)?: `)
+
+// commitBump returns the bump a conventional commit message calls for:
+// major for a breaking change, minor for feat, patch for fix and perf,
+// and none for anything else, including messages that do not follow the
+// convention.
+func commitBump(msg string) Bump {
+	subject, body, _ := strings.Cut(msg, "\n")
+	m := header.FindStringSubmatch(subject)
+	if m == nil {
+		return NoBump
+	}
+	if m[2] == "!" {
+		return Major
+	}
+	for _, line := range strings.Split(body, "\n") {
+		if strings.HasPrefix(line, "BREAKING CHANGE:") || strings.HasPrefix(line, "BREAKING-CHANGE:") {
+			return Major
+		}
+	}
+	switch strings.ToLower(m[1]) {
+	case "feat":
+		return Minor
+	case "fix", "perf":
+		return Patch
+	}
+	return NoBump
+}
+
+// next returns the version after from for bump. Before v1 a breaking
+// change only bumps the minor version; from v1 on it needs a new module
+// path, which is left to the developer. A module's first release is
+// v0.x, or vN.0.0 for a module path ending in /vN.
+func next(m *Module, from string, bump Bump) (string, error) {
+	if from == "" {
+		_, major, _ := module.SplitPathVersion(m.Path)
+		if major != "" {
+			return strings.TrimLeft(major, "/.") + ".0.0", nil
+		}
+		from = "v0.0.0"
+	}
+	var maj, min, patch int
+	if _, err := fmt.Sscanf(semver.Canonical(from), "v%d.%d.%d", &maj, &min, &patch); err != nil {
+		return "", fmt.Errorf("%s: bad version %s", m.Dir, from)
+	}
+	if bump == Major && maj == 0 {
+		bump = Minor
+	}
+	switch bump {
+	case Major:
+		return "", fmt.Errorf("%s: breaking change after %s needs a new major version module path, %s/v%d", m.Dir, from, strings.TrimSuffix(m.Path, "/v"+fmt.Sprint(maj)), maj+1)
+	case Minor:
+		return fmt.Sprintf("v%d.%d.0", maj, min+1), nil
+	default:
+		return fmt.Sprintf("v%d.%d.%d", maj, min, patch+1), nil
+	}
+}
+
+// pathspec returns the git pathspec of m's own files: its directory
+// without the modules nested in it.
+func (r *Repo) pathspec(m *Module) []string {
+	spec := []string{m.Dir}
+	for _, other := range r.Modules {
+		if other != m && (m.Dir == "." || strings.HasPrefix(other.Dir, m.Dir+"/")) {
+			spec = append(spec, ":(exclude)"+other.Dir)
+		}
+	}
+	return spec
+}
+
+// PlanRelease works out which modules to release from the commits since
+// their latest release tags reachable from HEAD. Each module gets the
+// largest bump its conventional commits call for, and at least a patch
+// release if it requires a module that is released, since its go.mod is
+// updated to the new version. The plan is sorted dependencies first.
+func (r *Repo) PlanRelease() ([]Release, error) {
+	mods, err := r.Sort(r.Modules)
+	if err != nil {
+		return nil, err
+	}
+	released := make(map[*Module]string)
+	var plan []Release
+	for _, m := range mods {
+		from, err := r.LatestTag(m, "HEAD")
+		if err != nil {
+			return nil, err
+		}
+		rel := Release{Module: m, From: from}
+		rng := "HEAD"
+		if from != "" {
+			rng = m.TagPrefix() + from + "..HEAD"
+		}
+		out, err := r.git(append([]string{"log", "--format=%B%x1e", rng, "--"}, r.pathspec(m)...)...)
+		if err != nil {
+			return nil, err
+		}
+		for _, msg := range strings.Split(out, "\x1e") {
+			msg = strings.TrimSpace(msg)
+			if b := commitBump(msg); b != NoBump {
+				rel.Bump = max(rel.Bump, b)
+				subject, _, _ := strings.Cut(msg, "\n")
+				rel.Reasons = append(rel.Reasons, subject)
+			}
+		}
+		for _, dep := range r.Requires(m) {
+			if v, ok := released[dep]; ok {
+				rel.Bump = max(rel.Bump, Patch)
+				rel.Reasons = append(rel.Reasons, "require "+dep.Path+" "+v)
+			}
+		}
+		if rel.Bump == NoBump {
+			continue
+		}
+		if rel.To, err = next(m, from, rel.Bump); err != nil {
+			return nil, err
+		}
+		plan = append(plan, rel)
+		released[m] = rel.To
+	}
+	return plan, nil
+}
+
+// Release carries out plan as one change: it sets every requirement on a
+// released module to its new version, commits the go.mod files it
+// changed, and creates all the release tags at that commit in a single
+// ref transaction. The work tree must be clean. If any step fails, the
+// go.mod files, HEAD and tags are left as they were, though r itself
+// should be discovered again.
+func (r *Repo) Release(plan []Release) error {
+	if len(plan) == 0 {
+		return nil
+	}
+	if out, err := r.git("status", "--porcelain", "--untracked-files=no", "--", "."); err != nil {
+		return err
+	} else if out != "" {
+		return fmt.Errorf("uncommitted changes:\n%s", out)
+	}
+	head, err := r.git("rev-parse", "HEAD")
+	if err != nil {
+		return err
+	}
+	versions := make(map[*Module]string)
+	var tags []string
+	for _, rel := range plan {
+		versions[rel.Module] = rel.To
+		tags = append(tags, rel.Tag())
+	}
+
+	saved := make(map[string][]byte)
+	rollback := func(err error) error {
+		var rerrs []error
+		for name, data := range saved {
+			if werr := os.WriteFile(name, data, 0o644); werr != nil {
+				rerrs = append(rerrs, werr)
+			}
+		}
+		if _, rerr := r.git("reset", "-q", "--soft", head); rerr != nil {
+			rerrs = append(rerrs, rerr)
+		} else if _, rerr := r.git("reset", "-q", "--", "."); rerr != nil {
+			rerrs = append(rerrs, rerr)
+		}
+		if len(rerrs) > 0 {
+			return fmt.Errorf("%w; rolling back: %v", err, errors.Join(rerrs...))
+		}
+		return err
+	}
+	var files []string
+	for _, m := range r.Modules {
+		if err := r.checkPlaceholders(m); err != nil {
+			return rollback(err)
+		}
+		name := filepath.Join(r.Root, filepath.FromSlash(m.Dir), "go.mod")
+		data, err := os.ReadFile(name)
+		if err != nil {
+			return rollback(err)
+		}
+		ok, err := r.update(m, func(f *modfile.File) error {
+			for _, req := range append([]*modfile.Require(nil), f.Require...) {
+				dep := r.byPath[req.Mod.Path]
+				v, released := versions[dep]
+				if !released {
+					if _, placeholder := m.Placeholders[req.Mod.Path]; dep == nil || !placeholder {
+						continue
+					}
+					tag, err := r.LatestTag(dep, "HEAD")
+					if err != nil {
+						return err
+					}
+					if tag == "" {
+						return fmt.Errorf("%s/go.mod: require %s: not released yet", m.Dir, dep.Path)
+					}
+					v = tag
+				}
+				if err := f.AddRequire(dep.Path, v); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		if ok {
+			saved[name] = data
+			files = append(files, filepath.Join(m.Dir, "go.mod"))
+		}
+		if err != nil {
+			return rollback(err)
+		}
+	}
+	if len(files) > 0 {
+		if _, err := r.git(append([]string{"add", "--"}, files...)...); err != nil {
+			return rollback(err)
+		}
+		msg := "chore(release): " + strings.Join(tags, " ")
+		if _, err := r.git(append([]string{"commit", "-q", "-m", msg, "--"}, files...)...); err != nil {
+			return rollback(err)
+		}
+	}
+
+	commit, err := r.git("rev-parse", "HEAD")
+	if err != nil {
+		return rollback(err)
+	}
+	var tx strings.Builder
+	tx.WriteString("start\n")
+	for _, tag := range tags {
+		fmt.Fprintf(&tx, "create refs/tags/%s %s\n", tag, commit)
+	}
+	tx.WriteString("commit\n")
+	if _, err := r.gitInput(tx.String(), "update-ref", "--stdin"); err != nil {
+		return rollback(err)
+	}
+	return nil
+}
@@ -0,0 +1,117 @@
+package mono
+
+import (
+	"fmt"
+	"sort"
+
+	"golang.org/x/mod/modfile"
+)
+
+// Develop points every cross-module requirement at the local copy of the
+// required module: each module gets a replace directive to the directory
+// of every repo module it depends on, directly or not, since the go
+// command only honours the replaces of the main module. Placeholder
+// versions become ZeroVersion. It returns the modules whose go.mod it
+// rewrote.
+func (r *Repo) Develop() ([]*Module, error) {
+	var changed []*Module
+	for _, m := range r.Modules {
+		if err := r.checkPlaceholders(m); err != nil {
+			return changed, err
+		}
+		ok, err := r.update(m, func(f *modfile.File) error {
+			for _, dep := range r.deps(m) {
+				if err := f.AddReplace(dep.Path, "", relDir(m, dep), ""); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return changed, err
+		}
+		if ok {
+			changed = append(changed, m)
+		}
+	}
+	return changed, nil
+}
+
+// Pin prepares the repo for release: every cross-module requirement gets
+// the version that version returns for the required module, and the local
+// replace directives Develop added are dropped. It returns the modules
+// whose go.mod it rewrote.
+func (r *Repo) Pin(version func(*Module) (string, error)) ([]*Module, error) {
+	versions := make(map[string]string)
+	var changed []*Module
+	for _, m := range r.Modules {
+		if err := r.checkPlaceholders(m); err != nil {
+			return changed, err
+		}
+		ok, err := r.update(m, func(f *modfile.File) error {
+			for _, req := range append([]*modfile.Require(nil), f.Require...) {
+				dep := r.byPath[req.Mod.Path]
+				if dep == nil || dep == m {
+					continue
+				}
+				v, ok := versions[dep.Path]
+				if !ok {
+					var err error
+					if v, err = version(dep); err != nil {
+						return fmt.Errorf("version of %s: %w", dep.Path, err)
+					}
+					versions[dep.Path] = v
+				}
+				if err := f.AddRequire(dep.Path, v); err != nil {
+					return err
+				}
+			}
+			for _, rep := range append([]*modfile.Replace(nil), f.Replace...) {
+				if r.byPath[rep.Old.Path] != nil && rep.New.Version == "" {
+					if err := f.DropReplace(rep.Old.Path, rep.Old.Version); err != nil {
+						return err
+					}
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return changed, err
+		}
+		if ok {
+			changed = append(changed, m)
+		}
+	}
+	return changed, nil
+}
+
+// checkPlaceholders fails if m has a placeholder version for a module
+// outside the repo, which neither Develop nor Pin can fix.
+func (r *Repo) checkPlaceholders(m *Module) error {
+	for p, v := range m.Placeholders {
+		if r.byPath[p] == nil {
+			return fmt.Errorf("%s/go.mod: require %s %s: not a module of this repo and not a valid version", m.Dir, p, v)
+		}
+	}
+	return nil
+}
+
+// deps returns the repo modules m depends on, directly or transitively,
+// sorted by path.
+func (r *Repo) deps(m *Module) []*Module {
+	seen := map[*Module]bool{m: true}
+	var out []*Module
+	var visit func(*Module)
+	visit = func(m *Module) {
+		for _, dep := range r.Requires(m) {
+			if !seen[dep] {
+				seen[dep] = true
+				out = append(out, dep)
+				visit(dep)
+			}
+		}
+	}
+	visit(m)
+	sort.Slice(out, func(i, j int) bool { return out[i].Path < out[j].Path })
+	return out
+}
@@ -0,0 +1,78 @@
+package mono
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Result is the outcome of running the commands of a plan in one module.
+type Result struct {
+	Module *Module
+	// Err is the first failing command's error, or an error saying which
+	// dependency failed when the module was skipped.
+	Err     error
+	Skipped bool
+	// Output is the combined output of the commands run.
+	Output  []byte
+	Elapsed time.Duration
+}
+
+// Run runs cmds, one after the other, in the directory of each module of
+// mods, with up to parallel modules at a time. A module starts once the
+// modules of mods it requires have passed, and is skipped if one of them
+// failed. mods must be sorted as Sort does. Results are in the order of
+// mods.
+func (r *Repo) Run(ctx context.Context, mods []*Module, parallel int, cmds ...[]string) []Result {
+	if parallel < 1 {
+		parallel = 1
+	}
+	results := make([]Result, len(mods))
+	done := make(map[*Module]chan struct{}, len(mods))
+	index := make(map[*Module]int, len(mods))
+	for i, m := range mods {
+		done[m] = make(chan struct{})
+		index[m] = i
+	}
+	sem := make(chan struct{}, parallel)
+	for i, m := range mods {
+		go func(i int, m *Module) {
+			defer close(done[m])
+			res := &results[i]
+			res.Module = m
+			for _, dep := range r.Requires(m) {
+				ch, ok := done[dep]
+				if !ok {
+					continue
+				}
+				<-ch
+				if d := results[index[dep]]; d.Err != nil {
+					res.Skipped = true
+					res.Err = fmt.Errorf("dependency %s failed", dep.Path)
+					return
+				}
+			}
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			start := time.Now()
+			for _, args := range cmds {
+				cmd := exec.CommandContext(ctx, args[0], args[1:]...)
+				cmd.Dir = filepath.Join(r.Root, filepath.FromSlash(m.Dir))
+				out, err := cmd.CombinedOutput()
+				res.Output = append(res.Output, out...)
+				if err != nil {
+					res.Err = fmt.Errorf("%s: %w", strings.Join(args, " "), err)
+					break
+				}
+			}
+			res.Elapsed = time.Since(start)
+		}(i, m)
+	}
+	for _, m := range mods {
+		<-done[m]
+	}
+	return results
+}
@@ -0,0 +1,80 @@
+package mono
+
+import (
+	"go/version"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+
+	"golang.org/x/mod/modfile"
+)
+
+// SyncWork creates or updates go.work at the root so that it uses every
+// module of the repo and nothing else. Other directives of an existing
+// go.work, such as toolchain and replace, are kept. The go version is
+// raised to the highest one any module declares. It reports whether the
+// file changed.
+func (r *Repo) SyncWork() (bool, error) {
+	file := filepath.Join(r.Root, "go.work")
+	old, err := os.ReadFile(file)
+	if err != nil && !os.IsNotExist(err) {
+		return false, err
+	}
+	wf, err := modfile.ParseWork(file, old, nil)
+	if err != nil {
+		return false, err
+	}
+
+	want := make(map[string]bool, len(r.Modules))
+	goVersion := "1.18" // the first release with workspaces
+	for _, m := range r.Modules {
+		want[useDir(m)] = true
+		if m.File.Go != nil && version.Compare("go"+m.File.Go.Version, "go"+goVersion) > 0 {
+			goVersion = m.File.Go.Version
+		}
+	}
+	if wf.Go == nil || version.Compare("go"+wf.Go.Version, "go"+goVersion) < 0 {
+		if err := wf.AddGoStmt(goVersion); err != nil {
+			return false, err
+		}
+	}
+	for _, u := range append([]*modfile.Use(nil), wf.Use...) {
+		dir := useDir(&Module{Dir: path.Clean(u.Path)})
+		if !want[dir] || u.Path != dir {
+			// Stale, or spelled differently; the canonical form is added
+			// below.
+			if err := wf.DropUse(u.Path); err != nil {
+				return false, err
+			}
+			continue
+		}
+		delete(want, dir)
+	}
+	var add []string
+	for dir := range want {
+		add = append(add, dir)
+	}
+	sort.Strings(add)
+	for _, dir := range add {
+		if err := wf.AddUse(dir, ""); err != nil {
+			return false, err
+		}
+	}
+	wf.SortBlocks()
+	wf.Cleanup()
+
+	data := modfile.Format(wf.Syntax)
+	if string(data) == string(old) {
+		return false, nil
+	}
+	return true, os.WriteFile(file, data, 0o644)
+}
+
+// useDir is how go.work refers to m.
+func useDir(m *Module) string {
+	if m.Dir == "." {
+		return "."
+	}
+	return "./" + m.Dir
+}
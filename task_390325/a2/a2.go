@@ -2,86 +2,17 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
+	"log/slog"
 	"math/rand"
 	"net/http"
 	"time"
-)
-
-// Define the Error interface with additional methods
-type AppError interface {
-	error
-	Code() int
-	IsClientError() bool
-	IsTransient() bool
-}
-
-// ClientError for client-side issues
-type ClientError struct {
-	msg  string
-	code int
-}
-
-func (e *ClientError) Error() string {
-	return e.msg
-}
-
-func (e *ClientError) Code() int {
-	return e.code
-}
-
-func (e *ClientError) IsClientError() bool {
-	return true
-}
 
-func (e *ClientError) IsTransient() bool {
-	return false
-}
-
-// ServerError for server-side issues
-type ServerError struct {
-	msg  string
-	code int
-}
-
-func (e *ServerError) Error() string {
-	return e.msg
-}
-
-func (e *ServerError) Code() int {
-	return e.code
-}
-
-func (e *ServerError) IsClientError() bool {
-	return false
-}
-
-func (e *ServerError) IsTransient() bool {
-	return false
-}
-
-// TransientError for temporary issues
-type TransientError struct {
-	msg  string
-	code int
-}
-
-func (e *TransientError) Error() string {
-	return e.msg
-}
-
-func (e *TransientError) Code() int {
-	return e.code
-}
-
-func (e *TransientError) IsClientError() bool {
-	return false
-}
-
-func (e *TransientError) IsTransient() bool {
-	return true
-}
+	"github.com/shailendra-s-123/golang_random_5/task_390325/apperr"
+	"github.com/shailendra-s-123/golang_random_5/task_390325/retry"
+)
 
 // Logger interface for dependency injection
 type Logger interface {
@@ -90,8 +21,9 @@ type Logger interface {
 
 type SimpleLogger struct{}
 
-func (l *SimpleLogger) LogError(err error, context string) {
-	log.Printf("Error: %s | Context: %s", err, context)
+// LogError logs err at the level its kind calls for.
+func (l *SimpleLogger) LogError(err error, where string) {
+	apperr.Log(context.Background(), slog.Default(), "request failed", err, "context", where)
 }
 
 // Repository layer
@@ -101,51 +33,43 @@ type UserRepository struct {
 
 func (r *UserRepository) FindUserByID(ctx context.Context, id string) (string, error) {
 	if id == "" {
-		return "", &ClientError{"user ID is required", http.StatusBadRequest}
+		return "", apperr.New(apperr.Invalid, "user ID is required")
 	}
 	// Simulate a database error with a probabilistic approach (e.g., downtime)
 	if rand.Float32() < 0.5 {
 		// Simulate transient error
-		return "", &TransientError{"temporary database connection failed", http.StatusInternalServerError}
+		return "", apperr.Wrap(errors.New("temporary database connection failed"), apperr.Unavailable, "user store unavailable")
 	}
 	// Simulate a successful database response
 	return "User Name", nil
 }
 
-// backoffRetries function with exponential backoff
-func backoffRetries(maxRetries int, fn func() (string, error)) (string, error) {
-	var result string
-	var err error
-	for i := 0; i < maxRetries; i++ {
-		result, err = fn()
-		if err == nil {
-			return result, nil
-		}
-
-		// Check if the error is transient, if so, retry it
-		if transientErr, ok := err.(AppError); ok && transientErr.IsTransient() {
-			time.Sleep(time.Duration(1<<i) * time.Millisecond * 100) // Exponential backoff
-			continue
-		}
-		return "", err // Non-transient or non-app error, return it immediately
-	}
-	return "", err // Return last error if retries exhausted
-}
-
 // Service layer
 type UserService struct {
 	repo   *UserRepository
 	logger Logger
-}
-
-func NewUserService(repo *UserRepository, logger Logger) *UserService {
-	return &UserService{repo: repo, logger: logger}
+	policy retry.Policy
+}
+
+// NewUserService retries transient repository errors up to maxAttempts
+// times, with exponential backoff starting at backoff.
+func NewUserService(repo *UserRepository, logger Logger, maxAttempts int, backoff time.Duration) *UserService {
+	return &UserService{repo: repo, logger: logger, policy: retry.Policy{
+		MaxAttempts: maxAttempts,
+		MaxElapsed:  5 * time.Second,
+		Backoff:     retry.Exponential(backoff, 2*time.Second),
+		Budget:      retry.NewBudget(100, 0.1),
+		OnAttempt: func(a retry.Attempt) {
+			if a.Delay > 0 {
+				log.Printf("Attempt %d failed with transient error: %v. Retrying in %v...", a.N, a.Err, a.Delay)
+			}
+		},
+	}}
 }
 
 func (s *UserService) GetUserByID(ctx context.Context, id string) (string, error) {
-	// Wrap the repo call to include retries
-	return backoffRetries(3, func() (string, error) {
-		return s.repo.FindUserByID(ctx, id) // Attempt to find user
+	return retry.Retry(ctx, s.policy, func(ctx context.Context) (string, error) {
+		return s.repo.FindUserByID(ctx, id)
 	})
 }
 
@@ -156,11 +80,7 @@ func userHandler(service *UserService, logger Logger) http.HandlerFunc {
 		user, err := service.GetUserByID(r.Context(), id)
 		if err != nil {
 			logger.LogError(err, "Handler.userHandler")
-			if appErr, ok := err.(AppError); ok {
-				http.Error(w, appErr.Error(), appErr.Code())
-				return
-			}
-			http.Error(w, "internal server error", http.StatusInternalServerError)
+			apperr.WriteHTTP(w, err)
 			return
 		}
 		w.WriteHeader(http.StatusOK)
@@ -187,7 +107,7 @@ func main() {
 	// Initialize dependencies
 	logger := &SimpleLogger{}
 	repo := &UserRepository{logger: logger}
-	service := NewUserService(repo, logger)
+	service := NewUserService(repo, logger, 3, 100*time.Millisecond)
 
 	// Create HTTP server
 	mux := http.NewServeMux()
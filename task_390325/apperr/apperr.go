@@ -0,0 +1,175 @@
+// Package apperr classifies application errors by Kind. The kind of an
+// error decides its HTTP status, its gRPC code, whether the operation is
+// worth retrying and how loudly to log it, so that every transport maps
+// the same failure the same way.
+package apperr
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"google.golang.org/grpc/codes"
+)
+
+// Kind is the class of an error.
+type Kind int
+
+const (
+	// Internal is a bug or an unexpected failure. It is the zero Kind and
+	// the kind of errors that do not say otherwise.
+	Internal Kind = iota
+	// Invalid means the request is malformed or fails validation.
+	Invalid
+	NotFound
+	// Conflict means the request clashes with the current state, such as
+	// creating something that already exists.
+	Conflict
+	Unauthenticated
+	PermissionDenied
+	// RateLimited means the caller sent too much and may try again later.
+	RateLimited
+	// Canceled means the caller gave up on the request.
+	Canceled
+	// Timeout means the operation ran out of time, possibly before a
+	// dependency answered.
+	Timeout
+	// Unavailable means a dependency is down or overloaded for now.
+	Unavailable
+)
+
+var kinds = [...]struct {
+	name      string
+	status    int
+	code      codes.Code
+	retryable bool
+	level     slog.Level
+}{
+	Internal:         {"internal", http.StatusInternalServerError, codes.Internal, false, slog.LevelError},
+	Invalid:          {"invalid", http.StatusBadRequest, codes.InvalidArgument, false, slog.LevelInfo},
+	NotFound:         {"not found", http.StatusNotFound, codes.NotFound, false, slog.LevelInfo},
+	Conflict:         {"conflict", http.StatusConflict, codes.AlreadyExists, false, slog.LevelInfo},
+	Unauthenticated:  {"unauthenticated", http.StatusUnauthorized, codes.Unauthenticated, false, slog.LevelInfo},
+	PermissionDenied: {"permission denied", http.StatusForbidden, codes.PermissionDenied, false, slog.LevelWarn},
+	RateLimited:      {"rate limited", http.StatusTooManyRequests, codes.ResourceExhausted, true, slog.LevelWarn},
+	Canceled:         {"canceled", 499, codes.Canceled, false, slog.LevelInfo},
+	Timeout:          {"timeout", http.StatusGatewayTimeout, codes.DeadlineExceeded, true, slog.LevelWarn},
+	Unavailable:      {"unavailable", http.StatusServiceUnavailable, codes.Unavailable, true, slog.LevelWarn},
+}
+
+func (k Kind) valid() Kind {
+	if k < 0 || int(k) >= len(kinds) {
+		return Internal
+	}
+	return k
+}
+
+func (k Kind) String() string { return kinds[k.valid()].name }
+
+// HTTPStatus is the response status for errors of kind k. Canceled uses
+// 499, the status nginx logs for requests the client closed.
+func (k Kind) HTTPStatus() int { return kinds[k.valid()].status }
+
+// GRPCCode is the status code for errors of kind k.
+func (k Kind) GRPCCode() codes.Code { return kinds[k.valid()].code }
+
+// Retryable reports whether an operation that failed with kind k may
+// succeed if tried again unchanged.
+func (k Kind) Retryable() bool { return kinds[k.valid()].retryable }
+
+// LogLevel is the level to log errors of kind k at: expected client
+// mistakes are Info, conditions worth watching are Warn and Internal is
+// Error.
+func (k Kind) LogLevel() slog.Level { return kinds[k.valid()].level }
+
+// IsClientError reports whether kind k is the caller's fault.
+func (k Kind) IsClientError() bool {
+	s := k.HTTPStatus()
+	return s >= 400 && s < 500 && k != RateLimited && k != Canceled
+}
+
+// Error is an error with a Kind. Msg is meant for the caller; Err, the
+// cause, is only for logs, as it may expose internals.
+type Error struct {
+	Kind Kind
+	Msg  string
+	Err  error
+}
+
+// New returns an error of the given kind.
+func New(kind Kind, msg string) *Error {
+	return &Error{Kind: kind, Msg: msg}
+}
+
+// Wrap returns an error of the given kind caused by err, which must not
+// be nil.
+func Wrap(err error, kind Kind, msg string) *Error {
+	return &Error{Kind: kind, Msg: msg, Err: err}
+}
+
+func (e *Error) Error() string {
+	switch {
+	case e.Err == nil && e.Msg == "":
+		return e.Kind.String()
+	case e.Err == nil:
+		return e.Msg
+	case e.Msg == "":
+		return e.Err.Error()
+	}
+	return e.Msg + ": " + e.Err.Error()
+}
+
+func (e *Error) Unwrap() error { return e.Err }
+
+// KindOf returns the kind of the outermost *Error in err's chain. Errors
+// without one are classified by their gRPC status if they carry one, and
+// the context errors as Canceled and Timeout; anything else is Internal.
+func KindOf(err error) Kind {
+	var e *Error
+	switch {
+	case errors.As(err, &e):
+		return e.Kind
+	case err == nil:
+		return Internal
+	}
+	if code, ok := grpcCode(err); ok {
+		return FromGRPCCode(code)
+	}
+	switch {
+	case errors.Is(err, context.Canceled):
+		return Canceled
+	case errors.Is(err, context.DeadlineExceeded):
+		return Timeout
+	}
+	return Internal
+}
+
+// Message is the text to show the caller for err: the message of its
+// *Error, or the kind's name if that is empty or the error is Internal,
+// whose details stay in the logs.
+func Message(err error) string {
+	var e *Error
+	if !errors.As(err, &e) || e.Kind.valid() == Internal || e.Msg == "" {
+		return KindOf(err).String()
+	}
+	return e.Msg
+}
+
+// HTTPStatus is KindOf(err).HTTPStatus().
+func HTTPStatus(err error) int { return KindOf(err).HTTPStatus() }
+
+// Retryable is KindOf(err).Retryable().
+func Retryable(err error) bool { return KindOf(err).Retryable() }
+
+// WriteHTTP writes err as a plain text error response.
+func WriteHTTP(w http.ResponseWriter, err error) {
+	http.Error(w, Message(err), HTTPStatus(err))
+}
+
+// Log logs err at the level its kind calls for, with the kind as an
+// attribute.
+func Log(ctx context.Context, logger *slog.Logger, msg string, err error, args ...any) {
+	kind := KindOf(err)
+	logger.Log(ctx, kind.LogLevel(), msg, append([]any{"err", err, "kind", kind.String()}, args...)...)
+}
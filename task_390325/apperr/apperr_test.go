@@ -0,0 +1,115 @@
+package apperr
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestKinds(t *testing.T) {
+	for k := Internal; k <= Unavailable; k++ {
+		if k.String() == "" || k.HTTPStatus() == 0 {
+			t.Errorf("kind %d has no entry", k)
+		}
+		// Every kind survives a round trip through a gRPC status.
+		if got := FromGRPCCode(k.GRPCCode()); got != k {
+			t.Errorf("%v: FromGRPCCode(%v) = %v", k, k.GRPCCode(), got)
+		}
+		if k.IsClientError() && k.Retryable() {
+			t.Errorf("%v is both a client error and retryable", k)
+		}
+	}
+	if k := Kind(99); k.String() != "internal" || k.HTTPStatus() != http.StatusInternalServerError {
+		t.Errorf("out of range kind: %v %d", k, k.HTTPStatus())
+	}
+}
+
+func TestKindOf(t *testing.T) {
+	cause := errors.New("dial tcp: connection refused")
+	for _, tc := range []struct {
+		err  error
+		want Kind
+	}{
+		{New(NotFound, "no such user"), NotFound},
+		{fmt.Errorf("lookup: %w", Wrap(cause, Unavailable, "user store unavailable")), Unavailable},
+		{Wrap(New(NotFound, "inner"), Invalid, "outer"), Invalid},
+		{fmt.Errorf("query: %w", context.DeadlineExceeded), Timeout},
+		{context.Canceled, Canceled},
+		{status.Error(codes.ResourceExhausted, "slow down"), RateLimited},
+		{cause, Internal},
+	} {
+		if got := KindOf(tc.err); got != tc.want {
+			t.Errorf("KindOf(%v) = %v, want %v", tc.err, got, tc.want)
+		}
+	}
+}
+
+func TestErrorsAs(t *testing.T) {
+	cause := errors.New("connection refused")
+	err := fmt.Errorf("GetUser: %w", Wrap(cause, Unavailable, "user store unavailable"))
+	if err.Error() != "GetUser: user store unavailable: connection refused" {
+		t.Errorf("Error() = %q", err)
+	}
+	var e *Error
+	if !errors.As(err, &e) || e.Kind != Unavailable || !errors.Is(err, cause) {
+		t.Fatalf("As: %v", e)
+	}
+	if !Retryable(err) || HTTPStatus(err) != http.StatusServiceUnavailable {
+		t.Errorf("Retryable %v, HTTPStatus %d", Retryable(err), HTTPStatus(err))
+	}
+}
+
+func TestMessage(t *testing.T) {
+	for _, tc := range []struct {
+		err  error
+		want string
+	}{
+		{New(Invalid, "user ID is required"), "user ID is required"},
+		{Wrap(errors.New("nil map"), Internal, "saving user"), "internal"},
+		{errors.New("secret path /etc/db.conf"), "internal"},
+		{New(Timeout, ""), "timeout"},
+	} {
+		if got := Message(tc.err); got != tc.want {
+			t.Errorf("Message(%v) = %q, want %q", tc.err, got, tc.want)
+		}
+	}
+}
+
+func TestWriteHTTP(t *testing.T) {
+	rec := httptest.NewRecorder()
+	WriteHTTP(rec, fmt.Errorf("handler: %w", New(NotFound, "no such user")))
+	if rec.Code != http.StatusNotFound || rec.Body.String() != "no such user\n" {
+		t.Errorf("got %d %q", rec.Code, rec.Body)
+	}
+}
+
+func TestToGRPC(t *testing.T) {
+	for _, tc := range []struct {
+		err  error
+		code codes.Code
+		msg  string
+	}{
+		{fmt.Errorf("handler: %w", Wrap(errors.New("row locked"), Conflict, "user exists")), codes.AlreadyExists, "user exists"},
+		{errors.New("secret"), codes.Internal, "internal"},
+		{status.Error(codes.NotFound, "gone"), codes.NotFound, "gone"},
+		{context.DeadlineExceeded, codes.DeadlineExceeded, "timeout"},
+	} {
+		s := status.Convert(ToGRPC(tc.err))
+		if s.Code() != tc.code || s.Message() != tc.msg {
+			t.Errorf("ToGRPC(%v) = %v %q, want %v %q", tc.err, s.Code(), s.Message(), tc.code, tc.msg)
+		}
+	}
+	if ToGRPC(nil) != nil {
+		t.Error("ToGRPC(nil) != nil")
+	}
+	// Returned unwrapped, an *Error is a status error by itself.
+	if s, ok := status.FromError(New(PermissionDenied, "admins only")); !ok || s.Code() != codes.PermissionDenied || s.Message() != "admins only" {
+		t.Errorf("FromError: %v %v", s, ok)
+	}
+}
@@ -0,0 +1,85 @@
+package apperr
+
+import (
+	"context"
+	"errors"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// GRPCStatus makes e a gRPC status error, so that a handler can return it
+// as is: the code comes from its kind and the message from Message.
+func (e *Error) GRPCStatus() *status.Status {
+	return status.New(e.Kind.GRPCCode(), Message(e))
+}
+
+// FromGRPCCode returns the kind for a gRPC status code, so that errors
+// returned by a server keep their semantics on the client.
+func FromGRPCCode(code codes.Code) Kind {
+	switch code {
+	case codes.InvalidArgument, codes.OutOfRange:
+		return Invalid
+	case codes.NotFound:
+		return NotFound
+	case codes.AlreadyExists, codes.Aborted, codes.FailedPrecondition:
+		return Conflict
+	case codes.Unauthenticated:
+		return Unauthenticated
+	case codes.PermissionDenied:
+		return PermissionDenied
+	case codes.ResourceExhausted:
+		return RateLimited
+	case codes.Canceled:
+		return Canceled
+	case codes.DeadlineExceeded:
+		return Timeout
+	case codes.Unavailable:
+		return Unavailable
+	}
+	return Internal
+}
+
+// grpcCode returns the code of err if it is a gRPC status error.
+func grpcCode(err error) (codes.Code, bool) {
+	s, ok := status.FromError(err)
+	if !ok {
+		return codes.Unknown, false
+	}
+	return s.Code(), true
+}
+
+// ToGRPC returns err as a gRPC status error. An *Error in the chain
+// decides the status, since grpc-go would otherwise put the text of every
+// wrapping error in the message; other status errors pass through, and
+// any other error gets the code of its kind and a message that does not
+// leak its cause.
+func ToGRPC(err error) error {
+	var e *Error
+	switch {
+	case err == nil:
+		return nil
+	case errors.As(err, &e):
+		return e.GRPCStatus().Err()
+	}
+	if _, ok := grpcCode(err); ok {
+		return err
+	}
+	return status.Error(KindOf(err).GRPCCode(), Message(err))
+}
+
+// UnaryServerInterceptor applies ToGRPC to the errors of unary handlers.
+func UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		resp, err := handler(ctx, req)
+		return resp, ToGRPC(err)
+	}
+}
+
+// StreamServerInterceptor applies ToGRPC to the errors of stream handlers.
+func StreamServerInterceptor() grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, _ *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		return ToGRPC(handler(srv, ss))
+	}
+}
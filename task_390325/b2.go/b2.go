@@ -2,86 +2,17 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
+	"log/slog"
 	"math/rand"
 	"net/http"
 	"time"
-)
-
-// Define the Error interface with additional methods
-type AppError interface {
-	error
-	Code() int
-	IsClientError() bool
-	IsTransient() bool
-}
-
-// ClientError for client-side issues
-type ClientError struct {
-	msg  string
-	code int
-}
-
-func (e *ClientError) Error() string {
-	return e.msg
-}
-
-func (e *ClientError) Code() int {
-	return e.code
-}
-
-func (e *ClientError) IsClientError() bool {
-	return true
-}
 
-func (e *ClientError) IsTransient() bool {
-	return false
-}
-
-// ServerError for server-side issues
-type ServerError struct {
-	msg  string
-	code int
-}
-
-func (e *ServerError) Error() string {
-	return e.msg
-}
-
-func (e *ServerError) Code() int {
-	return e.code
-}
-
-func (e *ServerError) IsClientError() bool {
-	return false
-}
-
-func (e *ServerError) IsTransient() bool {
-	return false
-}
-
-// TransientServerError for server-side transient issues
-type TransientServerError struct {
-	msg  string
-	code int
-}
-
-func (e *TransientServerError) Error() string {
-	return e.msg
-}
-
-func (e *TransientServerError) Code() int {
-	return e.code
-}
-
-func (e *TransientServerError) IsClientError() bool {
-	return false
-}
-
-func (e *TransientServerError) IsTransient() bool {
-	return true
-}
+	"github.com/shailendra-s-123/golang_random_5/task_390325/apperr"
+	"github.com/shailendra-s-123/golang_random_5/task_390325/retry"
+)
 
 // Logger interface for dependency injection
 type Logger interface {
@@ -90,25 +21,9 @@ type Logger interface {
 
 type SimpleLogger struct{}
 
-func (l *SimpleLogger) LogError(err error, context string) {
-	log.Printf("Error: %s | Context: %s", err, context)
-}
-
-// Retry function with exponential backoff
-func retry(ctx context.Context, attempts int, backoff time.Duration, f func(context.Context) error) error {
-	for attempt := 1; attempt <= attempts; attempt++ {
-		err := f(ctx)
-		if err == nil {
-			return nil
-		}
-		log.Printf("Attempt %d failed: %v, retrying...", attempt, err)
-		select {
-		case <-ctx.Done():
-			return ctx.Err()
-		case <-time.After(backoff * time.ExponentialBackoff(1)):
-		}
-	}
-	return fmt.Errorf("max attempts reached: %d", attempts)
+// LogError logs err at the level its kind calls for.
+func (l *SimpleLogger) LogError(err error, where string) {
+	apperr.Log(context.Background(), slog.Default(), "request failed", err, "context", where)
 }
 
 // Repository layer
@@ -118,11 +33,11 @@ type UserRepository struct {
 
 func (r *UserRepository) FindUserByID(ctx context.Context, id string) (string, error) {
 	if id == "" {
-		return "", &ClientError{"user ID is required", http.StatusBadRequest}
+		return "", apperr.New(apperr.Invalid, "user ID is required")
 	}
 	// Simulate a transient database error
 	if rand.Intn(2) == 0 {
-		return "", &TransientServerError{"database connection failed transiently", http.StatusInternalServerError}
+		return "", apperr.Wrap(errors.New("database connection failed transiently"), apperr.Unavailable, "user store unavailable")
 	}
 	return "", nil
 }
@@ -131,23 +46,29 @@ func (r *UserRepository) FindUserByID(ctx context.Context, id string) (string, e
 type UserService struct {
 	repo   *UserRepository
 	logger Logger
-	maxAttempts int
-	backoff    time.Duration
+	policy retry.Policy
 }
 
+// NewUserService retries transient repository errors up to maxAttempts
+// times, with exponential backoff starting at backoff.
 func NewUserService(repo *UserRepository, logger Logger, maxAttempts int, backoff time.Duration) *UserService {
-	return &UserService{repo: repo, logger: logger, maxAttempts: maxAttempts, backoff: backoff}
+	return &UserService{repo: repo, logger: logger, policy: retry.Policy{
+		MaxAttempts: maxAttempts,
+		MaxElapsed:  5 * time.Second,
+		Backoff:     retry.Exponential(backoff, 2*time.Second),
+		Budget:      retry.NewBudget(100, 0.1),
+		OnAttempt: func(a retry.Attempt) {
+			if a.Delay > 0 {
+				log.Printf("Attempt %d failed with transient error: %v. Retrying in %v...", a.N, a.Err, a.Delay)
+			}
+		},
+	}}
 }
 
 func (s *UserService) GetUserByID(ctx context.Context, id string) (string, error) {
-	user, err := retry(
-		ctx,
-		s.maxAttempts,
-		s.backoff,
-		func(ctx context.Context) error {
-			return s.repo.FindUserByID(ctx, id)
-		},
-	)
+	user, err := retry.Retry(ctx, s.policy, func(ctx context.Context) (string, error) {
+		return s.repo.FindUserByID(ctx, id)
+	})
 	if err != nil {
 		s.logger.LogError(err, "Service.GetUserByID")
 		return "", err
@@ -162,11 +83,7 @@ func userHandler(service *UserService, logger Logger) http.HandlerFunc {
 		user, err := service.GetUserByID(r.Context(), id)
 		if err != nil {
 			logger.LogError(err, "Handler.userHandler")
-			if appErr, ok := err.(AppError); ok {
-				http.Error(w, appErr.Error(), appErr.Code())
-				return
-			}
-			http.Error(w, "internal server error", http.StatusInternalServerError)
+			apperr.WriteHTTP(w, err)
 			return
 		}
 		w.WriteHeader(http.StatusOK)
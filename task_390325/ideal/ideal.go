@@ -2,53 +2,14 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
+	"log/slog"
 	"net/http"
-)
-
-// Define the Error interface with additional methods
-type AppError interface {
-	error
-	Code() int
-	IsClientError() bool
-}
-
-// ClientError for client-side issues
-type ClientError struct {
-	msg  string
-	code int
-}
-
-func (e *ClientError) Error() string {
-	return e.msg
-}
-
-func (e *ClientError) Code() int {
-	return e.code
-}
-
-func (e *ClientError) IsClientError() bool {
-	return true
-}
-
-// ServerError for server-side issues
-type ServerError struct {
-	msg  string
-	code int
-}
-
-func (e *ServerError) Error() string {
-	return e.msg
-}
 
-func (e *ServerError) Code() int {
-	return e.code
-}
-
-func (e *ServerError) IsClientError() bool {
-	return false
-}
+	"github.com/shailendra-s-123/golang_random_5/task_390325/apperr"
+)
 
 // Logger interface for dependency injection
 type Logger interface {
@@ -57,8 +18,9 @@ type Logger interface {
 
 type SimpleLogger struct{}
 
-func (l *SimpleLogger) LogError(err error, context string) {
-	log.Printf("Error: %s | Context: %s", err, context)
+// LogError logs err at the level its kind calls for.
+func (l *SimpleLogger) LogError(err error, where string) {
+	apperr.Log(context.Background(), slog.Default(), "request failed", err, "context", where)
 }
 
 // Repository layer
@@ -68,10 +30,10 @@ type UserRepository struct {
 
 func (r *UserRepository) FindUserByID(ctx context.Context, id string) (string, error) {
 	if id == "" {
-		return "", &ClientError{"user ID is required", http.StatusBadRequest}
+		return "", apperr.New(apperr.Invalid, "user ID is required")
 	}
 	// Simulate a database error
-	return "", &ServerError{"database connection failed", http.StatusInternalServerError}
+	return "", apperr.Wrap(errors.New("database connection failed"), apperr.Unavailable, "user store unavailable")
 }
 
 // Service layer
@@ -100,11 +62,7 @@ func userHandler(service *UserService, logger Logger) http.HandlerFunc {
 		user, err := service.GetUserByID(r.Context(), id)
 		if err != nil {
 			logger.LogError(err, "Handler.userHandler")
-			if appErr, ok := err.(AppError); ok {
-				http.Error(w, appErr.Error(), appErr.Code())
-				return
-			}
-			http.Error(w, "internal server error", http.StatusInternalServerError)
+			apperr.WriteHTTP(w, err)
 			return
 		}
 		w.WriteHeader(http.StatusOK)
@@ -0,0 +1,67 @@
+package retry
+
+import (
+	"math/rand/v2"
+	"time"
+)
+
+// Backoff returns how long to wait after the attempt-th failed attempt,
+// counting from 1. prev is what it returned for the previous attempt, zero
+// after the first.
+type Backoff func(attempt int, prev time.Duration) time.Duration
+
+// Constant waits d between attempts.
+func Constant(d time.Duration) Backoff {
+	return func(int, time.Duration) time.Duration { return d }
+}
+
+// Exponential waits a random time up to base doubled after every attempt,
+// capped at limit: the "full jitter" of the AWS architecture blog, which
+// spreads out clients that failed together.
+func Exponential(base, limit time.Duration) Backoff {
+	return func(attempt int, _ time.Duration) time.Duration {
+		return jitter(0, ceiling(base, limit, attempt))
+	}
+}
+
+// Decorrelated waits a random time between base and three times the
+// previous wait, capped at limit: the "decorrelated jitter" of the AWS
+// architecture blog, which grows about as fast as Exponential but keeps
+// successive waits apart.
+func Decorrelated(base, limit time.Duration) Backoff {
+	return func(_ int, prev time.Duration) time.Duration {
+		return min(jitter(base, 3*max(prev, base)), limit)
+	}
+}
+
+// Fibonacci waits base times the attempt-th Fibonacci number, capped at
+// limit: 1, 1, 2, 3, 5... times base. It grows slower than Exponential.
+func Fibonacci(base, limit time.Duration) Backoff {
+	return func(attempt int, _ time.Duration) time.Duration {
+		a, b := time.Duration(1), time.Duration(1)
+		for i := 1; i < attempt; i++ {
+			a, b = b, a+b
+			if a*base >= limit || a*base < 0 {
+				return limit
+			}
+		}
+		return min(a*base, limit)
+	}
+}
+
+// ceiling returns base*2^(attempt-1), capped at limit without overflowing.
+func ceiling(base, limit time.Duration, attempt int) time.Duration {
+	d := base
+	for i := 1; i < attempt && d < limit; i++ {
+		d *= 2
+	}
+	return min(d, limit)
+}
+
+// jitter returns a random duration in [lo, hi).
+func jitter(lo, hi time.Duration) time.Duration {
+	if hi <= lo {
+		return lo
+	}
+	return lo + rand.N(hi-lo)
+}
@@ -0,0 +1,38 @@
+package retry
+
+import "sync"
+
+// Budget limits retries across every call that shares it, so that a
+// failing dependency does not get hit with a multiple of its normal load:
+// the token bucket of gRPC's retry throttling. Every failed attempt takes
+// a token and every success puts back ratio of one; retries are allowed
+// only while more than half of the tokens are left. First attempts are
+// never throttled.
+type Budget struct {
+	mu     sync.Mutex
+	tokens float64
+	max    float64
+	ratio  float64
+}
+
+// NewBudget returns a full budget of maxTokens tokens. With ratio 0.1,
+// about one retry is allowed per ten successful calls once the failures
+// have used up the spare half.
+func NewBudget(maxTokens, ratio float64) *Budget {
+	return &Budget{tokens: maxTokens, max: maxTokens, ratio: ratio}
+}
+
+// failure records a failed attempt and reports whether a retry is
+// allowed.
+func (b *Budget) failure() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.tokens = max(b.tokens-1, 0)
+	return b.tokens > b.max/2
+}
+
+func (b *Budget) success() {
+	b.mu.Lock()
+	b.tokens = min(b.tokens+b.ratio, b.max)
+	b.mu.Unlock()
+}
@@ -0,0 +1,166 @@
+// Package retry calls an operation until it succeeds, fails for good or
+// runs out of attempts, time or budget, waiting between attempts as a
+// Backoff says.
+package retry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/status"
+
+	"github.com/shailendra-s-123/golang_random_5/task_390325/apperr"
+)
+
+// Policy says when and how often to retry. The zero Policy retries the
+// errors apperr considers retryable with Exponential(100ms, 10s) until the
+// context is done.
+type Policy struct {
+	// MaxAttempts caps the number of attempts, the first one included;
+	// zero means no cap.
+	MaxAttempts int
+	// MaxElapsed is how long to keep trying from the first attempt; zero
+	// means no limit. No retry starts whose wait would end past it.
+	MaxElapsed time.Duration
+	Backoff    Backoff
+	// Retryable reports whether an error is worth another attempt;
+	// apperr.Retryable if nil.
+	Retryable func(error) bool
+	// Budget, if set, is shared with other calls and can deny retries.
+	Budget *Budget
+	// OnAttempt, if set, is called after every attempt.
+	OnAttempt func(Attempt)
+}
+
+// Attempt describes an attempt that has just finished.
+type Attempt struct {
+	// N counts the attempts from 1.
+	N   int
+	Err error
+	// Elapsed is the time since the first attempt started.
+	Elapsed time.Duration
+	// Delay is how long Retry waits before the next attempt, zero if it
+	// makes no more.
+	Delay time.Duration
+}
+
+// Retry calls f until it returns a nil or non-retryable error, returning
+// its result. When it gives up on a retryable error, the error says why
+// and wraps the last one. If ctx is done while waiting, the error wraps
+// ctx.Err() instead, with the last error in its text.
+//
+// The wait after a failure is the policy's backoff, or the retry-after
+// hint the error carries if that is longer: see After.
+func Retry[T any](ctx context.Context, p Policy, f func(context.Context) (T, error)) (T, error) {
+	backoff := p.Backoff
+	if backoff == nil {
+		backoff = Exponential(100*time.Millisecond, 10*time.Second)
+	}
+	retryable := p.Retryable
+	if retryable == nil {
+		retryable = apperr.Retryable
+	}
+	start := time.Now()
+	var delay time.Duration
+	for n := 1; ; n++ {
+		v, err := f(ctx)
+		a := Attempt{N: n, Err: err, Elapsed: time.Since(start)}
+		if err == nil || !retryable(err) {
+			if err == nil && p.Budget != nil {
+				p.Budget.success()
+			}
+			if p.OnAttempt != nil {
+				p.OnAttempt(a)
+			}
+			return v, err
+		}
+
+		delay = backoff(n, delay)
+		if hint, ok := RetryAfter(err); ok && hint > delay {
+			delay = hint
+		}
+		var stop string
+		switch {
+		case p.Budget != nil && !p.Budget.failure():
+			stop = "retry budget exhausted"
+		case p.MaxAttempts > 0 && n >= p.MaxAttempts:
+			stop = "out of attempts"
+		case p.MaxElapsed > 0 && a.Elapsed+delay > p.MaxElapsed:
+			stop = "out of time"
+		}
+		if stop == "" {
+			a.Delay = delay
+		}
+		if p.OnAttempt != nil {
+			p.OnAttempt(a)
+		}
+		if stop != "" {
+			return v, fmt.Errorf("retry: %s after %d attempts: %w", stop, n, err)
+		}
+
+		t := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return v, fmt.Errorf("retry: %w after %d attempts, last error: %v", ctx.Err(), n, err)
+		case <-t.C:
+		}
+	}
+}
+
+// Do is Retry for operations without a result.
+func Do(ctx context.Context, p Policy, f func(context.Context) error) error {
+	_, err := Retry(ctx, p, func(ctx context.Context) (struct{}, error) {
+		return struct{}{}, f(ctx)
+	})
+	return err
+}
+
+type hinted struct {
+	error
+	after time.Duration
+}
+
+func (h *hinted) Unwrap() error             { return h.error }
+func (h *hinted) RetryAfter() time.Duration { return h.after }
+
+// After returns err with a hint to wait at least d before trying again,
+// as a server asks with a Retry-After header. The result wraps err.
+func After(err error, d time.Duration) error {
+	return &hinted{err, d}
+}
+
+// RetryAfter returns the retry-after hint of err: one set by After or by
+// any error in the chain with a RetryAfter() time.Duration method, or the
+// RetryInfo detail of a gRPC status.
+func RetryAfter(err error) (time.Duration, bool) {
+	var h interface{ RetryAfter() time.Duration }
+	if errors.As(err, &h) {
+		return h.RetryAfter(), true
+	}
+	if s, ok := status.FromError(err); ok {
+		for _, d := range s.Details() {
+			if info, ok := d.(*errdetails.RetryInfo); ok && info.GetRetryDelay() != nil {
+				return info.GetRetryDelay().AsDuration(), true
+			}
+		}
+	}
+	return 0, false
+}
+
+// ParseRetryAfter parses the value of a Retry-After header, either
+// seconds or an HTTP date, into how long to wait from now.
+func ParseRetryAfter(value string, now time.Time) (time.Duration, bool) {
+	if secs, err := strconv.Atoi(value); err == nil && secs >= 0 {
+		return time.Duration(secs) * time.Second, true
+	}
+	if t, err := http.ParseTime(value); err == nil {
+		return max(t.Sub(now), 0), true
+	}
+	return 0, false
+}
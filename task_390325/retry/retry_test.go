@@ -0,0 +1,179 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+
+	"github.com/shailendra-s-123/golang_random_5/task_390325/apperr"
+)
+
+var unavailable = apperr.New(apperr.Unavailable, "down")
+
+// failing returns an operation that fails with err n times, then succeeds.
+func failing(n int, err error) func(context.Context) (string, error) {
+	calls := 0
+	return func(context.Context) (string, error) {
+		calls++
+		if calls <= n {
+			return "", err
+		}
+		return "ok", nil
+	}
+}
+
+func TestBackoffs(t *testing.T) {
+	for i, want := range []time.Duration{1, 1, 2, 3, 5, 8, 10, 10} {
+		attempt := i + 1
+		if got := Fibonacci(time.Second, 10*time.Second)(attempt, 0); got != want*time.Second {
+			t.Errorf("Fibonacci(%d) = %v, want %v", attempt, got, want*time.Second)
+		}
+	}
+	if got := Fibonacci(time.Second, time.Minute)(1000, 0); got != time.Minute {
+		t.Errorf("Fibonacci(1000) = %v", got)
+	}
+	exp := Exponential(10*time.Millisecond, time.Second)
+	dec := Decorrelated(10*time.Millisecond, time.Second)
+	var prev time.Duration
+	for attempt := 1; attempt <= 100; attempt++ {
+		ceil := min(10*time.Millisecond<<min(attempt-1, 20), time.Second)
+		if d := exp(attempt, 0); d < 0 || d >= ceil {
+			t.Fatalf("Exponential(%d) = %v, ceiling %v", attempt, d, ceil)
+		}
+		d := dec(attempt, prev)
+		if d < 10*time.Millisecond || d > time.Second || d > 3*max(prev, 10*time.Millisecond) {
+			t.Fatalf("Decorrelated(%d, %v) = %v", attempt, prev, d)
+		}
+		prev = d
+	}
+	if got := Constant(time.Second)(7, 0); got != time.Second {
+		t.Errorf("Constant = %v", got)
+	}
+}
+
+func TestRetry(t *testing.T) {
+	var attempts []Attempt
+	p := Policy{
+		MaxAttempts: 5,
+		Backoff:     Constant(time.Millisecond),
+		OnAttempt:   func(a Attempt) { attempts = append(attempts, a) },
+	}
+	v, err := Retry(context.Background(), p, failing(2, unavailable))
+	if v != "ok" || err != nil {
+		t.Fatalf("got %q, %v", v, err)
+	}
+	if len(attempts) != 3 || attempts[0].Err != unavailable || attempts[0].Delay != time.Millisecond || attempts[2].Err != nil || attempts[2].Delay != 0 {
+		t.Fatalf("attempts %+v", attempts)
+	}
+
+	// Errors that are not retryable end it at once, unwrapped.
+	attempts = nil
+	invalid := apperr.New(apperr.Invalid, "bad id")
+	if _, err := Retry(context.Background(), p, failing(2, invalid)); err != invalid || len(attempts) != 1 {
+		t.Fatalf("got %v after %d attempts", err, len(attempts))
+	}
+
+	attempts = nil
+	_, err = Retry(context.Background(), p, failing(10, unavailable))
+	if !errors.Is(err, unavailable) || !strings.Contains(err.Error(), "out of attempts after 5 attempts") || len(attempts) != 5 {
+		t.Fatalf("got %v after %d attempts", err, len(attempts))
+	}
+}
+
+func TestMaxElapsedAndContext(t *testing.T) {
+	p := Policy{MaxElapsed: 50 * time.Millisecond, Backoff: Constant(20 * time.Millisecond)}
+	start := time.Now()
+	_, err := Retry(context.Background(), p, failing(100, unavailable))
+	// It gives up before a wait that would end past MaxElapsed, rather
+	// than sleeping through it.
+	if !strings.Contains(err.Error(), "out of time") || time.Since(start) > 100*time.Millisecond {
+		t.Fatalf("got %v after %v", err, time.Since(start))
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Millisecond)
+	defer cancel()
+	err = Do(ctx, Policy{Backoff: Constant(time.Hour)}, func(context.Context) error { return unavailable })
+	if !errors.Is(err, context.DeadlineExceeded) || apperr.KindOf(err) != apperr.Timeout {
+		t.Fatalf("got %v", err)
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	var delays []time.Duration
+	p := Policy{
+		MaxAttempts: 2,
+		Backoff:     Constant(time.Millisecond),
+		OnAttempt:   func(a Attempt) { delays = append(delays, a.Delay) },
+	}
+	Retry(context.Background(), p, failing(1, After(unavailable, 20*time.Millisecond)))
+	if delays[0] != 20*time.Millisecond {
+		t.Fatalf("delays %v", delays)
+	}
+
+	s, _ := status.New(codes.Unavailable, "overloaded").WithDetails(&errdetails.RetryInfo{RetryDelay: durationpb.New(3 * time.Second)})
+	if d, ok := RetryAfter(s.Err()); !ok || d != 3*time.Second {
+		t.Fatalf("RetryInfo: %v %v", d, ok)
+	}
+	if _, ok := RetryAfter(unavailable); ok {
+		t.Fatal("hint without one")
+	}
+
+	now := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+	for value, want := range map[string]time.Duration{
+		"120":                           2 * time.Minute,
+		"Thu, 02 Jan 2025 03:04:35 GMT": 30 * time.Second,
+		"Thu, 02 Jan 2025 03:00:00 GMT": 0,
+	} {
+		if d, ok := ParseRetryAfter(value, now); !ok || d != want {
+			t.Errorf("ParseRetryAfter(%q) = %v, %v", value, d, ok)
+		}
+	}
+	if _, ok := ParseRetryAfter("soon", now); ok {
+		t.Error("parsed soon")
+	}
+}
+
+func TestBudget(t *testing.T) {
+	b := NewBudget(10, 0.5)
+	p := Policy{Budget: b, Backoff: Constant(0), MaxAttempts: 100}
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	calls := 0
+	for range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			Do(context.Background(), p, func(context.Context) error {
+				mu.Lock()
+				calls++
+				mu.Unlock()
+				return unavailable
+			})
+		}()
+	}
+	wg.Wait()
+	// The shared budget allows only the spare half of its tokens to be
+	// spent on retries, however many calls fail at once.
+	if calls > 8+5 {
+		t.Fatalf("%d calls", calls)
+	}
+	err := Do(context.Background(), p, func(context.Context) error { return unavailable })
+	if !strings.Contains(err.Error(), "budget exhausted after 1 attempts") {
+		t.Fatalf("got %v", err)
+	}
+	// Successes refill it.
+	for range 20 {
+		Do(context.Background(), p, func(context.Context) error { return nil })
+	}
+	if _, err := Retry(context.Background(), p, failing(1, unavailable)); err != nil {
+		t.Fatalf("after refill: %v", err)
+	}
+}